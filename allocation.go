@@ -0,0 +1,71 @@
+package main
+
+import "time"
+
+//Share of the worker day committed to the partially allocated task
+type workerAllocation struct {
+	taskID     string
+	startTime  time.Time
+	stopTime   time.Time
+	allocation float32
+}
+
+//Sum the worker allocations overlapping the startTime-stopTime range and find the latest stop time of them.
+//Ranges touching at the boundary are not overlapping
+func sumAllocations(worker scheduledWorker, startTime, stopTime time.Time) (float32, time.Time) {
+	var allocation float32
+	var latestStopTime time.Time
+	for _, v := range worker.allocations {
+		if v.startTime.Before(stopTime) && startTime.Before(v.stopTime) {
+			allocation += v.allocation
+			if v.stopTime.After(latestStopTime) {
+				latestStopTime = v.stopTime
+			}
+		}
+	}
+	return allocation, latestStopTime
+}
+
+//Check if the worker allocations together with the task fit into the full working day during the task time range.
+//Full-time task allocation is 1, so it can't overlap any partial allocation
+func hasAllocationCapacity(worker scheduledWorker, taskID string, startTime, stopTime time.Time) bool {
+	allocation, _ := sumAllocations(worker, startTime, stopTime)
+	//0.0001 to fix the floating point edge cases, e.g. 0.7 + 0.3
+	return allocation+tasksDB[taskID].allocation <= 1.0001
+}
+
+//Find the earliest start time, not before startTime, when the worker has enough free allocation during the whole task duration.
+//Task overlapping the busy worker is pushed after the overlapping allocations
+func findAllocationStartTime(worker scheduledWorker, taskID string, startTime time.Time) time.Time {
+	site := taskSite(taskID)
+	for {
+		stopTime := site.AddTaskHours(startTime, tasksDB[taskID].duration)
+		if hasAllocationCapacity(worker, taskID, startTime, stopTime) {
+			return startTime
+		}
+		_, latestStopTime := sumAllocations(worker, startTime, stopTime)
+		logger.Debugf("Worker is fully allocated, task:%v, worker:%v, startTime:%v, nextStartTime:%v", taskID, worker.workerID, startTime, latestStopTime)
+		startTime = site.AddHours(latestStopTime, 0)
+	}
+}
+
+//Commit the worker to the task, partially allocated worker stays on site and available for other tasks from the task start time
+func allocateWorker(worker *scheduledWorker, task scheduledTask) {
+	worker.started = true
+	worker.tainted = true
+	if tasksDB[task.taskID].allocation >= 1 {
+		worker.availableAt = task.stopTime
+		return
+	}
+	if worker.availableAt.Before(task.startTime) {
+		worker.availableAt = task.startTime
+	}
+	for i, v := range worker.allocations {
+		if v.taskID == task.taskID {
+			worker.allocations[i].startTime = task.startTime
+			worker.allocations[i].stopTime = task.stopTime
+			return
+		}
+	}
+	worker.allocations = append(worker.allocations, workerAllocation{taskID: task.taskID, startTime: task.startTime, stopTime: task.stopTime, allocation: tasksDB[task.taskID].allocation})
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+//Calculate makespan in hours assuming unlimited workers: every task starts as soon as all prerequisites are finished
+func calcUnlimitedWorkersMakespan() float32 {
+	startTimes := make(map[string]time.Time)
+	stopTimes := make(map[string]time.Time)
+	inProgress := make(map[string]struct{})
+
+	var calcStopTime func(taskID string) time.Time
+	calcStopTime = func(taskID string) time.Time {
+		if stopTime, ok := stopTimes[taskID]; ok {
+			return stopTime
+		}
+		if _, ok := inProgress[taskID]; ok {
+			logger.Fatal("Circular prerequisites for the task: ", taskID)
+		}
+		inProgress[taskID] = struct{}{}
+
+		site := taskSite(taskID)
+		startTime := scheduleStartTime
+		for prerequisiteID := range tasksDB[taskID].prerequisites {
+			prerequisiteStopTime := calcStopTime(prerequisiteID)
+			dependencyStartTime := calcDependencyStartTime(taskID, prerequisiteID, startTimes[prerequisiteID], prerequisiteStopTime)
+			if startTime.Before(dependencyStartTime) {
+				startTime = dependencyStartTime
+			}
+		}
+		if !tasksDB[taskID].pinnedDateTime.IsZero() {
+			startTime = tasksDB[taskID].pinnedDateTime
+		}
+		startTimes[taskID] = startTime
+		stopTimes[taskID] = site.AddTaskHours(startTime, tasksDB[taskID].duration)
+		delete(inProgress, taskID)
+		return stopTimes[taskID]
+	}
+
+	var makespan float32
+	for taskID := range tasksDB {
+		if makespan < float32(calcStopTime(taskID).Sub(scheduleStartTime).Hours()) {
+			makespan = float32(calcStopTime(taskID).Sub(scheduleStartTime).Hours())
+		}
+	}
+	return makespan
+}
+
+//Schedule all single-swap neighbors of the individual and return their fitnesses, n tasks give n*(n-1)/2 neighbors
+func sampleSwapNeighbors(individual individual) []float32 {
+	var fitnesses []float32
+	for i := 0; i < len(individual.tasks); i++ {
+		for j := i + 1; j < len(individual.tasks); j++ {
+			neighbor := copyIndividual(individual)
+			neighbor.tasks[i].taskID, neighbor.tasks[j].taskID = neighbor.tasks[j].taskID, neighbor.tasks[i].taskID
+			fitnesses = append(fitnesses, scheduleIndividual(neighbor, nil).fitness)
+		}
+	}
+	return fitnesses
+}
+
+func prettyPrintNeighborsFitness(individual individual) {
+	fitnesses := sampleSwapNeighbors(individual)
+	if len(fitnesses) == 0 {
+		logger.Info("Individual has no swap neighbors")
+		return
+	}
+	minFitness, maxFitness := fitnesses[0], fitnesses[0]
+	var totalFitness float32
+	var improving int
+	for _, fitness := range fitnesses {
+		if fitness < minFitness {
+			minFitness = fitness
+		}
+		if fitness > maxFitness {
+			maxFitness = fitness
+		}
+		if fitness < individual.fitness {
+			improving++
+		}
+		totalFitness += fitness
+	}
+	logger.Info("Swap neighbors =", len(fitnesses))
+	logger.Info("Neighbors fitness min/mean/max =", minFitness, totalFitness/float32(len(fitnesses)), maxFitness)
+	logger.Info("Improving neighbors =", improving)
+}
+
+//Find tasks of the critical chain which ends with the last finished task. Every chain task is the latest finished blocker of the next one:
+//either its prerequisite or the previous task of one of its workers
+func findCriticalChain(individual individual) map[string]struct{} {
+	tasks := make(map[string]scheduledTask)
+	var lastTask scheduledTask
+	for _, task := range individual.tasks {
+		if task.stopTime.IsZero() {
+			continue
+		}
+		tasks[task.taskID] = task
+		if lastTask.stopTime.Before(task.stopTime) {
+			lastTask = task
+		}
+	}
+
+	criticalTasks := make(map[string]struct{})
+	for task, ok := lastTask, !lastTask.stopTime.IsZero(); ok; {
+		criticalTasks[task.taskID] = struct{}{}
+		var blocker scheduledTask
+		for prerequisiteID := range tasksDB[task.taskID].prerequisites {
+			if prerequisite, ok := tasks[prerequisiteID]; ok && blocker.stopTime.Before(prerequisite.stopTime) {
+				blocker = prerequisite
+			}
+		}
+		for _, workerID := range task.assignees {
+			for _, previousTask := range tasks {
+				if previousTask.taskID == task.taskID || previousTask.stopTime.After(task.startTime) || !blocker.stopTime.Before(previousTask.stopTime) {
+					continue
+				}
+				for _, assignee := range previousTask.assignees {
+					if assignee == workerID {
+						blocker = previousTask
+						break
+					}
+				}
+			}
+		}
+		_, visited := criticalTasks[blocker.taskID]
+		task, ok = blocker, !blocker.stopTime.IsZero() && !visited
+	}
+	return criticalTasks
+}
+
+//Worker limiting the schedule makespan
+type workerBottleneck struct {
+	workerID      string
+	criticalTasks int //number of the critical chain tasks assigned to the worker
+	utilization   float32
+}
+
+//Rank workers by the number of critical chain tasks and by utilization, the most limiting worker goes first
+func calculateBottleneckWorkers(individual individual) []workerBottleneck {
+	criticalTasks := findCriticalChain(individual)
+	criticalTasksCount := make(map[string]int)
+	var horizonEndTime time.Time
+	for _, task := range individual.tasks {
+		if horizonEndTime.Before(task.stopTime) {
+			horizonEndTime = task.stopTime
+		}
+		if _, ok := criticalTasks[task.taskID]; !ok {
+			continue
+		}
+		//The same worker is counted once per task
+		assigned := make(map[string]struct{})
+		for _, workerID := range task.assignees {
+			if _, ok := assigned[workerID]; !ok {
+				assigned[workerID] = struct{}{}
+				criticalTasksCount[workerID]++
+			}
+		}
+	}
+
+	var bottlenecks []workerBottleneck
+	for _, utilization := range calculateWorkersUtilization(individual, horizonEndTime) {
+		bottlenecks = append(bottlenecks, workerBottleneck{workerID: utilization.workerID, criticalTasks: criticalTasksCount[utilization.workerID], utilization: utilization.utilization})
+	}
+	//Utilization order is kept for the same number of critical tasks
+	sort.SliceStable(bottlenecks, func(i, j int) bool {
+		return bottlenecks[i].criticalTasks > bottlenecks[j].criticalTasks
+	})
+	return bottlenecks
+}
+
+func prettyPrintBottleneckWorkers(individual individual, count int) {
+	bottlenecks := calculateBottleneckWorkers(individual)
+	if count > len(bottlenecks) {
+		count = len(bottlenecks)
+	}
+	for _, v := range bottlenecks[:count] {
+		logger.Infof(";%v;%v;%v;%.1f%%", workersDB[v.workerID].name, v.workerID, v.criticalTasks, v.utilization)
+	}
+}
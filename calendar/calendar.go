@@ -10,6 +10,9 @@ import (
 
 const timeRoundingSeconds float32 = 600
 
+//AddHoursTolerance is the maximum difference in hours between requested and actual working hours of AddHours, caused by the time rounding
+const AddHoursTolerance float32 = timeRoundingSeconds / 3600
+
 //Site is a struct to store the working site time limitations
 type Site struct {
 	DailyStartTime time.Time
@@ -17,43 +20,116 @@ type Site struct {
 	Holidays       map[time.Time]struct{}
 	LunchStartTime time.Time
 	LunchEndTime   time.Time
+	//WorkingWeekdays overrides the default Monday to Friday working week, empty = Monday to Friday
+	WorkingWeekdays map[time.Weekday]struct{}
+	//MaxContinuousHours is the longest allowed block of work without a break, 0 = no mandatory breaks
+	MaxContinuousHours float32
+	//BreakDuration is the length of the mandatory break in hours
+	BreakDuration float32
+	//MaxOvertimeHours is the longest work after the DailyEndTime to finish the task instead of continuing on the next working day, 0 = no overtime
+	MaxOvertimeHours float32
+	//OvertimeMultiplier is the cost of one overtime hour in regular hours
+	OvertimeMultiplier float32
 }
 
 var logger = log.New(os.Stdout).WithoutDebug()
 
-//AddHours will add number of hours to the startTime, according to the Site working time limitation, holidays and weekends
-func (site Site) AddHours(startTime time.Time, hours float32) time.Time {
-	//TODO: Account for lunch hours
-	//TODO: Can break if start time is on the weekend or holiday
+//IsWorkingDay will check if the day of dayTime is a working weekday and not a holiday
+func (site Site) IsWorkingDay(dayTime time.Time) bool {
+	if len(site.WorkingWeekdays) > 0 {
+		if _, ok := site.WorkingWeekdays[dayTime.Weekday()]; !ok {
+			return false
+		}
+	} else if dayTime.Weekday() == time.Saturday || dayTime.Weekday() == time.Sunday {
+		return false
+	}
+	_, isHoliday := site.Holidays[time.Date(dayTime.Year(), dayTime.Month(), dayTime.Day(), 0, 0, 0, 0, dayTime.Location())]
+	return !isHoliday
+}
 
-	logger.Debugf("startTime:%v, hours:%v", startTime, hours)
+//hasLunch will check if the Site has non-zero lunch window
+func (site Site) hasLunch() bool {
+	return site.LunchStartTime.Before(site.LunchEndTime)
+}
 
-	//Start of current working day
-	todayStartTime := time.Date(startTime.Year(), startTime.Month(), startTime.Day(), site.DailyStartTime.Hour(), site.DailyStartTime.Minute(), site.DailyStartTime.Second(), 0, startTime.Location())
-	//End of current working day
-	todayEndTime := time.Date(startTime.Year(), startTime.Month(), startTime.Day(), site.DailyEndTime.Hour(), site.DailyEndTime.Minute(), site.DailyEndTime.Second(), 0, startTime.Location())
-	logger.Debugf("todayStartTime:%v, todayEndTime:%v", todayStartTime, todayEndTime)
-	//Move startTime to the first available time, if needed
-	if startTime.Before(todayStartTime) {
-		startTime = todayStartTime
-	} else if startTime.After(todayEndTime) {
-		startTime = todayStartTime.AddDate(0, 0, 1)
+//normalizeStartTime will move startTime to the next working instant: inside the daily working window of the working day
+func (site Site) normalizeStartTime(startTime time.Time) time.Time {
+	for {
+		todayStartTime := time.Date(startTime.Year(), startTime.Month(), startTime.Day(), site.DailyStartTime.Hour(), site.DailyStartTime.Minute(), site.DailyStartTime.Second(), 0, startTime.Location())
+		todayEndTime := time.Date(startTime.Year(), startTime.Month(), startTime.Day(), site.DailyEndTime.Hour(), site.DailyEndTime.Minute(), site.DailyEndTime.Second(), 0, startTime.Location())
+		if !site.IsWorkingDay(startTime) || startTime.After(todayEndTime) {
+			startTime = todayStartTime.AddDate(0, 0, 1)
+			continue
+		}
+		if startTime.Before(todayStartTime) {
+			return todayStartTime
+		}
+		return startTime
 	}
+}
 
-	//Move startTime to the first available working day, if needed
-	firstWorkday := false
-	for !firstWorkday {
-		if startTime.Weekday() == time.Saturday || startTime.Weekday() == time.Sunday {
-			startTime = startTime.AddDate(0, 0, 1)
-		} else if _, ok := site.Holidays[time.Date(startTime.Year(), startTime.Month(), startTime.Day(), 0, 0, 0, 0, startTime.Location())]; ok {
-			startTime = startTime.AddDate(0, 0, 1)
-		} else {
-			firstWorkday = true
+//AddTaskHours will add number of task work hours to the startTime like AddHours, but the task can be finished in the overtime instead of continuing on the next working day
+func (site Site) AddTaskHours(startTime time.Time, hours float32) time.Time {
+	endTime := site.AddHours(startTime, hours)
+	if site.MaxOvertimeHours > 0 && hours > 0 {
+		return site.finishInOvertime(startTime, endTime)
+	}
+	return endTime
+}
+
+//finishInOvertime will move the work of the last day to the overtime of the previous working day, if the task was in progress on that day and the last day work fits into MaxOvertimeHours
+func (site Site) finishInOvertime(startTime, endTime time.Time) time.Time {
+	endDayStartTime := time.Date(endTime.Year(), endTime.Month(), endTime.Day(), site.DailyStartTime.Hour(), site.DailyStartTime.Minute(), site.DailyStartTime.Second(), 0, endTime.Location())
+	lastDayHours := endTime.Sub(endDayStartTime).Hours()
+	if lastDayHours <= 0 || lastDayHours > float64(site.MaxOvertimeHours) {
+		return endTime
+	}
+	previousDay := endDayStartTime.AddDate(0, 0, -1)
+	for !site.IsWorkingDay(previousDay) {
+		if previousDay.Before(startTime) {
+			return endTime
 		}
+		previousDay = previousDay.AddDate(0, 0, -1)
+	}
+	previousDayEndTime := time.Date(previousDay.Year(), previousDay.Month(), previousDay.Day(), site.DailyEndTime.Hour(), site.DailyEndTime.Minute(), site.DailyEndTime.Second(), 0, previousDay.Location())
+	if !startTime.Before(previousDayEndTime) {
+		return endTime
 	}
+	logger.Debugf("Overtime:%v, previousDayEndTime:%v", lastDayHours, previousDayEndTime)
+	return previousDayEndTime.Add(endTime.Sub(endDayStartTime))
+}
 
-	//Refresh todayEndDate to actual today for the startTime
-	todayEndTime = time.Date(startTime.Year(), startTime.Month(), startTime.Day(), site.DailyEndTime.Hour(), site.DailyEndTime.Minute(), site.DailyEndTime.Second(), 0, startTime.Location())
+//OvertimeHours will calculate number of overtime hours of the work between startTime and endTime. Only the last day of work can be finished in the overtime
+func (site Site) OvertimeHours(startTime, endTime time.Time) float32 {
+	if site.MaxOvertimeHours <= 0 || !site.IsWorkingDay(endTime) {
+		return 0
+	}
+	overtimeStartTime := time.Date(endTime.Year(), endTime.Month(), endTime.Day(), site.DailyEndTime.Hour(), site.DailyEndTime.Minute(), site.DailyEndTime.Second(), 0, endTime.Location())
+	if overtimeStartTime.Before(startTime) {
+		overtimeStartTime = startTime
+	}
+	if !endTime.After(overtimeStartTime) {
+		return 0
+	}
+	return float32(endTime.Sub(overtimeStartTime).Hours())
+}
+
+//AddHours will add number of hours to the startTime, according to the Site working time limitation, lunch, holidays and weekends. Negative hours move the startTime back
+func (site Site) AddHours(startTime time.Time, hours float32) time.Time {
+	if hours < 0 {
+		return site.subtractHours(startTime, -hours)
+	}
+	if (site.hasLunch() || (site.MaxContinuousHours > 0 && site.BreakDuration > 0)) && hours >= 0 {
+		return site.addHoursStepwise(startTime, hours)
+	}
+
+	logger.Debugf("startTime:%v, hours:%v", startTime, hours)
+
+	//Move startTime to the first available working time before any duration arithmetic
+	startTime = site.normalizeStartTime(startTime)
+
+	//End of current working day
+	todayEndTime := time.Date(startTime.Year(), startTime.Month(), startTime.Day(), site.DailyEndTime.Hour(), site.DailyEndTime.Minute(), site.DailyEndTime.Second(), 0, startTime.Location())
 	logger.Debugf("newStartTime:%v, todayEndTime:%v", startTime, todayEndTime)
 
 	seconds := float64(hours * 3600)
@@ -61,6 +137,10 @@ func (site Site) AddHours(startTime time.Time, hours float32) time.Time {
 	workingHoursPerDay := site.DailyEndTime.Sub(site.DailyStartTime).Hours()
 	//Number of days required to finish work without holidays or weekends. 0.0001 (~0.4 seconds) to fix the edge cases, e.g. 8 hrs in 8 hrs working day
 	totalDays := int(math.Floor(float64(hours-0.0001) / workingHoursPerDay))
+	//Zero hours at the start of the working day shouldn't move to the end of the day
+	if totalDays < 0 {
+		totalDays = 0
+	}
 	//Account for the possible overflow of work to the next day, e.g. 4 hours work start at 15:00
 	if startTime.Add(time.Duration(seconds-float64(totalDays)*workingHoursPerDay*3600) * time.Second).After(todayEndTime) {
 		totalDays++
@@ -73,12 +153,8 @@ func (site Site) AddHours(startTime time.Time, hours float32) time.Time {
 	//Count required number of working days, skipping weekends and hoildays
 	var workingDays int = 0
 	for workingDays < totalDays {
-		if endTime.Weekday() == time.Saturday {
-			endTime = endTime.AddDate(0, 0, 2)
-		} else if _, ok := site.Holidays[endTime]; ok {
-			endTime = endTime.AddDate(0, 0, 1)
-		} else {
-			endTime = endTime.AddDate(0, 0, 1)
+		endTime = endTime.AddDate(0, 0, 1)
+		if site.IsWorkingDay(endTime) {
 			workingDays++
 		}
 	}
@@ -91,6 +167,17 @@ func (site Site) AddHours(startTime time.Time, hours float32) time.Time {
 	endTime = endTime.Add(time.Duration(remainingSeconds) * time.Second)
 	logger.Debugf("endTime:%v", endTime)
 
+	//Work can't continue after the close of business, roll the overflow to the start of the next working day
+	for {
+		endDayEndTime := time.Date(endTime.Year(), endTime.Month(), endTime.Day(), site.DailyEndTime.Hour(), site.DailyEndTime.Minute(), site.DailyEndTime.Second(), 0, endTime.Location())
+		if !endTime.After(endDayEndTime) {
+			break
+		}
+		overflow := endTime.Sub(endDayEndTime)
+		endTime = site.normalizeStartTime(endDayEndTime.Add(time.Second)).Add(overflow)
+		logger.Debugf("Overflow:%v, endTime:%v", overflow, endTime)
+	}
+
 	//Round up to timeRounding minutes
 	if !endTime.Equal(endTime.Truncate(time.Duration(timeRoundingSeconds) * time.Second)) {
 		endTime = endTime.Truncate(time.Duration(timeRoundingSeconds) * time.Second).Add(time.Duration(timeRoundingSeconds) * time.Second)
@@ -99,3 +186,217 @@ func (site Site) AddHours(startTime time.Time, hours float32) time.Time {
 
 	return endTime
 }
+
+//subtractHours will move endTime back by number of working hours, skipping weekends, holidays and lunch. Mandatory breaks are not accounted
+func (site Site) subtractHours(endTime time.Time, hours float32) time.Time {
+	remainingSeconds := float64(hours) * 3600
+	for remainingSeconds > 0 {
+		dayStartTime := time.Date(endTime.Year(), endTime.Month(), endTime.Day(), site.DailyStartTime.Hour(), site.DailyStartTime.Minute(), site.DailyStartTime.Second(), 0, endTime.Location())
+		dayEndTime := time.Date(endTime.Year(), endTime.Month(), endTime.Day(), site.DailyEndTime.Hour(), site.DailyEndTime.Minute(), site.DailyEndTime.Second(), 0, endTime.Location())
+		previousDayEndTime := dayEndTime.AddDate(0, 0, -1)
+		if !site.IsWorkingDay(endTime) || !endTime.After(dayStartTime) {
+			endTime = previousDayEndTime
+			continue
+		}
+		if endTime.After(dayEndTime) {
+			endTime = dayEndTime
+		}
+		//Work before the endTime continues from the lunch start or the previous working day
+		workStartTime := dayStartTime
+		nextEndTime := previousDayEndTime
+		if site.hasLunch() {
+			lunchStartTime := time.Date(endTime.Year(), endTime.Month(), endTime.Day(), site.LunchStartTime.Hour(), site.LunchStartTime.Minute(), site.LunchStartTime.Second(), 0, endTime.Location())
+			lunchEndTime := time.Date(endTime.Year(), endTime.Month(), endTime.Day(), site.LunchEndTime.Hour(), site.LunchEndTime.Minute(), site.LunchEndTime.Second(), 0, endTime.Location())
+			if endTime.After(lunchEndTime) {
+				workStartTime = lunchEndTime
+				nextEndTime = lunchStartTime
+			} else if endTime.After(lunchStartTime) {
+				endTime = lunchStartTime
+			}
+		}
+		availableSeconds := endTime.Sub(workStartTime).Seconds()
+		if remainingSeconds <= availableSeconds {
+			endTime = endTime.Add(-time.Duration(remainingSeconds * float64(time.Second)))
+			break
+		}
+		remainingSeconds -= availableSeconds
+		endTime = nextEndTime
+	}
+	logger.Debugf("endTime:%v", endTime)
+
+	//Round up to timeRounding minutes, so the lead is never longer than requested
+	if !endTime.Equal(endTime.Truncate(time.Duration(timeRoundingSeconds) * time.Second)) {
+		endTime = endTime.Truncate(time.Duration(timeRoundingSeconds) * time.Second).Add(time.Duration(timeRoundingSeconds) * time.Second)
+	}
+	return endTime
+}
+
+//addHoursStepwise will add number of hours to the startTime block by block, skipping the lunch and inserting the mandatory break after every MaxContinuousHours of work.
+//Continuous work is reset by the lunch and at the end of every working day
+func (site Site) addHoursStepwise(startTime time.Time, hours float32) time.Time {
+	remainingSeconds := float64(hours) * 3600
+	maxContinuousSeconds := math.Inf(1)
+	if site.MaxContinuousHours > 0 && site.BreakDuration > 0 {
+		maxContinuousSeconds = float64(site.MaxContinuousHours) * 3600
+	}
+	var continuousSeconds float64
+	endTime := startTime
+	for {
+		//Move endTime to the first available working time, if needed
+		todayStartTime := time.Date(endTime.Year(), endTime.Month(), endTime.Day(), site.DailyStartTime.Hour(), site.DailyStartTime.Minute(), site.DailyStartTime.Second(), 0, endTime.Location())
+		todayEndTime := time.Date(endTime.Year(), endTime.Month(), endTime.Day(), site.DailyEndTime.Hour(), site.DailyEndTime.Minute(), site.DailyEndTime.Second(), 0, endTime.Location())
+		if !endTime.Before(todayEndTime) || !site.IsWorkingDay(endTime) {
+			endTime = todayStartTime.AddDate(0, 0, 1)
+			continuousSeconds = 0
+			continue
+		}
+		if endTime.Before(todayStartTime) {
+			endTime = todayStartTime
+			continuousSeconds = 0
+		}
+		blockEndTime := todayEndTime
+		if site.hasLunch() {
+			todayLunchStartTime := time.Date(endTime.Year(), endTime.Month(), endTime.Day(), site.LunchStartTime.Hour(), site.LunchStartTime.Minute(), site.LunchStartTime.Second(), 0, endTime.Location())
+			todayLunchEndTime := time.Date(endTime.Year(), endTime.Month(), endTime.Day(), site.LunchEndTime.Hour(), site.LunchEndTime.Minute(), site.LunchEndTime.Second(), 0, endTime.Location())
+			if !endTime.Before(todayLunchStartTime) && endTime.Before(todayLunchEndTime) {
+				//Work can't be started during the lunch
+				endTime = todayLunchEndTime
+				continuousSeconds = 0
+				continue
+			}
+			if endTime.Before(todayLunchStartTime) && todayLunchStartTime.Before(blockEndTime) {
+				blockEndTime = todayLunchStartTime
+			}
+		}
+		if remainingSeconds <= 0 {
+			break
+		}
+
+		//Work until the end of the day, the lunch, the mandatory break or the end of the task, whichever comes first
+		blockSeconds := math.Min(blockEndTime.Sub(endTime).Seconds(), math.Min(maxContinuousSeconds-continuousSeconds, remainingSeconds))
+		endTime = endTime.Add(time.Duration(blockSeconds * float64(time.Second)))
+		remainingSeconds -= blockSeconds
+		continuousSeconds += blockSeconds
+		if remainingSeconds <= 0 {
+			break
+		}
+		if continuousSeconds >= maxContinuousSeconds {
+			logger.Debugf("Mandatory break at:%v", endTime)
+			endTime = endTime.Add(time.Duration(float64(site.BreakDuration) * 3600 * float64(time.Second)))
+			continuousSeconds = 0
+		}
+	}
+
+	//Round up to timeRounding minutes
+	if !endTime.Equal(endTime.Truncate(time.Duration(timeRoundingSeconds) * time.Second)) {
+		endTime = endTime.Truncate(time.Duration(timeRoundingSeconds) * time.Second).Add(time.Duration(timeRoundingSeconds) * time.Second)
+	}
+	logger.Debugf("endTime:%v", endTime)
+
+	return endTime
+}
+
+//WorkingHoursBetween will calculate number of working hours between startTime and endTime, according to the Site working time limitation, holidays and weekends.
+//Mandatory breaks are counted from the startTime, like in AddHours
+func (site Site) WorkingHoursBetween(startTime, endTime time.Time) float32 {
+	if endTime.Before(startTime) {
+		return -site.WorkingHoursBetween(endTime, startTime)
+	}
+	if site.MaxContinuousHours > 0 && site.BreakDuration > 0 {
+		return float32(site.workingHoursStepwise(startTime, endTime))
+	}
+
+	var hours float64
+	//Walk day by day from the start date and sum the working window overlap for every working day
+	day := time.Date(startTime.Year(), startTime.Month(), startTime.Day(), 0, 0, 0, 0, startTime.Location())
+	for !day.After(endTime) {
+		if site.IsWorkingDay(day) {
+			dayStartTime := time.Date(day.Year(), day.Month(), day.Day(), site.DailyStartTime.Hour(), site.DailyStartTime.Minute(), site.DailyStartTime.Second(), 0, day.Location())
+			dayEndTime := time.Date(day.Year(), day.Month(), day.Day(), site.DailyEndTime.Hour(), site.DailyEndTime.Minute(), site.DailyEndTime.Second(), 0, day.Location())
+			if dayStartTime.Before(startTime) {
+				dayStartTime = startTime
+			}
+			if dayEndTime.After(endTime) {
+				dayEndTime = endTime
+			}
+			if dayEndTime.After(dayStartTime) {
+				hours += dayEndTime.Sub(dayStartTime).Hours()
+				//Exclude the lunch overlapping with the working window
+				if site.hasLunch() {
+					lunchStartTime := time.Date(day.Year(), day.Month(), day.Day(), site.LunchStartTime.Hour(), site.LunchStartTime.Minute(), site.LunchStartTime.Second(), 0, day.Location())
+					lunchEndTime := time.Date(day.Year(), day.Month(), day.Day(), site.LunchEndTime.Hour(), site.LunchEndTime.Minute(), site.LunchEndTime.Second(), 0, day.Location())
+					if lunchStartTime.Before(dayStartTime) {
+						lunchStartTime = dayStartTime
+					}
+					if lunchEndTime.After(dayEndTime) {
+						lunchEndTime = dayEndTime
+					}
+					if lunchEndTime.After(lunchStartTime) {
+						hours -= lunchEndTime.Sub(lunchStartTime).Hours()
+					}
+				}
+			}
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	logger.Debugf("startTime:%v, endTime:%v, hours:%v", startTime, endTime, hours)
+
+	return float32(hours)
+}
+
+//workingHoursStepwise will walk from the startTime to the endTime block by block like addHoursStepwise and sum the working hours, skipping the lunch and the mandatory breaks
+func (site Site) workingHoursStepwise(startTime, endTime time.Time) float64 {
+	maxContinuousSeconds := float64(site.MaxContinuousHours) * 3600
+	var continuousSeconds, workingSeconds float64
+	currentTime := startTime
+	for currentTime.Before(endTime) {
+		todayStartTime := time.Date(currentTime.Year(), currentTime.Month(), currentTime.Day(), site.DailyStartTime.Hour(), site.DailyStartTime.Minute(), site.DailyStartTime.Second(), 0, currentTime.Location())
+		todayEndTime := time.Date(currentTime.Year(), currentTime.Month(), currentTime.Day(), site.DailyEndTime.Hour(), site.DailyEndTime.Minute(), site.DailyEndTime.Second(), 0, currentTime.Location())
+		if !currentTime.Before(todayEndTime) || !site.IsWorkingDay(currentTime) {
+			currentTime = todayStartTime.AddDate(0, 0, 1)
+			continuousSeconds = 0
+			continue
+		}
+		if currentTime.Before(todayStartTime) {
+			currentTime = todayStartTime
+			continuousSeconds = 0
+			continue
+		}
+		blockEndTime := todayEndTime
+		if site.hasLunch() {
+			todayLunchStartTime := time.Date(currentTime.Year(), currentTime.Month(), currentTime.Day(), site.LunchStartTime.Hour(), site.LunchStartTime.Minute(), site.LunchStartTime.Second(), 0, currentTime.Location())
+			todayLunchEndTime := time.Date(currentTime.Year(), currentTime.Month(), currentTime.Day(), site.LunchEndTime.Hour(), site.LunchEndTime.Minute(), site.LunchEndTime.Second(), 0, currentTime.Location())
+			if !currentTime.Before(todayLunchStartTime) && currentTime.Before(todayLunchEndTime) {
+				currentTime = todayLunchEndTime
+				continuousSeconds = 0
+				continue
+			}
+			if currentTime.Before(todayLunchStartTime) && todayLunchStartTime.Before(blockEndTime) {
+				blockEndTime = todayLunchStartTime
+			}
+		}
+		if endTime.Before(blockEndTime) {
+			blockEndTime = endTime
+		}
+
+		//Work until the end of the block or the mandatory break, whichever comes first
+		blockSeconds := math.Min(blockEndTime.Sub(currentTime).Seconds(), maxContinuousSeconds-continuousSeconds)
+		currentTime = currentTime.Add(time.Duration(blockSeconds * float64(time.Second)))
+		workingSeconds += blockSeconds
+		continuousSeconds += blockSeconds
+		if continuousSeconds >= maxContinuousSeconds {
+			currentTime = currentTime.Add(time.Duration(float64(site.BreakDuration) * 3600 * float64(time.Second)))
+			continuousSeconds = 0
+		}
+	}
+	logger.Debugf("startTime:%v, endTime:%v, hours:%v", startTime, endTime, workingSeconds/3600)
+
+	return workingSeconds / 3600
+}
+
+//VerifyAddHours will check the AddHours inverse property: WorkingHoursBetween(startTime, AddHours(startTime, hours)) should be equal to hours within AddHoursTolerance. Returns actual working hours and the result of the check
+func (site Site) VerifyAddHours(startTime time.Time, hours float32) (float32, bool) {
+	actualHours := site.WorkingHoursBetween(startTime, site.AddHours(startTime, hours))
+	diff := actualHours - hours
+	return actualHours, diff > -0.0001 && diff < AddHoursTolerance+0.0001
+}
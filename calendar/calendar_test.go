@@ -0,0 +1,144 @@
+package calendar
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+//clock will create the daily time of the Site
+func clock(hour, minute int) time.Time {
+	return time.Date(0, 1, 1, hour, minute, 0, 0, time.UTC)
+}
+
+func TestAddHoursMandatoryBreaks(t *testing.T) {
+	site := Site{DailyStartTime: clock(6, 0), DailyEndTime: clock(22, 0)}
+	startTime := time.Date(2020, 12, 21, 6, 0, 0, 0, time.UTC)
+	endTimeWithoutBreaks := site.AddHours(startTime, 10)
+
+	site.MaxContinuousHours = 4
+	site.BreakDuration = 0.5
+	endTime := site.AddHours(startTime, 10)
+
+	//10 hours with 4 hours cap: 4h, break, 4h, break, 2h
+	if expected := endTimeWithoutBreaks.Add(2 * 30 * time.Minute); !endTime.Equal(expected) {
+		t.Errorf("AddHours with breaks = %v, expected %v", endTime, expected)
+	}
+	if hours := site.WorkingHoursBetween(startTime, endTime); hours != 10 {
+		t.Errorf("WorkingHoursBetween = %v, expected 10", hours)
+	}
+}
+
+func TestAddHoursLunch(t *testing.T) {
+	site := Site{DailyStartTime: clock(8, 0), DailyEndTime: clock(18, 0), LunchStartTime: clock(12, 0), LunchEndTime: clock(13, 0)}
+	zeroLunchSite := Site{DailyStartTime: clock(8, 0), DailyEndTime: clock(18, 0), LunchStartTime: clock(12, 0), LunchEndTime: clock(12, 0)}
+	day := func(hour int) time.Time { return time.Date(2020, 12, 21, hour, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		name      string
+		site      Site
+		startTime time.Time
+		expected  time.Time
+	}{
+		{"crossing lunch", site, day(11), day(14)},
+		{"starting at lunch", site, day(12), day(15)},
+		{"before lunch", site, day(9), day(11)},
+		{"zero lunch", zeroLunchSite, day(11), day(13)},
+	}
+	for _, test := range tests {
+		if endTime := test.site.AddHours(test.startTime, 2); !endTime.Equal(test.expected) {
+			t.Errorf("AddHours %v = %v, expected %v", test.name, endTime, test.expected)
+		}
+	}
+}
+
+func TestAddHoursNonWorkingStart(t *testing.T) {
+	site := Site{DailyStartTime: clock(8, 0), DailyEndTime: clock(16, 0), Holidays: map[time.Time]struct{}{time.Date(2020, 12, 21, 0, 0, 0, 0, time.UTC): {}}}
+	//Saturday start moves to Monday 8:00, the holiday is a week later
+	saturday := time.Date(2020, 12, 12, 9, 0, 0, 0, time.UTC)
+	if endTime, expected := site.AddHours(saturday, 2), time.Date(2020, 12, 14, 10, 0, 0, 0, time.UTC); !endTime.Equal(expected) {
+		t.Errorf("AddHours from Saturday = %v, expected %v", endTime, expected)
+	}
+	//2 hours on Friday, the rest continues on Tuesday after the Monday holiday
+	friday := time.Date(2020, 12, 18, 14, 0, 0, 0, time.UTC)
+	if endTime, expected := site.AddHours(friday, 4), time.Date(2020, 12, 22, 10, 0, 0, 0, time.UTC); !endTime.Equal(expected) {
+		t.Errorf("AddHours from Friday before the holiday = %v, expected %v", endTime, expected)
+	}
+}
+
+func TestAddHoursPastDailyEnd(t *testing.T) {
+	site := Site{DailyStartTime: clock(8, 0), DailyEndTime: clock(16, 0)}
+	//8.5 hours in the 8 hours day end 30 minutes into the next day instead of 30 minutes after the close
+	startTime := time.Date(2020, 12, 21, 8, 0, 0, 0, time.UTC)
+	if endTime, expected := site.AddHours(startTime, 8.5), time.Date(2020, 12, 22, 8, 30, 0, 0, time.UTC); !endTime.Equal(expected) {
+		t.Errorf("AddHours 8.5 = %v, expected %v", endTime, expected)
+	}
+	//Full day ends exactly at the close
+	if endTime, expected := site.AddHours(startTime, 8), time.Date(2020, 12, 21, 16, 0, 0, 0, time.UTC); !endTime.Equal(expected) {
+		t.Errorf("AddHours 8 = %v, expected %v", endTime, expected)
+	}
+}
+
+func TestAddHoursNegativeAcrossWeekend(t *testing.T) {
+	site := Site{DailyStartTime: clock(8, 0), DailyEndTime: clock(16, 0)}
+	monday := func(hour int) time.Time { return time.Date(2020, 12, 21, hour, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		startTime time.Time
+		expected  time.Time
+	}{
+		//2 hours on Monday and 2 hours on Friday before the weekend
+		{monday(10), time.Date(2020, 12, 18, 14, 0, 0, 0, time.UTC)},
+		{monday(8), time.Date(2020, 12, 18, 12, 0, 0, 0, time.UTC)},
+		//Lead inside the same day
+		{monday(14), monday(10)},
+	}
+	for _, test := range tests {
+		if endTime := site.AddHours(test.startTime, -4); !endTime.Equal(test.expected) {
+			t.Errorf("AddHours %v -4 = %v, expected %v", test.startTime, endTime, test.expected)
+		}
+	}
+}
+
+func TestWorkingHoursBetweenSkipsMandatoryBreaks(t *testing.T) {
+	site := Site{DailyStartTime: clock(8, 0), DailyEndTime: clock(18, 0), MaxContinuousHours: 3, BreakDuration: 1}
+	startTime := time.Date(2020, 12, 21, 8, 0, 0, 0, time.UTC)
+	//8:00-11:00 work, 11:00-12:00 break, 12:00-13:00 work
+	if hours := site.WorkingHoursBetween(startTime, startTime.Add(5*time.Hour)); hours != 4 {
+		t.Errorf("WorkingHoursBetween = %v, expected 4", hours)
+	}
+}
+
+//randomSite will create a Site with random working hours, holidays, working weekdays, lunch and mandatory breaks
+func randomSite(random *rand.Rand) Site {
+	dailyStartHour := 5 + random.Intn(5)
+	dailyEndHour := dailyStartHour + 6 + random.Intn(6)
+	site := Site{DailyStartTime: clock(dailyStartHour, 10*random.Intn(6)), DailyEndTime: clock(dailyEndHour, 10*random.Intn(6))}
+	if random.Intn(2) == 0 {
+		lunchStartHour := dailyStartHour + 2 + random.Intn(3)
+		site.LunchStartTime = clock(lunchStartHour, 0)
+		site.LunchEndTime = clock(lunchStartHour, 30+10*random.Intn(3))
+	}
+	site.Holidays = make(map[time.Time]struct{})
+	for i := 0; i < random.Intn(5); i++ {
+		site.Holidays[time.Date(2020, 12, 18+random.Intn(20), 0, 0, 0, 0, time.UTC)] = struct{}{}
+	}
+	if random.Intn(3) == 0 {
+		site.WorkingWeekdays = map[time.Weekday]struct{}{time.Monday: {}, time.Tuesday: {}, time.Wednesday: {}, time.Thursday: {}, time.Friday: {}, time.Saturday: {}}
+	}
+	if random.Intn(2) == 0 {
+		site.MaxContinuousHours = float32(2 + random.Intn(4))
+		site.BreakDuration = float32(10*(1+random.Intn(6))) / 60
+	}
+	return site
+}
+
+func TestAddHoursInverseProperty(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < 5000; i++ {
+		site := randomSite(random)
+		startTime := time.Date(2020, 12, 18+random.Intn(14), random.Intn(24), 10*random.Intn(6), 0, 0, time.UTC)
+		hours := float32(1+random.Intn(400)) / 10
+		if actualHours, ok := site.VerifyAddHours(startTime, hours); !ok {
+			t.Errorf("site:%+v, startTime:%v, hours:%v, endTime:%v, actual hours:%v", site, startTime, hours, site.AddHours(startTime, hours), actualHours)
+		}
+	}
+}
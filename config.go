@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+//Tuning parameters loaded from the JSON or YAML config file, unspecified fields keep their current values.
+//Every flag of the GA, AHP, fitness, assignment and driving parameters has a field here
+type tuningConfig struct {
+	PopulationSize           int     `json:"populationSize" yaml:"populationSize"`
+	GenerationsLimit         int     `json:"generationsLimit" yaml:"generationsLimit"`
+	CrossoverRate            float32 `json:"crossoverRate" yaml:"crossoverRate"`
+	CrossoverMethod          string  `json:"crossoverMethod" yaml:"crossoverMethod"`
+	SelectionMethod          string  `json:"selectionMethod" yaml:"selectionMethod"`
+	MutationRate             float32 `json:"mutationRate" yaml:"mutationRate"`
+	ElitismRate              float32 `json:"elitismRate" yaml:"elitismRate"`
+	ImmigrationRate          float32 `json:"immigrationRate" yaml:"immigrationRate"`
+	Deadend                  float32 `json:"deadend" yaml:"deadend"`
+	TourneySampleSize        int     `json:"tourneySampleSize" yaml:"tourneySampleSize"`
+	CrossoverParentsNumber   int     `json:"crossoverParentsNumber" yaml:"crossoverParentsNumber"`
+	MaxCrossoverLength       int     `json:"maxCrossoverLength" yaml:"maxCrossoverLength"`
+	CrossoverLocality        int     `json:"crossoverLocality" yaml:"crossoverLocality"`
+	MaxMutatedGenes          int     `json:"maxMutatedGenes" yaml:"maxMutatedGenes"`
+	MutationTypePreference   float32 `json:"mutationTypePreference" yaml:"mutationTypePreference"`
+	StagnationLimit          int     `json:"stagnationLimit" yaml:"stagnationLimit"`
+	ConvergenceEpsilon       float32 `json:"convergenceEpsilon" yaml:"convergenceEpsilon"`
+	ReshuffleStagnation      bool    `json:"reshuffleStagnation" yaml:"reshuffleStagnation"`
+	AdaptiveMutation         bool    `json:"adaptiveMutation" yaml:"adaptiveMutation"`
+	MinMutationRate          float32 `json:"minMutationRate" yaml:"minMutationRate"`
+	MaxMutationRate          float32 `json:"maxMutationRate" yaml:"maxMutationRate"`
+	MutationRateStep         float32 `json:"mutationRateStep" yaml:"mutationRateStep"`
+	DiversityThreshold       float32 `json:"diversityThreshold" yaml:"diversityThreshold"`
+	WeightDistance           float32 `json:"weightDistance" yaml:"weightDistance"`
+	WeightDelay              float32 `json:"weightDelay" yaml:"weightDelay"`
+	WeightProjectFamiliarity float32 `json:"weightProjectFamiliarity" yaml:"weightProjectFamiliarity"`
+	WeightDemand             float32 `json:"weightDemand" yaml:"weightDemand"`
+	WeightScarcity           float32 `json:"weightScarcity" yaml:"weightScarcity"`
+	WeightTrades             float32 `json:"weightTrades" yaml:"weightTrades"`
+	WeightWorkerOvertime     float32 `json:"weightWorkerOvertime" yaml:"weightWorkerOvertime"`
+	WeightMakespan           float32 `json:"weightMakespan" yaml:"weightMakespan"`
+	WeightDrivingHours       float32 `json:"weightDrivingHours" yaml:"weightDrivingHours"`
+	WeightDelayHours         float32 `json:"weightDelayHours" yaml:"weightDelayHours"`
+	WeightProjectContinuity  float32 `json:"weightProjectContinuity" yaml:"weightProjectContinuity"`
+	WeightProjectIdle        float32 `json:"weightProjectIdle" yaml:"weightProjectIdle"`
+	WeightOvertime           float32 `json:"weightOvertime" yaml:"weightOvertime"`
+	WeightPeakOvertime       float32 `json:"weightPeakOvertime" yaml:"weightPeakOvertime"`
+	AllowOvertime            bool    `json:"allowOvertime" yaml:"allowOvertime"`
+	MaxValueDriving          float32 `json:"maxValueDriving" yaml:"maxValueDriving"`
+	MaxValueDelay            float32 `json:"maxValueDelay" yaml:"maxValueDelay"`
+	MaxValueDemand           float32 `json:"maxValueDemand" yaml:"maxValueDemand"`
+	PinnedDateTimeSnap       float32 `json:"pinnedDateTimeSnap" yaml:"pinnedDateTimeSnap"`
+	HomeDrivingDiscount      float32 `json:"homeDrivingDiscount" yaml:"homeDrivingDiscount"`
+	AssignmentStrategy       string  `json:"assignmentStrategy" yaml:"assignmentStrategy"`
+	SynchronizeCrew          bool    `json:"synchronizeCrew" yaml:"synchronizeCrew"`
+	AddPinnedWorkers         bool    `json:"addPinnedWorkers" yaml:"addPinnedWorkers"`
+	DistanceMetric           string  `json:"distanceMetric" yaml:"distanceMetric"`
+	DrivingSpeed             float64 `json:"drivingSpeed" yaml:"drivingSpeed"`
+	DetourFactor             float64 `json:"detourFactor" yaml:"detourFactor"`
+	IslandsNumber            int     `json:"islandsNumber" yaml:"islandsNumber"`
+	MigrationInterval        int     `json:"migrationInterval" yaml:"migrationInterval"`
+	MigrationSize            int     `json:"migrationSize" yaml:"migrationSize"`
+}
+
+//Snapshot of the current tuning parameters
+func currentTuningConfig() tuningConfig {
+	return tuningConfig{
+		PopulationSize:           populationSize,
+		GenerationsLimit:         generationsLimit,
+		CrossoverRate:            crossoverRate,
+		CrossoverMethod:          crossoverMethod,
+		SelectionMethod:          selectionMethod,
+		MutationRate:             mutationRate,
+		ElitismRate:              elitismRate,
+		ImmigrationRate:          immigrationRate,
+		Deadend:                  deadend,
+		TourneySampleSize:        tourneySampleSize,
+		CrossoverParentsNumber:   crossoverParentsNumber,
+		MaxCrossoverLength:       maxCrossoverLength,
+		CrossoverLocality:        crossoverLocality,
+		MaxMutatedGenes:          maxMutatedGenes,
+		MutationTypePreference:   mutationTypePreference,
+		StagnationLimit:          stagnationLimit,
+		ConvergenceEpsilon:       convergenceEpsilon,
+		ReshuffleStagnation:      reshuffleStagnation,
+		AdaptiveMutation:         adaptiveMutation,
+		MinMutationRate:          minMutationRate,
+		MaxMutationRate:          maxMutationRate,
+		MutationRateStep:         mutationRateStep,
+		DiversityThreshold:       diversityThreshold,
+		WeightDistance:           weightDistance,
+		WeightDelay:              weightDelay,
+		WeightProjectFamiliarity: weightProjectFamiliarity,
+		WeightDemand:             weightDemand,
+		WeightScarcity:           weightScarcity,
+		WeightTrades:             weightTrades,
+		WeightWorkerOvertime:     weightWorkerOvertime,
+		WeightMakespan:           weightMakespan,
+		WeightDrivingHours:       weightDrivingHours,
+		WeightDelayHours:         weightDelayHours,
+		WeightProjectContinuity:  weightProjectContinuity,
+		WeightProjectIdle:        weightProjectIdle,
+		WeightOvertime:           weightOvertime,
+		WeightPeakOvertime:       weightPeakOvertime,
+		AllowOvertime:            allowOvertime,
+		MaxValueDriving:          maxValueDriving,
+		MaxValueDelay:            maxValueDelay,
+		MaxValueDemand:           maxValueDemand,
+		PinnedDateTimeSnap:       pinnedDateTimeSnap,
+		HomeDrivingDiscount:      homeDrivingDiscount,
+		AssignmentStrategy:       assignmentStrategy,
+		SynchronizeCrew:          synchronizeCrew,
+		AddPinnedWorkers:         addPinnedWorkers,
+		DistanceMetric:           distanceMetric,
+		DrivingSpeed:             drivingSpeed,
+		DetourFactor:             detourFactor,
+		IslandsNumber:            islandsNumber,
+		MigrationInterval:        migrationInterval,
+		MigrationSize:            migrationSize,
+	}
+}
+
+//Set the tuning parameters from the config
+func (config tuningConfig) apply() {
+	populationSize = config.PopulationSize
+	generationsLimit = config.GenerationsLimit
+	crossoverRate = config.CrossoverRate
+	crossoverMethod = config.CrossoverMethod
+	selectionMethod = config.SelectionMethod
+	mutationRate = config.MutationRate
+	elitismRate = config.ElitismRate
+	immigrationRate = config.ImmigrationRate
+	deadend = config.Deadend
+	tourneySampleSize = config.TourneySampleSize
+	crossoverParentsNumber = config.CrossoverParentsNumber
+	maxCrossoverLength = config.MaxCrossoverLength
+	crossoverLocality = config.CrossoverLocality
+	maxMutatedGenes = config.MaxMutatedGenes
+	mutationTypePreference = config.MutationTypePreference
+	stagnationLimit = config.StagnationLimit
+	convergenceEpsilon = config.ConvergenceEpsilon
+	reshuffleStagnation = config.ReshuffleStagnation
+	adaptiveMutation = config.AdaptiveMutation
+	minMutationRate = config.MinMutationRate
+	maxMutationRate = config.MaxMutationRate
+	mutationRateStep = config.MutationRateStep
+	diversityThreshold = config.DiversityThreshold
+	weightDistance = config.WeightDistance
+	weightDelay = config.WeightDelay
+	weightProjectFamiliarity = config.WeightProjectFamiliarity
+	weightDemand = config.WeightDemand
+	weightScarcity = config.WeightScarcity
+	weightTrades = config.WeightTrades
+	weightWorkerOvertime = config.WeightWorkerOvertime
+	weightMakespan = config.WeightMakespan
+	weightDrivingHours = config.WeightDrivingHours
+	weightDelayHours = config.WeightDelayHours
+	weightProjectContinuity = config.WeightProjectContinuity
+	weightProjectIdle = config.WeightProjectIdle
+	weightOvertime = config.WeightOvertime
+	weightPeakOvertime = config.WeightPeakOvertime
+	allowOvertime = config.AllowOvertime
+	maxValueDriving = config.MaxValueDriving
+	maxValueDelay = config.MaxValueDelay
+	maxValueDemand = config.MaxValueDemand
+	pinnedDateTimeSnap = config.PinnedDateTimeSnap
+	homeDrivingDiscount = config.HomeDrivingDiscount
+	assignmentStrategy = config.AssignmentStrategy
+	synchronizeCrew = config.SynchronizeCrew
+	addPinnedWorkers = config.AddPinnedWorkers
+	distanceMetric = config.DistanceMetric
+	drivingSpeed = config.DrivingSpeed
+	detourFactor = config.DetourFactor
+	islandsNumber = config.IslandsNumber
+	migrationInterval = config.MigrationInterval
+	migrationSize = config.MigrationSize
+}
+
+//Read JSON or YAML config file on top of the current tuning parameters, .yaml and .yml files are parsed as YAML
+func loadConfig(fileName string) tuningConfig {
+	config := currentTuningConfig()
+	configData, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		logger.Fatal("Couldn't open the "+fileName+" file\r\n", err)
+	}
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(configData, &config)
+	default:
+		err = json.Unmarshal(configData, &config)
+	}
+	if err != nil {
+		logger.Fatal("Couldn't parse the "+fileName+" file\r\n", err)
+	}
+	return config
+}
+
+//Apply config file and parse the command line again, so flags take precedence over the config file
+func applyConfigFile(fileName string) {
+	loadConfig(fileName).apply()
+	err := flag.CommandLine.Parse(os.Args[1:])
+	if err != nil {
+		logger.Fatal(err)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+//Parse valid workers column, alternative crews are separated by "|", e.g. "W1 W2|W3 W4"
+func parseCrews(validWorkers string) []map[string]struct{} {
+	var crews []map[string]struct{}
+	for _, crewWorkers := range strings.Split(validWorkers, "|") {
+		crew := make(map[string]struct{})
+		for _, v := range strings.Fields(crewWorkers) {
+			crew[v] = struct{}{}
+		}
+		crews = append(crews, crew)
+	}
+	return crews
+}
+
+//Format alternative crews back into the valid workers column
+func formatCrews(crews []map[string]struct{}) string {
+	var formattedCrews []string
+	for _, crew := range crews {
+		var crewWorkers []string
+		for workerID := range crew {
+			crewWorkers = append(crewWorkers, workerID)
+		}
+		sort.Strings(crewWorkers)
+		formattedCrews = append(formattedCrews, strings.Join(crewWorkers, " "))
+	}
+	return strings.Join(formattedCrews, "|")
+}
+
+//Select the crew the task workers should be assigned from, nil = any valid worker.
+//Crew of the partially assigned task contains all current assignees, otherwise the crew with the best total fitness of idealWorkerCount workers is selected
+func selectTaskCrew(task scheduledTask, workers []scheduledWorker) map[string]struct{} {
+	crews := tasksDB[task.taskID].crews
+	if len(crews) == 0 {
+		return nil
+	}
+
+	if len(task.assignees) > 0 {
+		for _, crew := range crews {
+			allAssigned := true
+			for _, workerID := range task.assignees {
+				if _, ok := crew[workerID]; !ok {
+					allAssigned = false
+					break
+				}
+			}
+			if allAssigned {
+				return crew
+			}
+		}
+		//Assignees don't belong to the same crew, task can't be completed
+		return make(map[string]struct{})
+	}
+
+	var bestCrew map[string]struct{}
+	var bestCrewFitness float32
+	for _, crew := range crews {
+		//Crew can't complete the task
+		if len(crew) < tasksDB[task.taskID].minWorkerCount {
+			continue
+		}
+		var crewFitness []float32
+		for _, worker := range workers {
+			if _, ok := crew[worker.workerID]; ok {
+				crewFitness = append(crewFitness, worker.fitness)
+			}
+		}
+		sort.Slice(crewFitness, func(i, j int) bool {
+			return crewFitness[i] > crewFitness[j]
+		})
+		var totalFitness float32
+		for i := 0; i < tasksDB[task.taskID].idealWorkerCount && i < len(crewFitness); i++ {
+			totalFitness += crewFitness[i]
+		}
+		if bestCrew == nil || totalFitness > bestCrewFitness {
+			bestCrew = crew
+			bestCrewFitness = totalFitness
+		}
+	}
+	if bestCrew == nil {
+		return make(map[string]struct{})
+	}
+	return bestCrew
+}
@@ -0,0 +1,133 @@
+package main
+
+//Average normalized Kendall tau distance between the task order of the best individual and the task orders of the other individuals,
+//0 = all individuals have the best individual order. Individuals should be sorted by fitness
+func calcPopulationDiversity(individuals []individual) float32 {
+	orders, ranks, ok := calcTaskOrders(individuals)
+	if !ok || len(individuals) < 2 {
+		return 0
+	}
+	tasksNumber := len(orders[0])
+	positions := make([]int, tasksNumber)
+	buffer := make([]int, tasksNumber)
+	var totalDistance float32
+	for i := 1; i < len(individuals); i++ {
+		totalDistance += calcOrderDistance(orders[0], ranks[i], positions, buffer)
+	}
+	return totalDistance / float32(len(individuals)-1)
+}
+
+//Raise the mutation rate by mutationRateStep if the diversity is below diversityThreshold and lower it otherwise,
+//the new rate is kept inside the minMutationRate-maxMutationRate range
+func adaptMutationRate(rate float32, diversity float32) float32 {
+	if diversity < diversityThreshold {
+		rate += mutationRateStep
+	} else {
+		rate -= mutationRateStep
+	}
+	if rate > maxMutationRate {
+		rate = maxMutationRate
+	}
+	if rate < minMutationRate {
+		rate = minMutationRate
+	}
+	return rate
+}
+
+//Population genotype diversity statistics
+type diversityStatistics struct {
+	uniqueGenotypes      int     //number of individuals with distinct hashes
+	averageOrderDistance float32 //average normalized Kendall tau distance between the task orders of all individual pairs, 0 = identical orders
+}
+
+//Calculate the unique genotypes number and the average pairwise task order distance.
+//Task orders are converted to the task indexes once per individual, so the pairs are compared without the allocations
+func diversityStats(individuals []individual) diversityStatistics {
+	var stats diversityStatistics
+	stats.uniqueGenotypes = len(calcIndividualsHash(individuals))
+	orders, ranks, ok := calcTaskOrders(individuals)
+	if !ok || len(individuals) < 2 {
+		return stats
+	}
+	tasksNumber := len(orders[0])
+	positions := make([]int, tasksNumber)
+	buffer := make([]int, tasksNumber)
+	pairsNumber := 0
+	var totalDistance float32
+	for i := range individuals {
+		for j := i + 1; j < len(individuals); j++ {
+			totalDistance += calcOrderDistance(orders[i], ranks[j], positions, buffer)
+			pairsNumber++
+		}
+	}
+	stats.averageOrderDistance = totalDistance / float32(pairsNumber)
+	return stats
+}
+
+//Convert the task orders to the task indexes of the first individual order and to the positions of the task indexes in the individual order.
+//Returns false if the individuals have different number of tasks
+func calcTaskOrders(individuals []individual) ([][]int, [][]int, bool) {
+	if len(individuals) == 0 {
+		return nil, nil, false
+	}
+	tasksNumber := len(individuals[0].tasks)
+	taskIndexes := make(map[string]int, tasksNumber)
+	for i, v := range individuals[0].tasks {
+		taskIndexes[v.taskID] = i
+	}
+	orders := make([][]int, len(individuals))
+	ranks := make([][]int, len(individuals))
+	for i, individual := range individuals {
+		if len(individual.tasks) != tasksNumber {
+			return nil, nil, false
+		}
+		orders[i] = make([]int, tasksNumber)
+		ranks[i] = make([]int, tasksNumber)
+		for j, v := range individual.tasks {
+			orders[i][j] = taskIndexes[v.taskID]
+			ranks[i][taskIndexes[v.taskID]] = j
+		}
+	}
+	return orders, ranks, true
+}
+
+//Calculate the normalized Kendall tau distance as the share of task pairs ordered differently in 2 task orders, 0 = identical orders, 1 = reversed orders.
+//First order is the task indexes, second order is the positions of the task indexes, positions and buffer are reused between the calls
+func calcOrderDistance(firstOrder, secondRanks []int, positions, buffer []int) float32 {
+	tasksNumber := len(firstOrder)
+	if tasksNumber < 2 {
+		return 0
+	}
+	//Discordant pairs are the inversions of the second order positions listed in the first order
+	for i, v := range firstOrder {
+		positions[i] = secondRanks[v]
+	}
+	inversions := countInversions(positions[:tasksNumber], buffer[:tasksNumber])
+	return float32(inversions) / float32(tasksNumber*(tasksNumber-1)/2)
+}
+
+//Count inversions with the merge sort, values are sorted in place
+func countInversions(values []int, buffer []int) int {
+	if len(values) < 2 {
+		return 0
+	}
+	middle := len(values) / 2
+	inversions := countInversions(values[:middle], buffer[:middle]) + countInversions(values[middle:], buffer[middle:])
+	i, j, k := 0, middle, 0
+	for i < middle && j < len(values) {
+		if values[j] < values[i] {
+			//All remaining left values are greater than the right value
+			inversions += middle - i
+			buffer[k] = values[j]
+			j++
+		} else {
+			buffer[k] = values[i]
+			i++
+		}
+		k++
+	}
+	k += copy(buffer[k:], values[i:middle])
+	copy(buffer[k:], values[j:])
+	copy(values, buffer)
+	return inversions
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"gitlab.com/alex.skylight/sambo/location"
+)
+
+const drivingTimeDBFileName string = "driving_time.csv"
+
+//Source of the driving time between workers and projects, haversine is used if driving times are not defined
+var drivingTimeProvider location.RouteProvider = location.HaversineProvider{}
+
+//Calculate driving time in hours from the origin to the destination, haversine is used if the provider fails
+func calcDrivingTime(originLatitude, originLongitude, destinationLatitude, destinationLongitude float64) float32 {
+	drivingTime, err := drivingTimeProvider.DrivingTime(originLatitude, originLongitude, destinationLatitude, destinationLongitude)
+	if err != nil {
+		logger.Warn("Couldn't get driving time from the provider, using haversine: ", err)
+		return location.CalcDrivingTime(originLatitude, originLongitude, destinationLatitude, destinationLongitude)
+	}
+	return drivingTime
+}
+
+//Calculate driving time in hours from the origin to the project, haversine driving time uses the project driving speed if it's defined
+func calcProjectDrivingTime(originLatitude, originLongitude float64, projectID string) float32 {
+	project := projectsDB[projectID]
+	if provider, ok := drivingTimeProvider.(location.HaversineProvider); ok && project.drivingSpeed > 0 {
+		provider.Speed = project.drivingSpeed
+		drivingTime, _ := provider.DrivingTime(originLatitude, originLongitude, project.latitude, project.longitude)
+		return drivingTime
+	}
+	return calcDrivingTime(originLatitude, originLongitude, project.latitude, project.longitude)
+}
+
+//Discount the first driving leg from the worker home by homeDrivingDiscount
+func discountHomeDriving(worker scheduledWorker, drivingTime float32) float32 {
+	if worker.started {
+		return drivingTime
+	}
+	return drivingTime * (1 - homeDrivingDiscount)
+}
+
+//Create Google Maps provider and prefetch driving times from worker homes and projects to all projects
+func newGMapsProvider() *location.GMapsProvider {
+	provider := location.NewGMapsProvider()
+	provider.MaxConcurrentRequests = gmapsConcurrency
+	provider.QPS = gmapsQPS
+	provider.Fallback = drivingTimeProvider
+	var origins, destinations []location.Point
+	for _, project := range projectsDB {
+		destinations = append(destinations, location.Point{Latitude: project.latitude, Longitude: project.longitude})
+	}
+	origins = append(origins, destinations...)
+	for _, worker := range workersDB {
+		origins = append(origins, location.Point{Latitude: worker.latitude, Longitude: worker.longitude})
+	}
+	err := provider.Prefetch(origins, destinations)
+	if err != nil {
+		logger.Warn("Couldn't prefetch driving times, haversine will be used for the missing routes: ", err)
+	}
+	return provider
+}
+
+//Parse and validate the latitude and longitude columns of the CSV record, label and 1-based column numbers are used in the error messages
+func parseLatLon(record []string, latitudeColumn, longitudeColumn int, label string) (float64, float64, error) {
+	latitude, err := strconv.ParseFloat(record[latitudeColumn], 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("couldn't parse %v latitude value in column %v: %v", label, latitudeColumn+1, err)
+	}
+	longitude, err := strconv.ParseFloat(record[longitudeColumn], 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("couldn't parse %v longitude value in column %v: %v", label, longitudeColumn+1, err)
+	}
+	if err = location.ValidateCoordinates(latitude, longitude); err != nil {
+		return 0, 0, fmt.Errorf("invalid %v coordinates in columns %v and %v, latitude and longitude could be swapped: %v", label, latitudeColumn+1, longitudeColumn+1, err)
+	}
+	return latitude, longitude, nil
+}
+
+//Find coordinates of the worker home or project by ID
+func findLocation(id string) (float64, float64, bool) {
+	if project, ok := projectsDB[id]; ok {
+		return project.latitude, project.longitude, true
+	}
+	if worker, ok := workersDB[id]; ok {
+		return worker.latitude, worker.longitude, true
+	}
+	return 0, 0, false
+}
+
+//Read directional driving times between worker homes and projects into the matrix provider
+func readDrivingTimeCSV() (*location.MatrixProvider, error) {
+	provider := location.NewMatrixProvider()
+	drivingTimeDBFile, err := os.Open(drivingTimeDBFileName)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open the %v file: %v", drivingTimeDBFileName, err)
+	}
+	rowErrors := csvErrors{fileName: drivingTimeDBFileName}
+	drivingTimeData := newCSVReader(drivingTimeDBFile)
+	drivingTimeColumns, err := readCSVHeader(drivingTimeData, []string{"origin", "destination", "hours"})
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", drivingTimeDBFileName, err)
+	}
+	line := 1
+	for {
+		drivingTimeRecord, err := drivingTimeData.Read()
+		line++
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			rowErrors.add(line, drivingTimeRecord, "%v", err)
+			continue
+		}
+		originLatitude, originLongitude, ok := findLocation(drivingTimeColumns.get(drivingTimeRecord, "origin"))
+		if !ok {
+			rowErrors.add(line, drivingTimeRecord, "origin is missing: %v", drivingTimeColumns.get(drivingTimeRecord, "origin"))
+			continue
+		}
+		destinationLatitude, destinationLongitude, ok := findLocation(drivingTimeColumns.get(drivingTimeRecord, "destination"))
+		if !ok {
+			rowErrors.add(line, drivingTimeRecord, "destination is missing: %v", drivingTimeColumns.get(drivingTimeRecord, "destination"))
+			continue
+		}
+		hours, err := strconv.ParseFloat(drivingTimeColumns.get(drivingTimeRecord, "hours"), 32)
+		if err != nil {
+			rowErrors.add(line, drivingTimeRecord, "couldn't parse driving time value: %v", err)
+			continue
+		}
+		provider.SetDrivingTime(originLatitude, originLongitude, destinationLatitude, destinationLongitude, float32(hours))
+	}
+	return provider, rowErrors.err()
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	equipmentDBFileName     string = "equipment_info.csv"
+	taskEquipmentDBFileName string = "task_equipment.csv"
+)
+
+type equipment struct {
+	name     string
+	quantity int //number of units in the pool
+}
+
+var equipmentDB map[string]equipment //key is the equipment ID
+
+func readEquipmentInfoCSV() (map[string]equipment, error) {
+	var equipmentTemp equipment
+	equipmentDB := make(map[string]equipment)
+	equipmentDBFile, err := os.Open(equipmentDBFileName)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open the %v file: %v", equipmentDBFileName, err)
+	}
+	rowErrors := csvErrors{fileName: equipmentDBFileName}
+	equipmentData := newCSVReader(equipmentDBFile)
+	equipmentColumns, err := readCSVHeader(equipmentData, []string{"id", "name", "quantity"})
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", equipmentDBFileName, err)
+	}
+	line := 1
+	for {
+		equipmentRecord, err := equipmentData.Read()
+		line++
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			rowErrors.add(line, equipmentRecord, "%v", err)
+			continue
+		}
+		equipmentTemp.name = equipmentColumns.get(equipmentRecord, "name")
+		equipmentTemp.quantity, err = strconv.Atoi(equipmentColumns.get(equipmentRecord, "quantity"))
+		if err != nil {
+			rowErrors.add(line, equipmentRecord, "couldn't parse equipment quantity value: %v", err)
+			continue
+		}
+		equipmentDB[equipmentColumns.get(equipmentRecord, "id")] = equipmentTemp
+	}
+	return equipmentDB, rowErrors.err()
+}
+
+//Read equipment requirements and add them to the tasks
+func readTaskEquipmentCSV(tasks map[string]task) (map[string]task, error) {
+	taskEquipmentDBFile, err := os.Open(taskEquipmentDBFileName)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open the %v file: %v", taskEquipmentDBFileName, err)
+	}
+	rowErrors := csvErrors{fileName: taskEquipmentDBFileName}
+	taskEquipmentData := newCSVReader(taskEquipmentDBFile)
+	taskEquipmentColumns, err := readCSVHeader(taskEquipmentData, []string{"project", "task", "equipment", "quantity"})
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", taskEquipmentDBFileName, err)
+	}
+	line := 1
+	for {
+		taskEquipmentRecord, err := taskEquipmentData.Read()
+		line++
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			rowErrors.add(line, taskEquipmentRecord, "%v", err)
+			continue
+		}
+		taskID := taskEquipmentColumns.get(taskEquipmentRecord, "project") + "." + taskEquipmentColumns.get(taskEquipmentRecord, "task")
+		tempTask, ok := tasks[taskID]
+		if !ok {
+			rowErrors.add(line, taskEquipmentRecord, "task is missing: %v", taskID)
+			continue
+		}
+		quantity, err := strconv.Atoi(taskEquipmentColumns.get(taskEquipmentRecord, "quantity"))
+		if err != nil {
+			rowErrors.add(line, taskEquipmentRecord, "couldn't parse task equipment quantity value: %v", err)
+			continue
+		}
+		if tempTask.equipment == nil {
+			tempTask.equipment = make(map[string]int)
+		}
+		tempTask.equipment[taskEquipmentColumns.get(taskEquipmentRecord, "equipment")] += quantity
+		tasks[taskID] = tempTask
+	}
+	return tasks, rowErrors.err()
+}
+
+func verifyTaskEquipment() {
+	for k, task := range tasksDB {
+		for equipmentID, quantity := range task.equipment {
+			if _, ok := equipmentDB[equipmentID]; !ok {
+				logger.Error("Original task: ", task)
+				logger.Fatal("Equipment is missing: ", equipmentID)
+			}
+			//Task would wait forever for the equipment
+			if quantity > equipmentDB[equipmentID].quantity {
+				logger.Errorf("Task ID:%v, equipment ID:%v", k, equipmentID)
+				logger.Fatalf("Task requires %v units, but only %v available", quantity, equipmentDB[equipmentID].quantity)
+			}
+		}
+	}
+}
+
+//Find the earliest start time, not before startTime, when all required equipment is available for the whole task duration.
+//Equipment is booked by all other tasks with at least one worker assigned
+func findEquipmentStartTime(taskID string, startTime time.Time, tasks []scheduledTask) time.Time {
+	site := taskSite(taskID)
+	for {
+		stopTime := site.AddTaskHours(startTime, tasksDB[taskID].duration)
+		var nextStartTime time.Time
+		for equipmentID, quantity := range tasksDB[taskID].equipment {
+			//Count all units booked in the task time range
+			usedQuantity := 0
+			var earliestReleaseTime time.Time
+			for _, bookingTask := range tasks {
+				bookedQuantity, ok := tasksDB[bookingTask.taskID].equipment[equipmentID]
+				if !ok || bookingTask.taskID == taskID || bookingTask.stopTime.IsZero() {
+					continue
+				}
+				if bookingTask.startTime.Before(stopTime) && startTime.Before(bookingTask.stopTime) {
+					usedQuantity += bookedQuantity
+					if earliestReleaseTime.IsZero() || bookingTask.stopTime.Before(earliestReleaseTime) {
+						earliestReleaseTime = bookingTask.stopTime
+					}
+				}
+			}
+			if usedQuantity+quantity > equipmentDB[equipmentID].quantity && (nextStartTime.IsZero() || earliestReleaseTime.Before(nextStartTime)) {
+				nextStartTime = earliestReleaseTime
+			}
+		}
+		if nextStartTime.IsZero() {
+			return startTime
+		}
+		logger.Debugf("Equipment is not available, task:%v, startTime:%v, nextStartTime:%v", taskID, startTime, nextStartTime)
+		startTime = nextStartTime
+	}
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//JSON representation of the scheduled task, shared by all JSON exporters
+type scheduledTaskJSON struct {
+	TaskID        string   `json:"taskID"`
+	TaskName      string   `json:"taskName"`
+	ProjectID     string   `json:"projectID"`
+	ProjectName   string   `json:"projectName"`
+	Start         string   `json:"start"`
+	Stop          string   `json:"stop"`
+	AssigneeIDs   []string `json:"assigneeIDs"`
+	AssigneeNames []string `json:"assigneeNames"`
+}
+
+//Layout of the schedule output, configured outputDateTimeFormat overrides the output default layout
+func outputLayout(defaultLayout string) string {
+	if outputDateTimeFormat != "" {
+		return outputDateTimeFormat
+	}
+	return defaultLayout
+}
+
+//Format datetime for the schedule output, zero time is an empty string
+func formatOutputDateTime(dateTime time.Time, defaultLayout string) string {
+	if dateTime.IsZero() {
+		return ""
+	}
+	return dateTime.Format(outputLayout(defaultLayout))
+}
+
+//Layout should keep the full date and time up to minutes, so the exported schedule can be parsed back
+func validateDateTimeFormat(layout string) {
+	referenceTime := time.Date(2021, time.March, 14, 15, 9, 0, 0, time.UTC)
+	parsedTime, err := time.Parse(layout, referenceTime.Format(layout))
+	if err != nil {
+		logger.Fatal("Couldn't parse datetime formatted with the "+layout+" layout\r\n", err)
+	}
+	if !parsedTime.Equal(referenceTime) {
+		logger.Fatalf("Datetime layout %v loses the date or time, %v is parsed back as %v", layout, referenceTime, parsedTime)
+	}
+}
+
+//Format datetime for the JSON export, zero time is an empty string
+func formatJSONTime(dateTime time.Time) string {
+	return formatOutputDateTime(dateTime, time.RFC3339)
+}
+
+func newScheduledTaskJSON(task scheduledTask) scheduledTaskJSON {
+	taskJSON := scheduledTaskJSON{
+		TaskID:        strings.Split(task.taskID, ".")[1],
+		TaskName:      tasksDB[task.taskID].name,
+		ProjectID:     tasksDB[task.taskID].project,
+		ProjectName:   projectsDB[tasksDB[task.taskID].project].name,
+		Start:         formatJSONTime(task.startTime),
+		Stop:          formatJSONTime(task.stopTime),
+		AssigneeIDs:   make([]string, 0, len(task.assignees)),
+		AssigneeNames: make([]string, 0, len(task.assignees)),
+	}
+	for _, workerID := range task.assignees {
+		taskJSON.AssigneeIDs = append(taskJSON.AssigneeIDs, workerID)
+		taskJSON.AssigneeNames = append(taskJSON.AssigneeNames, workersDB[workerID].name)
+	}
+	return taskJSON
+}
+
+//Write the individual tasks as JSON Lines, one task object per line
+func writeScheduleJSONL(fileName string, individual individual) {
+	scheduleFile, err := os.Create(fileName)
+	if err != nil {
+		logger.Fatal("Couldn't create the "+fileName+" file\r\n", err)
+	}
+	defer scheduleFile.Close()
+	scheduleWriter := bufio.NewWriter(scheduleFile)
+	//Encoder adds a newline after every object
+	encoder := json.NewEncoder(scheduleWriter)
+	for _, task := range individual.tasks {
+		err = encoder.Encode(newScheduledTaskJSON(task))
+		if err != nil {
+			logger.Fatal("Couldn't write the "+fileName+" file\r\n", err)
+		}
+	}
+	err = scheduleWriter.Flush()
+	if err != nil {
+		logger.Fatal("Couldn't write the "+fileName+" file\r\n", err)
+	}
+}
+
+//JSON result document with the schedule summary and all the tasks
+type scheduleJSON struct {
+	Summary scheduleSummaryJSON `json:"summary"`
+	Tasks   []scheduledTaskJSON `json:"tasks"`
+}
+
+type scheduleSummaryJSON struct {
+	Fitness                float32           `json:"fitness"`
+	UnscheduledTasks       int               `json:"unscheduledTasks"`
+	FinishDateTime         string            `json:"finishDateTime"`
+	TotalDrivingHours      float32           `json:"totalDrivingHours"`
+	ProjectsFinishDateTime map[string]string `json:"projectsFinishDateTime"` //key is the project ID
+}
+
+//Write the individual as a single JSON document
+func writeScheduleJSON(fileName string, individual individual) {
+	schedule := scheduleJSON{
+		Summary: scheduleSummaryJSON{
+			Fitness:                individual.fitness,
+			UnscheduledTasks:       individual.fitnessData.unscheduledTasks,
+			FinishDateTime:         formatJSONTime(individual.fitnessData.finishDateTime),
+			TotalDrivingHours:      individual.fitnessData.drivingHours,
+			ProjectsFinishDateTime: make(map[string]string),
+		},
+		Tasks: make([]scheduledTaskJSON, 0, len(individual.tasks)),
+	}
+	for projectID, finishDateTime := range calcProjectsFinishDateTime(individual) {
+		schedule.Summary.ProjectsFinishDateTime[projectID] = formatJSONTime(finishDateTime)
+	}
+	for _, task := range individual.tasks {
+		schedule.Tasks = append(schedule.Tasks, newScheduledTaskJSON(task))
+	}
+	scheduleFile, err := os.Create(fileName)
+	if err != nil {
+		logger.Fatal("Couldn't create the "+fileName+" file\r\n", err)
+	}
+	defer scheduleFile.Close()
+	encoder := json.NewEncoder(scheduleFile)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(schedule)
+	if err != nil {
+		logger.Fatal("Couldn't write the "+fileName+" file\r\n", err)
+	}
+}
+
+//Format datetime for the CSV export, zero time is an empty string
+func formatCSVDateTime(dateTime time.Time) string {
+	if dateTime.IsZero() {
+		return ""
+	}
+	return dateTime.Format(defaultDateTimeFormat)
+}
+
+//Convert scheduled task into the task CSV record, scheduled start time and assignees are pinned
+func newTaskInfoRecord(task scheduledTask) []string {
+	taskInfo := tasksDB[task.taskID]
+	var validWorkers, prerequisites, lagHours, dependencyTypes, pinnedWorkers []string
+	for workerID := range taskInfo.validWorkers {
+		validWorkers = append(validWorkers, workerID)
+	}
+	sort.Strings(validWorkers)
+	formattedValidWorkers := strings.Join(validWorkers, " ")
+	if len(taskInfo.crews) > 0 {
+		formattedValidWorkers = formatCrews(taskInfo.crews)
+	}
+	for prerequisiteID := range taskInfo.prerequisites {
+		prerequisites = append(prerequisites, prerequisiteID)
+	}
+	sort.Strings(prerequisites)
+	for i, prerequisiteID := range prerequisites {
+		lagHours = append(lagHours, strconv.FormatFloat(float64(taskInfo.prerequisites[prerequisiteID]), 'f', -1, 32))
+		dependencyType, ok := taskInfo.dependencyTypes[prerequisiteID]
+		if !ok {
+			dependencyType = finishToStart
+		}
+		dependencyTypes = append(dependencyTypes, dependencyType)
+		prerequisites[i] = strings.Split(prerequisiteID, ".")[1]
+	}
+
+	pinnedDateTime := taskInfo.pinnedDateTime
+	pinMode := taskInfo.pinMode
+	if isTaskScheduled(task) && !task.startTime.IsZero() {
+		pinnedDateTime = task.startTime
+		pinMode = pinOnlyMode
+		//The same worker can be listed only once
+		assigned := make(map[string]struct{})
+		for _, workerID := range task.assignees {
+			if _, ok := assigned[workerID]; !ok {
+				assigned[workerID] = struct{}{}
+				pinnedWorkers = append(pinnedWorkers, workerID)
+			}
+		}
+	} else {
+		for workerID := range taskInfo.pinnedWorkerIDs {
+			pinnedWorkers = append(pinnedWorkers, workerID)
+		}
+		sort.Strings(pinnedWorkers)
+	}
+
+	var allocation string
+	if taskInfo.allocation < 1 {
+		allocation = strconv.FormatFloat(float64(taskInfo.allocation), 'f', -1, 32)
+	}
+	var optionalReward string
+	if taskInfo.optionalReward > 0 {
+		optionalReward = strconv.FormatFloat(float64(taskInfo.optionalReward), 'f', -1, 32)
+	}
+	return []string{
+		taskInfo.project,
+		strings.Split(task.taskID, ".")[1],
+		taskInfo.name,
+		formattedValidWorkers,
+		strings.Join(prerequisites, " "),
+		strconv.Itoa(taskInfo.idealWorkerCount),
+		strconv.Itoa(taskInfo.minWorkerCount),
+		strconv.Itoa(taskInfo.maxWorkerCount),
+		strconv.FormatFloat(float64(taskInfo.duration), 'f', -1, 32),
+		strings.Join(lagHours, " "),
+		formatCSVDateTime(pinnedDateTime),
+		strings.Join(pinnedWorkers, " "),
+		formatCSVDateTime(taskInfo.windowStart),
+		formatCSVDateTime(taskInfo.windowEnd),
+		optionalReward,
+		pinMode,
+		"", //recurring tasks are exported as the expanded instances
+		"",
+		strings.Join(taskInfo.trades, " "),
+		strings.Join(dependencyTypes, " "),
+		allocation,
+		formatCSVDateTime(taskInfo.latestStart),
+	}
+}
+
+//Write the individual tasks in the task CSV format, so the next run honors them as a fixed baseline
+func writeTaskInfoCSV(fileName string, individual individual) {
+	taskInfoFile, err := os.Create(fileName)
+	if err != nil {
+		logger.Fatal("Couldn't create the "+fileName+" file\r\n", err)
+	}
+	defer taskInfoFile.Close()
+	taskInfoWriter := csv.NewWriter(taskInfoFile)
+	err = taskInfoWriter.Write([]string{"project", "id", "name", "valid_workers", "prerequisites", "ideal_worker_count", "min_worker_count", "max_worker_count", "duration", "lag_hours", "pinned_datetime", "pinned_workers", "window_start", "window_end", "optional_reward", "pin_mode", "recurrence_days", "recurrence_count", "trades", "dependency_types", "allocation", "latest_start"})
+	if err != nil {
+		logger.Fatal("Couldn't write the "+fileName+" file\r\n", err)
+	}
+	for _, task := range individual.tasks {
+		err = taskInfoWriter.Write(newTaskInfoRecord(task))
+		if err != nil {
+			logger.Fatal("Couldn't write the "+fileName+" file\r\n", err)
+		}
+	}
+	taskInfoWriter.Flush()
+	if err = taskInfoWriter.Error(); err != nil {
+		logger.Fatal("Couldn't write the "+fileName+" file\r\n", err)
+	}
+}
+
+//Convert scheduled task into the schedule CSV record, columns follow the pretty printed schedule
+func newScheduleRecord(task scheduledTask) []string {
+	taskInfo := tasksDB[task.taskID]
+	var workerNames, predecessors, pinnedWorkers []string
+	for _, workerID := range task.assignees {
+		workerNames = append(workerNames, workersDB[workerID].name)
+	}
+	for prerequisiteID := range taskInfo.prerequisites {
+		predecessors = append(predecessors, prerequisiteID)
+	}
+	sort.Strings(predecessors)
+	for workerID := range taskInfo.pinnedWorkerIDs {
+		pinnedWorkers = append(pinnedWorkers, workersDB[workerID].name)
+	}
+	sort.Strings(pinnedWorkers)
+	return []string{
+		formatOutputDateTime(task.startTime, defaultDateTimeFormat),
+		formatOutputDateTime(task.stopTime, defaultDateTimeFormat),
+		projectsDB[taskInfo.project].name,
+		taskInfo.name,
+		strings.Join(workerNames, " "),
+		strings.Join(task.assignees, " "),
+		strings.Split(task.taskID, ".")[1],
+		taskInfo.project,
+		strings.Join(predecessors, " "),
+		strings.Join(pinnedWorkers, " "),
+		formatOutputDateTime(taskInfo.pinnedDateTime, defaultDateTimeFormat),
+	}
+}
+
+//Write the individual schedule as CSV with the header row
+func writeScheduleCSV(fileName string, individual individual) {
+	scheduleFile, err := os.Create(fileName)
+	if err != nil {
+		logger.Fatal("Couldn't create the "+fileName+" file\r\n", err)
+	}
+	defer scheduleFile.Close()
+	scheduleWriter := csv.NewWriter(scheduleFile)
+	err = scheduleWriter.Write([]string{"start", "stop", "projectName", "taskName", "workerNames", "workerIDs", "taskID", "projectID", "predecessors", "pinnedWorkers", "pinnedDateTime"})
+	if err != nil {
+		logger.Fatal("Couldn't write the "+fileName+" file\r\n", err)
+	}
+	for _, task := range individual.tasks {
+		err = scheduleWriter.Write(newScheduleRecord(task))
+		if err != nil {
+			logger.Fatal("Couldn't write the "+fileName+" file\r\n", err)
+		}
+	}
+	scheduleWriter.Flush()
+	if err = scheduleWriter.Error(); err != nil {
+		logger.Fatal("Couldn't write the "+fileName+" file\r\n", err)
+	}
+}
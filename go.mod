@@ -6,4 +6,5 @@ require (
 	github.com/smartystreets/goconvey v1.6.4
 	github.com/withmandala/go-log v0.1.0
 	golang.org/x/crypto v0.0.0-20201221181555-eec23a3978ad
+	gopkg.in/yaml.v2 v2.4.0
 )
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"time"
+)
+
+const icsDateTimeFormat string = "20060102T150405" //floating local time of the iCalendar events
+
+//Escape iCalendar TEXT value
+func escapeICSText(text string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(text)
+}
+
+//Fold iCalendar content line to 75 octets, continuation lines start with a space
+func foldICSLine(line string) string {
+	var folded strings.Builder
+	lineLength := 0
+	for _, r := range line {
+		runeLength := len(string(r))
+		if lineLength+runeLength > 75 {
+			folded.WriteString("\r\n ")
+			lineLength = 1
+		}
+		folded.WriteRune(r)
+		lineLength += runeLength
+	}
+	folded.WriteString("\r\n")
+	return folded.String()
+}
+
+//Convert scheduled task into the iCalendar event lines
+func newICSEvent(task scheduledTask, timeStamp time.Time) []string {
+	taskInfo := tasksDB[task.taskID]
+	//Times are emitted as floating local time of the schedule location
+	startTime := task.startTime.In(scheduleStartTime.Location())
+	stopTime := task.stopTime.In(scheduleStartTime.Location())
+	event := []string{
+		"BEGIN:VEVENT",
+		"UID:" + task.taskID + "@sambo",
+		"DTSTAMP:" + timeStamp.UTC().Format(icsDateTimeFormat) + "Z",
+		"DTSTART:" + startTime.Format(icsDateTimeFormat),
+		"DTEND:" + stopTime.Format(icsDateTimeFormat),
+		"SUMMARY:" + escapeICSText(taskInfo.name+" - "+projectsDB[taskInfo.project].name),
+	}
+	//The same worker is listed only once
+	assigned := make(map[string]struct{})
+	for _, workerID := range task.assignees {
+		if _, ok := assigned[workerID]; ok {
+			continue
+		}
+		assigned[workerID] = struct{}{}
+		event = append(event, `ATTENDEE;CN="`+strings.ReplaceAll(workersDB[workerID].name, `"`, "'")+`":urn:sambo:worker:`+workerID)
+	}
+	return append(event, "END:VEVENT")
+}
+
+//Write scheduled tasks of the individual as iCalendar events, unscheduled tasks are skipped
+func exportICS(fileName string, individual individual) {
+	icsFile, err := os.Create(fileName)
+	if err != nil {
+		logger.Fatal("Couldn't create the "+fileName+" file\r\n", err)
+	}
+	defer icsFile.Close()
+	icsWriter := bufio.NewWriter(icsFile)
+	lines := []string{"BEGIN:VCALENDAR", "VERSION:2.0", "PRODID:-//sambo//schedule//EN"}
+	timeStamp := time.Now()
+	for _, task := range individual.tasks {
+		if !isTaskScheduled(task) || task.startTime.IsZero() || task.stopTime.IsZero() {
+			continue
+		}
+		lines = append(lines, newICSEvent(task, timeStamp)...)
+	}
+	lines = append(lines, "END:VCALENDAR")
+	for _, line := range lines {
+		_, err = icsWriter.WriteString(foldICSLine(line))
+		if err != nil {
+			logger.Fatal("Couldn't write the "+fileName+" file\r\n", err)
+		}
+	}
+	err = icsWriter.Flush()
+	if err != nil {
+		logger.Fatal("Couldn't write the "+fileName+" file\r\n", err)
+	}
+}
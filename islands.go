@@ -0,0 +1,102 @@
+package main
+
+import "sync"
+
+//Evolve islandsNumber independent populations in parallel and migrate the best individuals to the next island in the ring every migrationInterval generations.
+//Every island keeps its evolution state between the migration rounds. All islands are merged into a single population sorted by fitness in the end
+func runIslandsGA(maxGenerations int, stagnationLimit int, epsilon float32) (population, int) {
+	islands := make([]population, islandsNumber)
+	states := make([]gaState, islandsNumber)
+	for i := range islands {
+		islands[i] = generatePopulation()
+	}
+	generationsNumber := 0
+	for generationsNumber < maxGenerations {
+		roundGenerations := migrationInterval
+		if roundGenerations > maxGenerations-generationsNumber {
+			roundGenerations = maxGenerations - generationsNumber
+		}
+		var wg sync.WaitGroup
+		for i := range islands {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				islands[i], states[i] = runGA(islands[i], states[i], roundGenerations, stagnationLimit, epsilon, nil)
+			}(i)
+		}
+		wg.Wait()
+
+		//Islands stopped by the stagnation limit run fewer generations
+		converged := true
+		for _, state := range states {
+			if state.generationsNumber > generationsNumber {
+				generationsNumber = state.generationsNumber
+			}
+			if !state.converged {
+				converged = false
+			}
+		}
+		for i, island := range islands {
+			logger.Infof("Island %v best fitness = %v", i, island.individuals[0].fitness)
+		}
+		if converged {
+			logger.Info("All islands are converged, stopping")
+			break
+		}
+		if generationsNumber < maxGenerations {
+			logger.Info("Migrating individuals between islands...")
+			migrateIndividuals(islands)
+		}
+	}
+	return mergeIslands(islands), generationsNumber
+}
+
+//Replace the worst individuals of every island with copies of the best migrationSize individuals of the previous island.
+//Migrants already present on the destination island are skipped, so the island keeps unique individuals
+func migrateIndividuals(islands []population) {
+	//Migrants are selected before any island is changed
+	migrants := make([][]individual, len(islands))
+	for i, island := range islands {
+		size := migrationSize
+		if size > len(island.individuals) {
+			size = len(island.individuals)
+		}
+		migrants[i] = copyIndividuals(island.individuals[:size])
+	}
+	for i := range islands {
+		destination := &islands[(i+1)%len(islands)]
+		destination.hashes = calcIndividualsHash(destination.individuals)
+		worstIndividual := len(destination.individuals) - 1
+		for _, migrant := range migrants[i] {
+			migrantHash := calcIndividualHash(migrant)
+			if _, ok := destination.hashes[migrantHash]; ok || worstIndividual < 0 {
+				continue
+			}
+			delete(destination.hashes, calcIndividualHash(destination.individuals[worstIndividual]))
+			destination.individuals[worstIndividual] = migrant
+			destination.hashes[migrantHash] = worstIndividual
+			worstIndividual--
+		}
+		//Migrants keep their fitness, so the destination island is sorted again and the hashes point to the new indexes
+		sortPopulation(destination.individuals)
+		destination.hashes = calcIndividualsHash(destination.individuals)
+	}
+}
+
+//Merge all islands into a single population without duplicates
+func mergeIslands(islands []population) population {
+	var merged population
+	merged.hashes = make(map[uint64]int)
+	for _, island := range islands {
+		for _, v := range island.individuals {
+			hash := calcIndividualHash(v)
+			if _, ok := merged.hashes[hash]; !ok {
+				merged.hashes[hash] = len(merged.individuals)
+				merged.individuals = append(merged.individuals, v)
+			}
+		}
+	}
+	sortPopulation(merged.individuals)
+	merged.hashes = calcIndividualsHash(merged.individuals)
+	return merged
+}
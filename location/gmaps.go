@@ -0,0 +1,240 @@
+package location
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/withmandala/go-log"
+)
+
+const (
+	//GMapsAPIKeyEnv is the environment variable with the Google Maps API key
+	GMapsAPIKeyEnv         string  = "GMAPS_API_KEY"
+	gmapsDistanceMatrixURL string  = "https://maps.googleapis.com/maps/api/distancematrix/json"
+	gmapsMaxBatchSize      int     = 10    //max origins or destinations per request, 10x10 = 100 elements limit
+	gmapsCachePrecision    float64 = 10000 //coordinates are rounded to 4 decimals (~11 m) for the cache key
+	gmapsDefaultConcurrent int     = 4     //default max number of concurrent requests
+	gmapsDefaultQPS        float64 = 10    //default max number of requests per second
+)
+
+var logger = log.New(os.Stdout).WithoutDebug()
+
+//Point is a location on the map
+type Point struct {
+	Latitude  float64
+	Longitude float64
+}
+
+//GMapsProvider is a RouteProvider based on the Google Distance Matrix API, failed requests are calculated by the Fallback provider
+type GMapsProvider struct {
+	APIKey                string
+	BaseURL               string
+	Client                *http.Client
+	Fallback              RouteProvider
+	MaxConcurrentRequests int     //max number of requests in flight across all goroutines, 0 = unlimited
+	QPS                   float64 //max number of requests per second across all goroutines, 0 = unlimited
+	cache                 map[Route]float32
+	mutex                 sync.RWMutex
+	limiterOnce           sync.Once
+	semaphore             chan struct{}
+	limiterMutex          sync.Mutex
+	nextRequestTime       time.Time
+}
+
+//Distance Matrix API response, only the fields used for driving time
+type gmapsResponse struct {
+	Status       string `json:"status"`
+	ErrorMessage string `json:"error_message"`
+	Rows         []struct {
+		Elements []struct {
+			Status   string `json:"status"`
+			Duration struct {
+				Value float64 `json:"value"` //seconds
+			} `json:"duration"`
+		} `json:"elements"`
+	} `json:"rows"`
+}
+
+//NewGMapsProvider will create GMapsProvider with the API key from the GMapsAPIKeyEnv environment variable and HaversineProvider fallback
+func NewGMapsProvider() *GMapsProvider {
+	return &GMapsProvider{
+		APIKey:                os.Getenv(GMapsAPIKeyEnv),
+		BaseURL:               gmapsDistanceMatrixURL,
+		Client:                &http.Client{Timeout: 30 * time.Second},
+		Fallback:              HaversineProvider{},
+		MaxConcurrentRequests: gmapsDefaultConcurrent,
+		QPS:                   gmapsDefaultQPS,
+		cache:                 make(map[Route]float32),
+	}
+}
+
+//acquire will block until the request is allowed by the concurrency and QPS limits
+func (provider *GMapsProvider) acquire() {
+	//Limits are read once, so they can be configured after NewGMapsProvider
+	provider.limiterOnce.Do(func() {
+		if provider.MaxConcurrentRequests > 0 {
+			provider.semaphore = make(chan struct{}, provider.MaxConcurrentRequests)
+		}
+	})
+	if provider.semaphore != nil {
+		provider.semaphore <- struct{}{}
+	}
+	if provider.QPS > 0 {
+		//Reserve the next request slot and wait for it
+		provider.limiterMutex.Lock()
+		now := time.Now()
+		if provider.nextRequestTime.Before(now) {
+			provider.nextRequestTime = now
+		}
+		wait := provider.nextRequestTime.Sub(now)
+		provider.nextRequestTime = provider.nextRequestTime.Add(time.Duration(float64(time.Second) / provider.QPS))
+		provider.limiterMutex.Unlock()
+		time.Sleep(wait)
+	}
+}
+
+//release will free the concurrency slot taken by acquire
+func (provider *GMapsProvider) release() {
+	if provider.semaphore != nil {
+		<-provider.semaphore
+	}
+}
+
+//roundCoordinate will round the coordinate to the cache precision
+func roundCoordinate(coordinate float64) float64 {
+	return math.Round(coordinate*gmapsCachePrecision) / gmapsCachePrecision
+}
+
+//cacheKey will build the directional cache key from the rounded coordinates, shared by all cached providers
+func cacheKey(origin, destination Point) Route {
+	return Route{roundCoordinate(origin.Latitude), roundCoordinate(origin.Longitude), roundCoordinate(destination.Latitude), roundCoordinate(destination.Longitude)}
+}
+
+//DrivingTime will return the driving time from the cache or request it from the API. Fallback driving time is returned if the API fails or the key is missing
+func (provider *GMapsProvider) DrivingTime(originLatitude, originLongitude, destinationLatitude, destinationLongitude float64) (float32, error) {
+	origin := Point{originLatitude, originLongitude}
+	destination := Point{destinationLatitude, destinationLongitude}
+	provider.mutex.RLock()
+	hours, ok := provider.cache[cacheKey(origin, destination)]
+	provider.mutex.RUnlock()
+	if ok {
+		return hours, nil
+	}
+	if provider.APIKey == "" {
+		return provider.Fallback.DrivingTime(originLatitude, originLongitude, destinationLatitude, destinationLongitude)
+	}
+
+	err := provider.Prefetch([]Point{origin}, []Point{destination})
+	if err == nil {
+		provider.mutex.RLock()
+		hours, ok = provider.cache[cacheKey(origin, destination)]
+		provider.mutex.RUnlock()
+		if ok {
+			return hours, nil
+		}
+		err = errors.New("no route found")
+	}
+	logger.Warn("Couldn't get driving time from the Distance Matrix API, using fallback: ", err)
+	hours, err = provider.Fallback.DrivingTime(originLatitude, originLongitude, destinationLatitude, destinationLongitude)
+	if err != nil {
+		return 0, err
+	}
+	//Failed route is cached with the fallback driving time to prevent repeated requests
+	provider.mutex.Lock()
+	provider.cache[cacheKey(origin, destination)] = hours
+	provider.mutex.Unlock()
+	return hours, nil
+}
+
+//Prefetch will request driving times for all origin and destination pairs in batches and store them in the cache
+func (provider *GMapsProvider) Prefetch(origins, destinations []Point) error {
+	if provider.APIKey == "" {
+		return errors.New(GMapsAPIKeyEnv + " is not set")
+	}
+	for i := 0; i < len(origins); i += gmapsMaxBatchSize {
+		originsBatch := origins[i:int(math.Min(float64(i+gmapsMaxBatchSize), float64(len(origins))))]
+		for j := 0; j < len(destinations); j += gmapsMaxBatchSize {
+			destinationsBatch := destinations[j:int(math.Min(float64(j+gmapsMaxBatchSize), float64(len(destinations))))]
+			drivingTimes, err := provider.requestDrivingTimes(originsBatch, destinationsBatch)
+			if err != nil {
+				return err
+			}
+			provider.mutex.Lock()
+			for k, v := range drivingTimes {
+				provider.cache[k] = v
+			}
+			provider.mutex.Unlock()
+		}
+	}
+	return nil
+}
+
+//formatPoints will format points for the Distance Matrix API request, e.g. 49.28,-123.12|49.2,-123
+func formatPoints(points []Point) string {
+	var formattedPoints []string
+	for _, point := range points {
+		formattedPoints = append(formattedPoints, strconv.FormatFloat(point.Latitude, 'f', -1, 64)+","+strconv.FormatFloat(point.Longitude, 'f', -1, 64))
+	}
+	return strings.Join(formattedPoints, "|")
+}
+
+//requestDrivingTimes will request driving times in hours for the single batch, routes without the result are skipped
+func (provider *GMapsProvider) requestDrivingTimes(origins, destinations []Point) (map[Route]float32, error) {
+	query := url.Values{}
+	query.Set("origins", formatPoints(origins))
+	query.Set("destinations", formatPoints(destinations))
+	query.Set("mode", "driving")
+	query.Set("key", provider.APIKey)
+	provider.acquire()
+	defer provider.release()
+	response, err := provider.Client.Get(provider.BaseURL + "?" + query.Encode())
+	if err != nil {
+		//Request URL contains the API key, so it shouldn't be logged
+		if urlErr, ok := err.(*url.Error); ok {
+			return nil, urlErr.Err
+		}
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected HTTP status: %v", response.Status)
+	}
+	return parseGMapsResponse(json.NewDecoder(response.Body), origins, destinations)
+}
+
+//parseGMapsResponse will decode the Distance Matrix API response, rows are origins and elements are destinations
+func parseGMapsResponse(decoder *json.Decoder, origins, destinations []Point) (map[Route]float32, error) {
+	var matrix gmapsResponse
+	err := decoder.Decode(&matrix)
+	if err != nil {
+		return nil, err
+	}
+	if matrix.Status != "OK" {
+		return nil, fmt.Errorf("distance matrix status %v: %v", matrix.Status, matrix.ErrorMessage)
+	}
+	if len(matrix.Rows) != len(origins) {
+		return nil, fmt.Errorf("expected %v rows, got %v", len(origins), len(matrix.Rows))
+	}
+	drivingTimes := make(map[Route]float32)
+	for i, row := range matrix.Rows {
+		if len(row.Elements) != len(destinations) {
+			return nil, fmt.Errorf("expected %v elements, got %v", len(destinations), len(row.Elements))
+		}
+		for j, element := range row.Elements {
+			if element.Status != "OK" {
+				continue
+			}
+			drivingTimes[cacheKey(origins[i], destinations[j])] = float32(element.Duration.Value / 3600)
+		}
+	}
+	return drivingTimes, nil
+}
@@ -0,0 +1,129 @@
+package location
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+//roundTripFunc is a stubbed HTTP transport returning the response without network requests
+type roundTripFunc func(request *http.Request) *http.Response
+
+func (f roundTripFunc) RoundTrip(request *http.Request) (*http.Response, error) {
+	return f(request), nil
+}
+
+//fixedProvider is a fallback RouteProvider with the same driving time for all routes
+type fixedProvider float32
+
+func (provider fixedProvider) DrivingTime(originLatitude, originLongitude, destinationLatitude, destinationLongitude float64) (float32, error) {
+	return float32(provider), nil
+}
+
+//newStubGMapsProvider will create GMapsProvider with the stubbed client always returning the body, requests are counted
+func newStubGMapsProvider(apiKey string, body string, requests *int) *GMapsProvider {
+	var mutex sync.Mutex
+	provider := NewGMapsProvider()
+	provider.APIKey = apiKey
+	provider.QPS = 0
+	provider.Fallback = fixedProvider(7)
+	provider.Client = &http.Client{Transport: roundTripFunc(func(request *http.Request) *http.Response {
+		mutex.Lock()
+		*requests++
+		mutex.Unlock()
+		return &http.Response{StatusCode: http.StatusOK, Status: "200 OK", Body: ioutil.NopCloser(strings.NewReader(body)), Header: make(http.Header)}
+	})}
+	return provider
+}
+
+func TestGMapsProviderParsesResponse(t *testing.T) {
+	const body = `{"status":"OK","rows":[{"elements":[{"status":"OK","duration":{"value":3600}},{"status":"OK","duration":{"value":1800}}]}]}`
+	var requests int
+	provider := newStubGMapsProvider("key", body, &requests)
+	err := provider.Prefetch([]Point{{49.2827, -123.1207}}, []Point{{49.2488, -122.9805}, {49.1666, -123.1336}})
+	if err != nil {
+		t.Fatal("Prefetch failed: ", err)
+	}
+	for destination, expected := range map[Point]float32{{49.2488, -122.9805}: 1, {49.1666, -123.1336}: 0.5} {
+		drivingTime, err := provider.DrivingTime(49.2827, -123.1207, destination.Latitude, destination.Longitude)
+		if err != nil || drivingTime != expected {
+			t.Errorf("DrivingTime to %v = %v, %v, expected %v", destination, drivingTime, err, expected)
+		}
+	}
+	//Cached routes don't need the new requests
+	if requests != 1 {
+		t.Errorf("Requests = %v, expected 1", requests)
+	}
+}
+
+func TestGMapsProviderFallback(t *testing.T) {
+	tests := []struct {
+		name             string
+		apiKey           string
+		body             string
+		expectedRequests int
+	}{
+		{"missing key", "", `{"status":"OK"}`, 0},
+		{"API error", "key", `{"status":"REQUEST_DENIED","error_message":"invalid key"}`, 1},
+		{"no route", "key", `{"status":"OK","rows":[{"elements":[{"status":"ZERO_RESULTS"}]}]}`, 1},
+	}
+	for _, test := range tests {
+		var requests int
+		provider := newStubGMapsProvider(test.apiKey, test.body, &requests)
+		//Failed route is cached with the fallback driving time, so the second call doesn't request it again
+		for i := 0; i < 2; i++ {
+			drivingTime, err := provider.DrivingTime(49.2827, -123.1207, 49.2488, -122.9805)
+			if err != nil || drivingTime != 7 {
+				t.Errorf("DrivingTime with %v = %v, %v, expected fallback 7", test.name, drivingTime, err)
+			}
+		}
+		if requests != test.expectedRequests {
+			t.Errorf("Requests with %v = %v, expected %v", test.name, requests, test.expectedRequests)
+		}
+	}
+}
+
+func TestGMapsProviderConcurrencyLimit(t *testing.T) {
+	var mutex sync.Mutex
+	var inFlight, maxInFlight int
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		mutex.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mutex.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mutex.Lock()
+		inFlight--
+		mutex.Unlock()
+		writer.Write([]byte(`{"status":"OK","rows":[{"elements":[{"status":"OK","duration":{"value":3600}}]}]}`))
+	}))
+	defer server.Close()
+
+	provider := NewGMapsProvider()
+	provider.APIKey = "key"
+	provider.BaseURL = server.URL
+	provider.Client = server.Client()
+	provider.MaxConcurrentRequests = 3
+	provider.QPS = 0
+	//Every goroutine requests a different route, so the cache doesn't serve them
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if drivingTime, err := provider.DrivingTime(49, -123, 49+float64(i)/100, -123); err != nil || drivingTime != 1 {
+				t.Errorf("DrivingTime = %v, %v, expected 1", drivingTime, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if maxInFlight > provider.MaxConcurrentRequests || maxInFlight == 0 {
+		t.Errorf("Max concurrent requests = %v, expected 1-%v", maxInFlight, provider.MaxConcurrentRequests)
+	}
+}
@@ -1,9 +1,13 @@
 package location
 
-import "math"
+import (
+	"fmt"
+	"math"
+	"sync"
+)
 
 const (
-	drivingSpeed float32 = 20 //cheap alternative to GMaps API, 1/20 KMH
+	defaultDrivingSpeed float32 = 20 //cheap alternative to GMaps API, average driving speed in km/h
 )
 
 //CalcDistance will calculate haversine distance between 2 points
@@ -25,8 +29,137 @@ func calcDistance(latitude1, longitude1, latitude2, longitude2 float64) float32
 	return float32(distance)
 }
 
-//CalcDrivingTime will calculate average driving time between 2 locations in hours
+//ValidateCoordinates will return an error if the latitude is outside [-90,90] or the longitude is outside [-180,180]
+func ValidateCoordinates(latitude, longitude float64) error {
+	if math.IsNaN(latitude) || latitude < -90 || latitude > 90 {
+		return fmt.Errorf("latitude %v is out of range [-90,90]", latitude)
+	}
+	if math.IsNaN(longitude) || longitude < -180 || longitude > 180 {
+		return fmt.Errorf("longitude %v is out of range [-180,180]", longitude)
+	}
+	return nil
+}
+
+//DistanceMetric is a method to approximate the road distance between 2 locations
+type DistanceMetric string
+
+const (
+	HaversineDistance DistanceMetric = "haversine" //great-circle distance
+	ManhattanDistance DistanceMetric = "manhattan" //north-south plus east-west great-circle distances, multiplied by the detour factor
+)
+
+//Calculate Manhattan distance between 2 points, east-west distance is measured at the middle latitude
+func calcManhattanDistance(latitude1, longitude1, latitude2, longitude2 float64) float32 {
+	middleLatitude := (latitude1 + latitude2) / 2
+	return calcDistance(latitude1, longitude1, latitude2, longitude1) + calcDistance(middleLatitude, longitude1, middleLatitude, longitude2)
+}
+
+//RouteProvider is a source of the driving time in hours from the origin to the destination, time can differ for the opposite direction
+type RouteProvider interface {
+	DrivingTime(originLatitude, originLongitude, destinationLatitude, destinationLongitude float64) (float32, error)
+}
+
+//HaversineProvider is a default symmetric RouteProvider based on the haversine distance and average driving speed
+type HaversineProvider struct {
+	//Speed is the average driving speed in km/h, 0 = 20 km/h
+	Speed float32
+	//Metric is the distance approximation, empty = HaversineDistance
+	Metric DistanceMetric
+	//DetourFactor multiplies the ManhattanDistance to account for the street layout, 0 = 1
+	DetourFactor float32
+}
+
+//DefaultProvider is used by CalcDrivingTime
+var DefaultProvider RouteProvider = HaversineProvider{}
+
+//Memoized distances, key is the metric and Route with rounded coordinates. Distances don't depend on the speed, so all providers share them.
+//Safe for the concurrent scheduling goroutines
+var distanceCache sync.Map
+
+type distanceCacheKey struct {
+	metric DistanceMetric
+	route  Route
+}
+
+//ClearDrivingTimeCache will remove all memoized haversine distances
+func ClearDrivingTimeCache() {
+	distanceCache.Range(func(key, value interface{}) bool {
+		distanceCache.Delete(key)
+		return true
+	})
+}
+
+//DrivingTime will calculate average driving time between 2 locations in hours
+func (provider HaversineProvider) DrivingTime(originLatitude, originLongitude, destinationLatitude, destinationLongitude float64) (float32, error) {
+	if err := ValidateCoordinates(originLatitude, originLongitude); err != nil {
+		return 0, err
+	}
+	if err := ValidateCoordinates(destinationLatitude, destinationLongitude); err != nil {
+		return 0, err
+	}
+	speed := provider.Speed
+	if speed <= 0 {
+		speed = defaultDrivingSpeed
+	}
+	metric := provider.Metric
+	if metric == "" {
+		metric = HaversineDistance
+	}
+	detourFactor := float32(1)
+	if metric == ManhattanDistance && provider.DetourFactor > 0 {
+		detourFactor = provider.DetourFactor
+	}
+	key := distanceCacheKey{metric, cacheKey(Point{originLatitude, originLongitude}, Point{destinationLatitude, destinationLongitude})}
+	if distance, ok := distanceCache.Load(key); ok {
+		return detourFactor * distance.(float32) / speed, nil
+	}
+	var distance float32
+	switch metric {
+	case HaversineDistance:
+		distance = calcDistance(originLatitude, originLongitude, destinationLatitude, destinationLongitude)
+	case ManhattanDistance:
+		distance = calcManhattanDistance(originLatitude, originLongitude, destinationLatitude, destinationLongitude)
+	default:
+		return 0, fmt.Errorf("unknown distance metric: %v", metric)
+	}
+	distanceCache.Store(key, distance)
+	return detourFactor * distance / speed, nil
+}
+
+//CalcDrivingTime will calculate average driving time between 2 locations in hours with the DefaultProvider, errors are ignored
 func CalcDrivingTime(latitude1, longitude1, latitude2, longitude2 float64) float32 {
-	//TODO: Replace with GMaps API
-	return calcDistance(latitude1, longitude1, latitude2, longitude2) / drivingSpeed
+	drivingTime, _ := DefaultProvider.DrivingTime(latitude1, longitude1, latitude2, longitude2)
+	return drivingTime
+}
+
+//Route is a directional pair of locations
+type Route struct {
+	OriginLatitude       float64
+	OriginLongitude      float64
+	DestinationLatitude  float64
+	DestinationLongitude float64
+}
+
+//MatrixProvider is a RouteProvider with the predefined directional driving times, missing routes are calculated by the Fallback provider
+type MatrixProvider struct {
+	DrivingTimes map[Route]float32
+	Fallback     RouteProvider
+}
+
+//NewMatrixProvider will create empty MatrixProvider with HaversineProvider fallback
+func NewMatrixProvider() *MatrixProvider {
+	return &MatrixProvider{DrivingTimes: make(map[Route]float32), Fallback: HaversineProvider{}}
+}
+
+//SetDrivingTime will store driving time in hours from the origin to the destination only
+func (provider *MatrixProvider) SetDrivingTime(originLatitude, originLongitude, destinationLatitude, destinationLongitude float64, hours float32) {
+	provider.DrivingTimes[Route{originLatitude, originLongitude, destinationLatitude, destinationLongitude}] = hours
+}
+
+//DrivingTime will return the stored driving time from the origin to the destination or the fallback driving time
+func (provider *MatrixProvider) DrivingTime(originLatitude, originLongitude, destinationLatitude, destinationLongitude float64) (float32, error) {
+	if hours, ok := provider.DrivingTimes[Route{originLatitude, originLongitude, destinationLatitude, destinationLongitude}]; ok {
+		return hours, nil
+	}
+	return provider.Fallback.DrivingTime(originLatitude, originLongitude, destinationLatitude, destinationLongitude)
 }
@@ -0,0 +1,124 @@
+package location
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDefaultProviderDrivingTime(t *testing.T) {
+	//Driving times of the haversine distance at 20 km/h before the providers were introduced
+	tests := []struct {
+		route    Route
+		expected float32
+	}{
+		{Route{0, 0, 0, 1}, 5.5597463},
+		{Route{49.2827, -123.1207, 49.2488, -122.9805}, 0.5424446},
+		{Route{49.2827, -123.1207, 49.2827, -123.1207}, 4.7467647e-06},
+	}
+	ClearDrivingTimeCache()
+	for _, test := range tests {
+		drivingTime, err := HaversineProvider{}.DrivingTime(test.route.OriginLatitude, test.route.OriginLongitude, test.route.DestinationLatitude, test.route.DestinationLongitude)
+		if err != nil || drivingTime != test.expected {
+			t.Errorf("DrivingTime %v = %v, %v, expected %v", test.route, drivingTime, err, test.expected)
+		}
+		if drivingTime := CalcDrivingTime(test.route.OriginLatitude, test.route.OriginLongitude, test.route.DestinationLatitude, test.route.DestinationLongitude); drivingTime != test.expected {
+			t.Errorf("CalcDrivingTime %v = %v, expected %v", test.route, drivingTime, test.expected)
+		}
+	}
+}
+
+func TestHaversineProviderSpeed(t *testing.T) {
+	route := Route{49.2827, -123.1207, 49.2488, -122.9805}
+	drivingTimes := make(map[float32]float32)
+	//Zero speed is the default 20 km/h
+	for _, speed := range []float32{0, 20, 40} {
+		drivingTime, err := HaversineProvider{Speed: speed}.DrivingTime(route.OriginLatitude, route.OriginLongitude, route.DestinationLatitude, route.DestinationLongitude)
+		if err != nil {
+			t.Fatal(err)
+		}
+		drivingTimes[speed] = drivingTime
+	}
+	if drivingTimes[0] != drivingTimes[20] || drivingTimes[40] != drivingTimes[20]/2 {
+		t.Errorf("Driving times = %v, expected the same for the default and 20 km/h and half for 40 km/h", drivingTimes)
+	}
+}
+
+func TestDistanceMetrics(t *testing.T) {
+	drivingTime := func(provider HaversineProvider, route Route) float32 {
+		drivingTime, err := provider.DrivingTime(route.OriginLatitude, route.OriginLongitude, route.DestinationLatitude, route.DestinationLongitude)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return drivingTime
+	}
+	//Driving times are compared up to the float32 rounding
+	almostEqual := func(a, b float32) bool {
+		return math.Abs(float64(a-b)) <= 1e-4*math.Abs(float64(b))
+	}
+	diagonal := Route{49.2, -123.2, 49.3, -123}
+	haversine := drivingTime(HaversineProvider{}, diagonal)
+	manhattan := drivingTime(HaversineProvider{Metric: ManhattanDistance}, diagonal)
+	//Grid route is longer than the straight line, but not longer than both legs of the right triangle
+	if manhattan <= haversine || manhattan > haversine*math.Sqrt2 {
+		t.Errorf("Manhattan driving time = %v, expected between %v and %v", manhattan, haversine, haversine*math.Sqrt2)
+	}
+	if detour := drivingTime(HaversineProvider{Metric: ManhattanDistance, DetourFactor: 1.5}, diagonal); !almostEqual(detour, 1.5*manhattan) {
+		t.Errorf("Manhattan driving time with 1.5 detour factor = %v, expected %v", detour, 1.5*manhattan)
+	}
+	//Detour factor is used by the ManhattanDistance only
+	if detour := drivingTime(HaversineProvider{Metric: HaversineDistance, DetourFactor: 1.5}, diagonal); detour != haversine {
+		t.Errorf("Haversine driving time with 1.5 detour factor = %v, expected %v", detour, haversine)
+	}
+	//Both metrics are the same along the meridian
+	meridian := Route{49.2, -123.2, 49.3, -123.2}
+	if haversine, manhattan := drivingTime(HaversineProvider{}, meridian), drivingTime(HaversineProvider{Metric: ManhattanDistance}, meridian); !almostEqual(manhattan, haversine) {
+		t.Errorf("Driving time along the meridian = %v for haversine and %v for Manhattan, expected the same", haversine, manhattan)
+	}
+	if _, err := (HaversineProvider{Metric: "euclidean"}).DrivingTime(0, 0, 0, 1); err == nil {
+		t.Error("Unknown metric didn't return an error")
+	}
+}
+
+//countCachedDistances will count the memoized distances
+func countCachedDistances() int {
+	var count int
+	distanceCache.Range(func(key, value interface{}) bool {
+		count++
+		return true
+	})
+	return count
+}
+
+//BenchmarkDrivingTimeCache reports calcDistance calls per fitness pass of 10 workers and 20 sites, with and without the memoized distances
+func BenchmarkDrivingTimeCache(b *testing.B) {
+	var workers, sites []Point
+	for i := 0; i < 10; i++ {
+		workers = append(workers, Point{49 + float64(i)/100, -123})
+	}
+	for i := 0; i < 20; i++ {
+		sites = append(sites, Point{49, -123 + float64(i)/100})
+	}
+	for _, cached := range []bool{false, true} {
+		name := "uncached"
+		if cached {
+			name = "cached"
+		}
+		b.Run(name, func(b *testing.B) {
+			ClearDrivingTimeCache()
+			var distanceCalls int
+			for i := 0; i < b.N; i++ {
+				if !cached {
+					ClearDrivingTimeCache()
+				}
+				cachedDistances := countCachedDistances()
+				for _, worker := range workers {
+					for _, site := range sites {
+						CalcDrivingTime(worker.Latitude, worker.Longitude, site.Latitude, site.Longitude)
+					}
+				}
+				distanceCalls += countCachedDistances() - cachedDistances
+			}
+			b.ReportMetric(float64(distanceCalls)/float64(b.N), "distances/op")
+		})
+	}
+}
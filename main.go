@@ -1,13 +1,19 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
 	"hash/fnv"
 	"io"
 	"math"
 	"math/rand"
 	"os"
 	"reflect"
+	"runtime"
 	"sort"
 	"strconv"
 	"strings"
@@ -24,6 +30,7 @@ const (
 	projectsDBFileName           string = "project_info.csv"
 	projectFamiliarityDBFileName string = "worker_project_hours.csv"
 	workersTimeOffDBFileName     string = "worker_time_off.csv"
+	siteHolidaysDBFileName       string = "holidays.csv"
 )
 
 //Genetic algorithm parameters
@@ -33,16 +40,106 @@ var (
 	crossoverRate          float32 = 0.9   //how often to do crossover 0%-100% in decimal
 	mutationRate           float32 = 0.9   //how often to do mutation 0%-100% in decimal
 	elitismRate            float32 = 0.2   //how many of the best indviduals to keep intact
+	immigrationRate        float32 = 0     //how many fresh random individuals to add every generation to keep diversity
 	deadend                float32 = 10000 //round number to split between unscheduled tasks and real hours to complete
 	tourneySampleSize      int     = 3     //sample size for the tournament selection, should be less than population size-number of elites
 	crossoverParentsNumber int     = 2     //number of parents for the crossover
 	maxCrossoverLength     int     = 3     //max number of sequential tasks to cross between individuals
+	crossoverLocality      int     = 0     //max distance a task can be moved by the OX1 crossover, 0 = unlimited
 	maxMutatedGenes        int     = 3     //maximum number of mutated genes, min=2
 	mutationTypePreference float32 = 0.5   //prefered mutation type rate. 0 = 100% swap mutation, 1 = 100% displacement mutation
+	strictRates            bool    = false //fail on the out of range rates instead of clamping them
+	configFileName         string  = ""    //JSON or YAML config file with the tuning parameters, empty = defaults
+	stagnationLimit        int     = 0     //stop after this number of generations without the best fitness improvement, 0 = run all generations
+	convergenceEpsilon     float32 = 0     //min best fitness decrease counted as the improvement
+	reshuffleStagnation    bool    = true  //randomize GA parameters after 50 stagnant generations
+	randomSeed             int64   = 0     //seed of the random numbers generator, 0 = random seed
+)
+
+//Number of go routines to generate schedules simultaneously
+var threadsNum int = runtime.NumCPU()
+
+//Adaptive mutation parameters
+var (
+	adaptiveMutation   bool    = false //adjust the mutation rate every generation by the population diversity, every island adjusts its own rate
+	minMutationRate    float32 = 0.5   //lower bound of the adaptive mutation rate
+	maxMutationRate    float32 = 1     //upper bound of the adaptive mutation rate
+	mutationRateStep   float32 = 0.05  //mutation rate change per generation
+	diversityThreshold float32 = 0.2   //mutation rate is raised below this average task order distance from the best individual
+)
+
+//Island model parameters
+var (
+	islandsNumber     int = 1  //number of populations evolved in parallel, 1 = single population
+	migrationInterval int = 10 //generations between the migrations of the best individuals to the next island
+	migrationSize     int = 1  //number of the best individuals migrated from every island
+)
+
+//Individual fitness weights
+var (
+	weightProjectContinuity float32 = 0 //penalty for every worker switching projects between consecutive working days
+	weightProjectIdle       float32 = 1 //penalty for every idle working hour inside the no interruption projects
+	weightOvertime          float32 = 0 //penalty for every overtime hour multiplied by the site overtime multiplier
+	weightPeakOvertime      float32 = 0 //penalty for every overtime hour of the worker with the most overtime hours
+	weightMakespan          float32 = 1 //weight of every makespan hour
+	weightDrivingHours      float32 = 0 //penalty for every driving hour of all workers
+	weightDelayHours        float32 = 0 //penalty for every working hour workers wait on site for the tasks to start
+)
+
+//Allow tasks to finish in the site overtime window instead of continuing on the next working day
+var allowOvertime bool = false
+
+//Risk analysis parameters
+var (
+	monteCarloTrials int = 0 //number of Monte Carlo trials to resample task durations for the best schedule, 0 = disabled
+)
+
+//Driving time parameters
+var (
+	useGMaps         bool    = false //request driving times from the Google Distance Matrix API, API key is read from the GMAPS_API_KEY
+	gmapsConcurrency int     = 4     //max number of concurrent Distance Matrix API requests, 0 = unlimited
+	gmapsQPS         float64 = 10    //max number of Distance Matrix API requests per second, 0 = unlimited
+	drivingSpeed     float64 = 0     //haversine average driving speed in km/h, 0 = 20 km/h
+	detourFactor     float64 = 1     //Manhattan distance multiplier to account for the street layout
+)
+
+//Distance approximation of the driving times, haversine or manhattan
+var distanceMetric string = string(location.HaversineDistance)
+
+//Discount of the first driving leg from the worker home 0%-100% in decimal, 0 = the commute counts like the driving between tasks
+var homeDrivingDiscount float32 = 0
+
+//Rolling horizon parameters
+var (
+	nowDateTime      string = "" //current datetime to replan from, empty = default schedule start time
+	baselineFileName string = "" //JSON Lines baseline schedule, tasks before now are frozen, empty = plan from scratch
+)
+
+//Report parameters
+var (
+	reportUtilization    bool   = false //print workers utilization for the best schedule
+	reportByProject      bool   = false //print the best schedule grouped by project and sorted by start time
+	reportDispatch       bool   = false //print per-day dispatch sheets with tasks of every worker for the best schedule
+	reportWeekly         bool   = false //print ISO-week summary of projects and hours for every worker in the best schedule
+	reportTimeline       bool   = false //print per-day worker timelines with task, travel and idle blocks for the best schedule
+	reportFitness        int    = 0     //print fitness components of the N best individuals, 0 = disabled
+	reportUnlimited      bool   = false //print makespan with unlimited workers to compare with the best schedule
+	reportNeighbors      bool   = false //print fitness statistics of all single-swap neighbors of the best schedule
+	reportBottlenecks    int    = 0     //print N workers limiting the best schedule makespan, 0 = disabled
+	reportRelaxations    bool   = false //print constraint relaxations ranked by the unscheduled tasks if the best schedule is infeasible
+	reportDiversity      bool   = false //print unique genotypes and average task order distance of the population every generation
+	jsonlFileName        string = ""    //JSON Lines file to export the best schedule, empty = disabled
+	jsonFileName         string = ""    //JSON file to export the best schedule with the summary, empty = disabled
+	csvFileName          string = ""    //CSV file to export the best schedule, empty = disabled
+	icsFileName          string = ""    //iCalendar file to export the best schedule, empty = disabled
+	outputDateTimeFormat string = ""    //datetime layout of the pretty printed and exported schedule, empty = default layout of every output
+	taskInfoFileName     string = ""    //task CSV file to export the best schedule with pinned start times and workers, empty = disabled
+	reshuffleLogFileName string = ""    //CSV file to record the stagnation reshuffle events, empty = disabled
+	traceFileName        string = ""    //CSV file to record assignment decisions of the best schedule replay, empty = disabled
 )
 
 //Worker best fit, weighted decision matrix (AHP)
-const (
+var (
 	weightDistance           float32 = 1
 	weightDelay              float32 = 1
 	weightProjectFamiliarity float32 = 0.1
@@ -51,8 +148,55 @@ const (
 	maxValueDelay            float32 = 10 //~6 minutes delay
 	maxValueDemand           float32 = 1  //worker can be assigned to all tasks
 	pinnedDateTimeSnap       float32 = 8
-	//weightTrades             float32 = 1 //for the trades implementation
+	weightTrades             float32 = 1 //specialists with fewer trades are preferred for the tasks with required trades
+	weightWorkerOvertime     float32 = 1 //workers with fewer accumulated overtime hours are preferred
+)
+
+//Worker dynamic scarcity weight, 0 = disabled
+var weightScarcity float32 = 0
+
+//Worker assignment strategies
+const (
+	bestFitStrategy        string = "best-fit"        //sort workers by the AHP fitness and assign the best one
+	firstAvailableStrategy string = "first-available" //assign the first valid worker without calculating the fitness
+)
 
+//Pinned workers modes
+const (
+	pinOnlyMode       string = "only"         //only pinned workers can be assigned to the task
+	pinAtLeastOneMode string = "at-least-one" //at least one pinned worker should be assigned, other valid workers can join
+)
+
+//Prerequisite dependency types
+const (
+	finishToStart  string = "FS" //successor starts after the prerequisite stop time plus lag
+	startToStart   string = "SS" //successor starts after the prerequisite start time plus lag
+	finishToFinish string = "FF" //successor stops after the prerequisite stop time plus lag
+)
+
+//Crossover methods
+const (
+	ox1Crossover string = "ox1" //order 1 crossover, keeps the relative order of the second parent tasks
+	pmxCrossover string = "pmx" //partially mapped crossover, keeps the absolute positions of the second parent tasks
+)
+
+//Crossover method, ox1Crossover or pmxCrossover
+var crossoverMethod string = ox1Crossover
+
+//Selection methods
+const (
+	tournamentSelection string = "tournament" //best of the random tourneySampleSize individuals
+	rankSelection       string = "rank"       //selection probability is proportional to the reversed fitness rank
+)
+
+//Selection method, tournamentSelection or rankSelection
+var selectionMethod string = tournamentSelection
+
+//Worker assignment strategy
+var (
+	assignmentStrategy string = bestFitStrategy
+	synchronizeCrew    bool   = false //multi-worker task starts when the last assignee arrives
+	addPinnedWorkers   bool   = false //add pinned workers missing from the valid workers instead of failing
 )
 
 //Additional constants
@@ -60,7 +204,7 @@ const (
 	defaultDateFormat     string = "2006-01-02"       //format of date in the csv files
 	defaultTimeFormat     string = "15:04"            //format of time in the csv files
 	defaultDateTimeFormat string = "2006-01-02T15:04" //format of datetime in the csv files
-	threadsNum            int    = 256                //number of go routines to run simultaneously
+	prettyDateTimeFormat  string = "2006/01/02 15:04" //format of datetime in the pretty printed schedule
 )
 
 type dateTimeRange struct {
@@ -69,11 +213,14 @@ type dateTimeRange struct {
 }
 
 type worker struct {
-	name          string
-	latitude      float64
-	longitude     float64
-	demand        float32 //how many tasks could potentialy be assigned to worker
-	blockedRanges []dateTimeRange
+	name           string
+	latitude       float64
+	longitude      float64
+	demand         float32 //how many tasks could potentialy be assigned to worker
+	blockedRanges  []dateTimeRange
+	trades         []string  //trades held by the worker
+	dailyStartTime time.Time //worker daily hours inside the task calendar, zero = task calendar hours
+	dailyEndTime   time.Time
 }
 
 type scheduledWorker struct {
@@ -88,7 +235,18 @@ type scheduledWorker struct {
 	valueDriving            float32
 	valueProjectFamiliarity float32
 	valueDemand             float32
-	// valueTrades             float32
+	valueScarcity           float32
+	valueTrades             float32
+	valueOvertime           float32
+	overtimeHours           float32 //overtime hours accumulated by the worker in the current schedule
+	drivingHours            float32 //driving hours accumulated by the worker in the current schedule
+	delayHours              float32 //working hours the worker waited on site for the tasks to start
+	//Partially allocated tasks, full-time tasks move availableAt instead
+	allocations []workerAllocation
+	//Worker has been assigned to a task, so the next driving leg doesn't start from home
+	started bool
+	//Worker has changed since its fitness was calculated for the current task
+	tainted bool
 }
 
 type project struct {
@@ -98,16 +256,33 @@ type project struct {
 	targetStartDate time.Time
 	targetEndDate   time.Time
 	site            calendar.Site
+	noInterruption  bool    //project tasks should follow each other without idle working time
+	drivingSpeed    float32 //haversine average driving speed to the project in km/h, 0 = global drivingSpeed
 }
 
 type individual struct {
-	tasks       []scheduledTask
-	workers     []scheduledWorker
-	fitness     float32
-	fitnessData struct {
-		unscheduledTasks int
-		finishDateTime   time.Time
-	}
+	tasks        []scheduledTask
+	workers      []scheduledWorker
+	droppedTasks map[string]struct{} //optional tasks excluded from the schedule
+	fitness      float32
+	fitnessData  fitnessBreakdown
+}
+
+//Individual fitness components, fitness = weightMakespan*makespan + unscheduledPenalty - optionalReward + projectSwitches + projectIdle + overtime + driving + delay.
+//Deadend penalty for every unscheduled task should dominate all other components, so infeasible schedules always lose
+type fitnessBreakdown struct {
+	unscheduledTasks   int       //number of the mandatory tasks with fewer than minWorkerCount workers
+	finishDateTime     time.Time //latest stop time of the scheduled tasks, zero if no tasks are scheduled
+	makespan           float32   //hours from scheduleStartTime to the last task stop time
+	drivingHours       float32   //total driving hours of all workers
+	unscheduledPenalty float32   //deadend penalty for the unscheduled mandatory tasks
+	optionalReward     float32   //reward for the scheduled optional tasks
+	projectSwitches    float32   //project continuity penalty
+	projectIdle        float32   //no interruption projects idle time penalty
+	overtime           float32   //overtime hours penalty
+	peakOvertime       float32   //max worker overtime hours penalty
+	driving            float32   //workers driving hours penalty
+	delay              float32   //workers waiting hours penalty
 }
 
 type population struct {
@@ -116,15 +291,29 @@ type population struct {
 }
 type task struct {
 	name             string
-	validWorkers     map[string]struct{} //unique hash map of empty structs to store validWorkers IDs
+	validWorkers     map[string]struct{}   //unique hash map of empty structs to store validWorkers IDs
+	crews            []map[string]struct{} //alternative valid workers sets, all task workers should be from the same crew
 	project          string
 	prerequisites    map[string]float32 //store unique prerequisite and corresponding lag/lead hours
+	dependencyTypes  map[string]string  //prerequisite dependency type, missing prerequisite = finishToStart
 	duration         float32
 	idealWorkerCount int
 	minWorkerCount   int
 	maxWorkerCount   int
 	pinnedDateTime   time.Time
 	pinnedWorkerIDs  map[string]struct{}
+	pinMode          string         //pinned workers mode, pinOnlyMode or pinAtLeastOneMode
+	equipment        map[string]int //required equipment ID and number of units
+	windowStart      time.Time      //task can't start before windowStart
+	windowEnd        time.Time      //task should be finished before windowEnd
+	latestStart      time.Time      //task can't start after latestStart, zero = no limit
+	site             *calendar.Site //task-specific working calendar, nil = project site
+	optionalReward   float32        //hours subtracted from the fitness if the task is scheduled, 0 = mandatory task
+	recurrenceDays   int            //days between the recurring task instances, 0 = one-off task
+	recurrenceCount  int            //number of the recurring task instances, 0 = limited by recurrenceUntil
+	recurrenceUntil  time.Time      //latest start date of the recurring task instances
+	trades           []string       //trades required from every assigned worker, empty = any valid worker
+	allocation       float32        //share of the worker day committed to the task, 1 = full-time
 }
 
 type scheduledTask struct {
@@ -147,127 +336,491 @@ var logger = log.New(os.Stdout).WithoutDebug()
 
 //.WithColor()
 
-func readProjectInfoCSV() map[string]project {
+//Create CSV reader skipping the UTF-8 byte order mark, so the quoted first header field is parsed correctly
+func newCSVReader(file io.Reader) *csv.Reader {
+	bufferedFile := bufio.NewReader(file)
+	if bom, err := bufferedFile.Peek(3); err == nil && bytes.Equal(bom, []byte{0xEF, 0xBB, 0xBF}) {
+		bufferedFile.Discard(3)
+	}
+	return csv.NewReader(bufferedFile)
+}
+
+//CSV column indexes by the column name, so the columns can be reordered and unknown columns are ignored
+type csvColumns map[string]int
+
+//Read the CSV header and map the case-insensitive column names to the indexes, all required columns should be present.
+//Rows shorter than the header are rejected by the CSV reader
+func readCSVHeader(reader *csv.Reader, requiredColumns []string) (csvColumns, error) {
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read the CSV header: %v", err)
+	}
+	columns := make(csvColumns)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := columns[name]; !ok {
+			columns[name] = i
+		}
+	}
+	var missingColumns []string
+	for _, name := range requiredColumns {
+		if _, ok := columns[name]; !ok {
+			missingColumns = append(missingColumns, name)
+		}
+	}
+	if len(missingColumns) > 0 {
+		return nil, fmt.Errorf("required columns are missing: %v", strings.Join(missingColumns, ", "))
+	}
+	return columns, nil
+}
+
+//Return the record field in the named column, empty string if the file doesn't have the column
+func (columns csvColumns) get(record []string, name string) string {
+	i, ok := columns[name]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return record[i]
+}
+
+//Malformed CSV rows collected by the reader, so all of them are reported at once instead of the first one
+type csvErrors struct {
+	fileName string
+	errors   []string
+}
+
+//Add the row error, line is the line number in the file starting from 1 for the header
+func (rowErrors *csvErrors) add(line int, record []string, format string, args ...interface{}) {
+	rowErrors.errors = append(rowErrors.errors, fmt.Sprintf("%v:%v: %v, original record: %v", rowErrors.fileName, line, fmt.Sprintf(format, args...), record))
+}
+
+//Return all collected row errors as a single error, nil if all rows are valid
+func (rowErrors *csvErrors) err() error {
+	if len(rowErrors.errors) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(rowErrors.errors, "\n"))
+}
+
+func readProjectInfoCSV() (map[string]project, error) {
 	var projectTemp project
 	projectsDB := make(map[string]project)
 	projectsDBFile, err := os.Open(projectsDBFileName)
 	if err != nil {
-		logger.Fatal("Couldn't open the "+projectsDBFileName+" file\r\n", err)
+		return nil, fmt.Errorf("couldn't open the %v file: %v", projectsDBFileName, err)
+	}
+	rowErrors := csvErrors{fileName: projectsDBFileName}
+	projectsData := newCSVReader(projectsDBFile)
+	projectsColumns, err := readCSVHeader(projectsData, []string{"id", "name", "latitude", "longitude", "target_start", "target_end", "daily_start", "daily_end"})
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", projectsDBFileName, err)
 	}
-	projectsData := csv.NewReader(projectsDBFile)
-	_, err = projectsData.Read() //skip CSV header
+	line := 1
 	for {
 		projectsRecord, err := projectsData.Read()
+		line++
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			logger.Fatal(err)
+			rowErrors.add(line, projectsRecord, "%v", err)
+			continue
+		}
+		projectTemp.name = projectsColumns.get(projectsRecord, "name")
+		projectTemp.latitude, projectTemp.longitude, err = parseLatLon(projectsRecord, projectsColumns["latitude"], projectsColumns["longitude"], "project")
+		if err != nil {
+			rowErrors.add(line, projectsRecord, "%v", err)
+			continue
 		}
-		projectTemp.name = projectsRecord[1]
-		projectTemp.latitude, err = strconv.ParseFloat(projectsRecord[2], 64)
+		projectTemp.targetStartDate, err = time.Parse(defaultDateFormat, projectsColumns.get(projectsRecord, "target_start"))
 		if err != nil {
-			logger.Error("Original record: ", projectsRecord)
-			logger.Fatal("Couldn't parse project latitude value", err)
+			rowErrors.add(line, projectsRecord, "couldn't parse project target start date value: %v", err)
+			continue
 		}
-		projectTemp.longitude, err = strconv.ParseFloat(projectsRecord[3], 64)
+		projectTemp.targetEndDate, err = time.Parse(defaultDateFormat, projectsColumns.get(projectsRecord, "target_end"))
 		if err != nil {
-			logger.Error("Original record: ", projectsRecord)
-			logger.Fatal("Couldn't parse project longitude value", err)
+			rowErrors.add(line, projectsRecord, "couldn't parse project target end date value: %v", err)
+			continue
 		}
-		projectTemp.targetStartDate, err = time.Parse(defaultDateFormat, projectsRecord[5])
+		projectTemp.site.DailyStartTime, err = time.Parse(defaultTimeFormat, projectsColumns.get(projectsRecord, "daily_start"))
 		if err != nil {
-			logger.Error("Original record: ", projectsRecord)
-			logger.Fatal("Couldn't parse project target start date value", err)
+			rowErrors.add(line, projectsRecord, "couldn't parse project daily start time value: %v", err)
+			continue
 		}
-		projectTemp.targetEndDate, err = time.Parse(defaultDateFormat, projectsRecord[6])
+		projectTemp.site.DailyEndTime, err = time.Parse(defaultTimeFormat, projectsColumns.get(projectsRecord, "daily_end"))
 		if err != nil {
-			logger.Error("Original record: ", projectsRecord)
-			logger.Fatal("Couldn't parse project target end date value", err)
+			rowErrors.add(line, projectsRecord, "couldn't parse project daily end time value: %v", err)
+			continue
+		}
+		//Mandatory break columns are optional
+		projectTemp.site.MaxContinuousHours = 0
+		projectTemp.site.BreakDuration = 0
+		if projectsColumns.get(projectsRecord, "max_continuous_hours") != "" {
+			maxContinuousHours, err := strconv.ParseFloat(projectsColumns.get(projectsRecord, "max_continuous_hours"), 32)
+			if err != nil {
+				rowErrors.add(line, projectsRecord, "couldn't parse project max continuous hours value: %v", err)
+				continue
+			}
+			projectTemp.site.MaxContinuousHours = float32(maxContinuousHours)
+		}
+		if projectsColumns.get(projectsRecord, "break_duration") != "" {
+			breakDuration, err := strconv.ParseFloat(projectsColumns.get(projectsRecord, "break_duration"), 32)
+			if err != nil {
+				rowErrors.add(line, projectsRecord, "couldn't parse project break duration value: %v", err)
+				continue
+			}
+			projectTemp.site.BreakDuration = float32(breakDuration)
+		}
+		//Lunch columns are optional, equal lunch start and end = no lunch
+		projectTemp.site.LunchStartTime = time.Time{}
+		projectTemp.site.LunchEndTime = time.Time{}
+		if projectsColumns.get(projectsRecord, "lunch_start") != "" && projectsColumns.get(projectsRecord, "lunch_end") != "" {
+			projectTemp.site.LunchStartTime, err = time.Parse(defaultTimeFormat, projectsColumns.get(projectsRecord, "lunch_start"))
+			if err != nil {
+				rowErrors.add(line, projectsRecord, "couldn't parse project lunch start time value: %v", err)
+				continue
+			}
+			projectTemp.site.LunchEndTime, err = time.Parse(defaultTimeFormat, projectsColumns.get(projectsRecord, "lunch_end"))
+			if err != nil {
+				rowErrors.add(line, projectsRecord, "couldn't parse project lunch end time value: %v", err)
+				continue
+			}
+		}
+		//No interruption column is optional
+		projectTemp.noInterruption = false
+		if projectsColumns.get(projectsRecord, "no_interruption") != "" {
+			projectTemp.noInterruption, err = strconv.ParseBool(projectsColumns.get(projectsRecord, "no_interruption"))
+			if err != nil {
+				rowErrors.add(line, projectsRecord, "couldn't parse project no interruption value: %v", err)
+				continue
+			}
+		}
+		//Overtime columns are optional, overtime window is used only if the overtime is allowed
+		projectTemp.site.MaxOvertimeHours = 0
+		projectTemp.site.OvertimeMultiplier = 1
+		if allowOvertime && projectsColumns.get(projectsRecord, "max_overtime_hours") != "" {
+			maxOvertimeHours, err := strconv.ParseFloat(projectsColumns.get(projectsRecord, "max_overtime_hours"), 32)
+			if err == nil && maxOvertimeHours < 0 {
+				err = fmt.Errorf("negative max_overtime_hours %v", maxOvertimeHours)
+			}
+			if err != nil {
+				rowErrors.add(line, projectsRecord, "couldn't parse project max overtime hours value: %v", err)
+				continue
+			}
+			projectTemp.site.MaxOvertimeHours = float32(maxOvertimeHours)
+		}
+		if projectsColumns.get(projectsRecord, "overtime_multiplier") != "" {
+			overtimeMultiplier, err := strconv.ParseFloat(projectsColumns.get(projectsRecord, "overtime_multiplier"), 32)
+			if err != nil {
+				rowErrors.add(line, projectsRecord, "couldn't parse project overtime multiplier value: %v", err)
+				continue
+			}
+			projectTemp.site.OvertimeMultiplier = float32(overtimeMultiplier)
+		}
+		//Driving speed column is optional, global driving speed is used by default
+		projectTemp.drivingSpeed = 0
+		if projectsColumns.get(projectsRecord, "driving_speed") != "" {
+			drivingSpeed, err := strconv.ParseFloat(projectsColumns.get(projectsRecord, "driving_speed"), 32)
+			if err == nil && drivingSpeed < 0 {
+				err = fmt.Errorf("negative driving_speed %v", drivingSpeed)
+			}
+			if err != nil {
+				rowErrors.add(line, projectsRecord, "couldn't parse project driving speed value: %v", err)
+				continue
+			}
+			projectTemp.drivingSpeed = float32(drivingSpeed)
+		}
+		projectsDB[projectsColumns.get(projectsRecord, "id")] = projectTemp
+	}
+	return projectsDB, rowErrors.err()
+}
+
+//Read holidays and add them to the project sites
+func readSiteHolidaysCSV(projects map[string]project) (map[string]project, error) {
+	siteHolidaysDBFile, err := os.Open(siteHolidaysDBFileName)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open the %v file: %v", siteHolidaysDBFileName, err)
+	}
+	rowErrors := csvErrors{fileName: siteHolidaysDBFileName}
+	siteHolidaysData := newCSVReader(siteHolidaysDBFile)
+	siteHolidaysColumns, err := readCSVHeader(siteHolidaysData, []string{"project", "date"})
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", siteHolidaysDBFileName, err)
+	}
+	line := 1
+	for {
+		siteHolidaysRecord, err := siteHolidaysData.Read()
+		line++
+		if err == io.EOF {
+			break
 		}
-		projectTemp.site.DailyStartTime, err = time.Parse(defaultTimeFormat, projectsRecord[7])
 		if err != nil {
-			logger.Error("Original record: ", projectsRecord)
-			logger.Fatal("Couldn't parse project daily start time value", err)
+			rowErrors.add(line, siteHolidaysRecord, "%v", err)
+			continue
+		}
+		projectTemp, ok := projects[siteHolidaysColumns.get(siteHolidaysRecord, "project")]
+		if !ok {
+			rowErrors.add(line, siteHolidaysRecord, "project is missing: %v", siteHolidaysColumns.get(siteHolidaysRecord, "project"))
+			continue
 		}
-		projectTemp.site.DailyEndTime, err = time.Parse(defaultTimeFormat, projectsRecord[8])
+		//Holiday key is a midnight in the schedule location to match the AddHours lookups
+		holiday, err := time.ParseInLocation(defaultDateFormat, siteHolidaysColumns.get(siteHolidaysRecord, "date"), scheduleStartTime.Location())
 		if err != nil {
-			logger.Error("Original record: ", projectsRecord)
-			logger.Fatal("Couldn't parse project daily end time value", err)
+			rowErrors.add(line, siteHolidaysRecord, "couldn't parse holiday date value: %v", err)
+			continue
 		}
-		projectsDB[projectsRecord[0]] = projectTemp
+		scheduleStartDate := time.Date(scheduleStartTime.Year(), scheduleStartTime.Month(), scheduleStartTime.Day(), 0, 0, 0, 0, scheduleStartTime.Location())
+		if holiday.Before(scheduleStartDate) {
+			logger.Error("Original record: ", siteHolidaysRecord)
+			logger.Error("Holiday is in the past")
+			continue
+		}
+		if projectTemp.site.Holidays == nil {
+			projectTemp.site.Holidays = make(map[time.Time]struct{})
+		}
+		projectTemp.site.Holidays[holiday] = struct{}{}
+		projects[siteHolidaysColumns.get(siteHolidaysRecord, "project")] = projectTemp
+		logger.Infof("Holiday %v attached to the project %v", holiday.Format(defaultDateFormat), siteHolidaysColumns.get(siteHolidaysRecord, "project"))
 	}
-	return projectsDB
+	return projects, rowErrors.err()
 }
 
-func readTaskInfoCSV() map[string]task {
+func readTaskInfoCSV() (map[string]task, error) {
 	var taskTemp task
 	tasksDB := make(map[string]task)
 	tasksDBFile, err := os.Open(tasksDBFileName)
 	if err != nil {
-		logger.Fatal("Couldn't open the "+tasksDBFileName+" file\r\n", err)
+		return nil, fmt.Errorf("couldn't open the %v file: %v", tasksDBFileName, err)
+	}
+	rowErrors := csvErrors{fileName: tasksDBFileName}
+	tasksData := newCSVReader(tasksDBFile)
+	tasksColumns, err := readCSVHeader(tasksData, []string{"project", "id", "name", "valid_workers", "ideal_worker_count", "duration"})
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", tasksDBFileName, err)
 	}
-	tasksData := csv.NewReader(tasksDBFile)
-	_, err = tasksData.Read() //skip CSV header
+	line := 1
 	for {
 		tasksRecord, err := tasksData.Read()
+		line++
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			logger.Fatal(err)
+			rowErrors.add(line, tasksRecord, "%v", err)
+			continue
 		}
-		taskTemp.project = tasksRecord[0]
-		taskTemp.name = tasksRecord[2]
+		taskTemp.project = tasksColumns.get(tasksRecord, "project")
+		taskTemp.name = tasksColumns.get(tasksRecord, "name")
 
 		taskTemp.validWorkers = make(map[string]struct{})
-		for _, v := range strings.Fields(tasksRecord[3]) {
+		for _, v := range strings.Fields(strings.ReplaceAll(tasksColumns.get(tasksRecord, "valid_workers"), "|", " ")) {
 			taskTemp.validWorkers[v] = struct{}{}
 		}
+		//Alternative crews are optional
+		taskTemp.crews = nil
+		if strings.Contains(tasksColumns.get(tasksRecord, "valid_workers"), "|") {
+			taskTemp.crews = parseCrews(tasksColumns.get(tasksRecord, "valid_workers"))
+		}
 
-		taskTemp.idealWorkerCount, err = strconv.Atoi(tasksRecord[5])
+		taskTemp.idealWorkerCount, err = strconv.Atoi(tasksColumns.get(tasksRecord, "ideal_worker_count"))
 		if err != nil {
-			logger.Error("Original record: ", tasksRecord)
-			logger.Fatal("Couldn't parse ideal worker count", err)
+			rowErrors.add(line, tasksRecord, "couldn't parse ideal worker count: %v", err)
+			continue
+		}
+		//Empty min and max worker counts default to the ideal worker count
+		taskTemp.minWorkerCount = taskTemp.idealWorkerCount
+		if tasksColumns.get(tasksRecord, "min_worker_count") != "" {
+			taskTemp.minWorkerCount, err = strconv.Atoi(tasksColumns.get(tasksRecord, "min_worker_count"))
+			if err != nil {
+				rowErrors.add(line, tasksRecord, "couldn't parse min worker count: %v", err)
+				continue
+			}
+		}
+		taskTemp.maxWorkerCount = taskTemp.idealWorkerCount
+		if tasksColumns.get(tasksRecord, "max_worker_count") != "" {
+			taskTemp.maxWorkerCount, err = strconv.Atoi(tasksColumns.get(tasksRecord, "max_worker_count"))
+			if err != nil {
+				rowErrors.add(line, tasksRecord, "couldn't parse max worker count: %v", err)
+				continue
+			}
 		}
 
 		taskTemp.prerequisites = make(map[string]float32)
-		prerequisitesTemp := strings.Fields(tasksRecord[4])
-		lagHoursTemp := strings.Fields(tasksRecord[9])
+		prerequisitesTemp := strings.Fields(tasksColumns.get(tasksRecord, "prerequisites"))
+		lagHoursTemp := strings.Fields(tasksColumns.get(tasksRecord, "lag_hours"))
+		//Empty lag hours = no lag for all prerequisites, otherwise every prerequisite should have its own lag hours
+		if len(lagHoursTemp) > 0 && len(lagHoursTemp) != len(prerequisitesTemp) {
+			rowErrors.add(line, tasksRecord, "task %v.%v has %v prerequisites, but %v lag hours values", taskTemp.project, tasksColumns.get(tasksRecord, "id"), len(prerequisitesTemp), len(lagHoursTemp))
+			continue
+		}
+		validLagHours := true
 		for i, v := range prerequisitesTemp {
+			if len(lagHoursTemp) == 0 {
+				taskTemp.prerequisites[taskTemp.project+"."+v] = 0
+				continue
+			}
 			lagHours, err := strconv.ParseFloat(lagHoursTemp[i], 32)
 			if err != nil {
-				logger.Error("Original record: ", tasksRecord)
-				logger.Fatal("Couldn't parse lag hours value", err)
+				rowErrors.add(line, tasksRecord, "couldn't parse lag hours value: %v", err)
+				validLagHours = false
+				break
 			}
 			taskTemp.prerequisites[taskTemp.project+"."+v] = float32(lagHours)
 		}
+		if !validLagHours {
+			continue
+		}
+		//Dependency types column is optional, empty = finish-to-start for all prerequisites
+		taskTemp.dependencyTypes = make(map[string]string)
+		dependencyTypesTemp := strings.Fields(strings.ToUpper(tasksColumns.get(tasksRecord, "dependency_types")))
+		if len(dependencyTypesTemp) > 0 && len(dependencyTypesTemp) != len(prerequisitesTemp) {
+			rowErrors.add(line, tasksRecord, "task %v.%v has %v prerequisites, but %v dependency types", taskTemp.project, tasksColumns.get(tasksRecord, "id"), len(prerequisitesTemp), len(dependencyTypesTemp))
+			continue
+		}
+		validDependencyTypes := true
+		for i, v := range dependencyTypesTemp {
+			if v != finishToStart && v != startToStart && v != finishToFinish {
+				rowErrors.add(line, tasksRecord, "unknown dependency type %v, should be %v, %v or %v", v, finishToStart, startToStart, finishToFinish)
+				validDependencyTypes = false
+				break
+			}
+			taskTemp.dependencyTypes[taskTemp.project+"."+prerequisitesTemp[i]] = v
+		}
+		if !validDependencyTypes {
+			continue
+		}
 
-		tempDuration, err := strconv.ParseFloat(tasksRecord[8], 32)
+		tempDuration, err := strconv.ParseFloat(tasksColumns.get(tasksRecord, "duration"), 32)
 		if err != nil {
-			logger.Error("Original record: ", tasksRecord)
-			logger.Fatal("Couldn't parse task duration value", err)
+			rowErrors.add(line, tasksRecord, "couldn't parse task duration value: %v", err)
+			continue
 		}
 		taskTemp.duration = float32(tempDuration)
 
 		taskTemp.pinnedDateTime = time.Time{}
-		if tasksRecord[10] != "" {
-			logger.Debugf("PinnedDateTime:=%v", tasksRecord[10])
-			taskTemp.pinnedDateTime, err = time.ParseInLocation(defaultDateTimeFormat, tasksRecord[10], scheduleStartTime.Location())
+		if tasksColumns.get(tasksRecord, "pinned_datetime") != "" {
+			logger.Debugf("PinnedDateTime:=%v", tasksColumns.get(tasksRecord, "pinned_datetime"))
+			taskTemp.pinnedDateTime, err = time.ParseInLocation(defaultDateTimeFormat, tasksColumns.get(tasksRecord, "pinned_datetime"), scheduleStartTime.Location())
 			if err != nil {
-				logger.Error("Original record: ", tasksRecord)
-				logger.Fatal("Couldn't parse task pinned datetime value", err)
+				rowErrors.add(line, tasksRecord, "couldn't parse task pinned datetime value: %v", err)
+				continue
 			}
 		}
 
 		taskTemp.pinnedWorkerIDs = make(map[string]struct{})
-		for _, v := range strings.Fields(tasksRecord[11]) {
+		for _, v := range strings.Fields(tasksColumns.get(tasksRecord, "pinned_workers")) {
 			taskTemp.pinnedWorkerIDs[v] = struct{}{}
 		}
 
-		tasksDB[taskTemp.project+"."+tasksRecord[1]] = taskTemp
+		//Task window columns are optional
+		taskTemp.windowStart = time.Time{}
+		taskTemp.windowEnd = time.Time{}
+		if tasksColumns.get(tasksRecord, "window_start") != "" {
+			taskTemp.windowStart, err = time.ParseInLocation(defaultDateTimeFormat, tasksColumns.get(tasksRecord, "window_start"), scheduleStartTime.Location())
+			if err != nil {
+				rowErrors.add(line, tasksRecord, "couldn't parse task window start value: %v", err)
+				continue
+			}
+		}
+		if tasksColumns.get(tasksRecord, "window_end") != "" {
+			taskTemp.windowEnd, err = time.ParseInLocation(defaultDateTimeFormat, tasksColumns.get(tasksRecord, "window_end"), scheduleStartTime.Location())
+			if err != nil {
+				rowErrors.add(line, tasksRecord, "couldn't parse task window end value: %v", err)
+				continue
+			}
+		}
+		taskTemp.latestStart = time.Time{}
+		if tasksColumns.get(tasksRecord, "latest_start") != "" {
+			taskTemp.latestStart, err = time.ParseInLocation(defaultDateTimeFormat, tasksColumns.get(tasksRecord, "latest_start"), scheduleStartTime.Location())
+			if err != nil {
+				rowErrors.add(line, tasksRecord, "couldn't parse task latest start value: %v", err)
+				continue
+			}
+		}
+
+		//Optional task reward column is optional
+		taskTemp.optionalReward = 0
+		if tasksColumns.get(tasksRecord, "optional_reward") != "" {
+			optionalReward, err := strconv.ParseFloat(tasksColumns.get(tasksRecord, "optional_reward"), 32)
+			if err != nil {
+				rowErrors.add(line, tasksRecord, "couldn't parse task optional reward value: %v", err)
+				continue
+			}
+			taskTemp.optionalReward = float32(optionalReward)
+		}
+
+		//Pin mode column is optional
+		taskTemp.pinMode = pinOnlyMode
+		if tasksColumns.get(tasksRecord, "pin_mode") != "" {
+			if tasksColumns.get(tasksRecord, "pin_mode") != pinOnlyMode && tasksColumns.get(tasksRecord, "pin_mode") != pinAtLeastOneMode {
+				rowErrors.add(line, tasksRecord, "unknown pin mode %v, should be %v or %v", tasksColumns.get(tasksRecord, "pin_mode"), pinOnlyMode, pinAtLeastOneMode)
+				continue
+			}
+			taskTemp.pinMode = tasksColumns.get(tasksRecord, "pin_mode")
+		}
+
+		//Recurrence columns are optional, the second column is either instances count or until date
+		taskTemp.recurrenceDays = 0
+		taskTemp.recurrenceCount = 0
+		taskTemp.recurrenceUntil = time.Time{}
+		if tasksColumns.get(tasksRecord, "recurrence_days") != "" {
+			taskTemp.recurrenceDays, err = strconv.Atoi(tasksColumns.get(tasksRecord, "recurrence_days"))
+			if err != nil || taskTemp.recurrenceDays < 1 {
+				rowErrors.add(line, tasksRecord, "couldn't parse task recurrence interval value: %v", err)
+				continue
+			}
+			taskTemp.recurrenceCount, err = strconv.Atoi(tasksColumns.get(tasksRecord, "recurrence_count"))
+			if err != nil {
+				taskTemp.recurrenceCount = 0
+				taskTemp.recurrenceUntil, err = time.ParseInLocation(defaultDateFormat, tasksColumns.get(tasksRecord, "recurrence_count"), scheduleStartTime.Location())
+				if err != nil {
+					rowErrors.add(line, tasksRecord, "couldn't parse task recurrence count or until date value: %v", err)
+					continue
+				}
+			}
+		}
+
+		//Required trades column is optional
+		taskTemp.trades = strings.Fields(tasksColumns.get(tasksRecord, "trades"))
+
+		//Allocation column is optional, partially allocated worker can work on other tasks at the same time
+		taskTemp.allocation = 1
+		if tasksColumns.get(tasksRecord, "allocation") != "" {
+			allocation, err := strconv.ParseFloat(tasksColumns.get(tasksRecord, "allocation"), 32)
+			if err != nil || allocation <= 0 || allocation > 1 {
+				rowErrors.add(line, tasksRecord, "couldn't parse task allocation value, should be in (0,1]: %v", err)
+				continue
+			}
+			taskTemp.allocation = float32(allocation)
+		}
+
+		tasksDB[taskTemp.project+"."+tasksColumns.get(tasksRecord, "id")] = taskTemp
+	}
+	return tasksDB, rowErrors.err()
+}
+
+//Check that pinned workers are part of valid workers, invalid pinned workers are added to the valid workers if addPinnedWorkers is set.
+//Returns false if any pinned worker is still invalid
+func verifyPinnedWorkers() bool {
+	valid := true
+	for k, task := range tasksDB {
+		for workerID := range task.pinnedWorkerIDs {
+			if _, ok := task.validWorkers[workerID]; ok {
+				continue
+			}
+			logger.Errorf("Task ID:%v, pinned worker ID:%v is not a valid worker", k, workerID)
+			if !addPinnedWorkers {
+				valid = false
+				continue
+			}
+			//Maps are shared with tasksDB, so the worker is added in place
+			task.validWorkers[workerID] = struct{}{}
+			for _, crew := range task.crews {
+				crew[workerID] = struct{}{}
+			}
+		}
 	}
-	return tasksDB
+	return valid
 }
 
 func verifyTaskDB() {
@@ -285,9 +838,57 @@ func verifyTaskDB() {
 		}
 	}
 
-	//TODO: Verify that predecessors are not circular
+	//Verify worker counts
+	for k, task := range tasksDB {
+		if task.minWorkerCount < 1 || task.minWorkerCount > task.idealWorkerCount || task.idealWorkerCount > task.maxWorkerCount {
+			logger.Errorf("Task ID:%v, min:%v, ideal:%v, max:%v", k, task.minWorkerCount, task.idealWorkerCount, task.maxWorkerCount)
+			logger.Fatal("Worker counts should satisfy 1 <= min <= ideal <= max")
+		}
+	}
+
+	//Verify that enough valid workers have the required trades
+	for k, task := range tasksDB {
+		if len(task.trades) == 0 {
+			continue
+		}
+		tradeWorkersCount := 0
+		for workerID := range task.validWorkers {
+			if hasRequiredTrades(scheduledTask{taskID: k}, workerID) {
+				tradeWorkersCount++
+			}
+		}
+		if tradeWorkersCount < task.minWorkerCount {
+			logger.Errorf("Task ID:%v, trades:%v, min:%v", k, task.trades, task.minWorkerCount)
+			logger.Fatalf("Only %v valid workers have the required trades", tradeWorkersCount)
+		}
+	}
+
+	//Verify that predecessors are not circular
+	cycles := findPrerequisiteCycles()
+	for _, cycle := range cycles {
+		logger.Error("Circular prerequisites: ", strings.Join(cycle, " -> "))
+	}
+	if len(cycles) > 0 {
+		logger.Fatal("Tasks with circular prerequisites can't be scheduled")
+	}
+
 	//TODO: Verify that predecessors and successors are not pinned to the same DateTime
-	//TODO: Verify that pinned worker is part of valid workers (?)
+	//Verify that pinned workers are part of valid workers
+	if !verifyPinnedWorkers() {
+		logger.Fatal("Pinned workers should be valid workers, use -add-pinned-workers to add them automatically")
+	}
+
+	//Verify task windows
+	for k, task := range tasksDB {
+		if !task.windowStart.IsZero() && !task.windowEnd.IsZero() && !task.windowStart.Before(task.windowEnd) {
+			logger.Error("Task window start is not before window end")
+			logger.Errorf("Task ID:%v", k)
+		}
+		if !task.windowStart.IsZero() && !task.latestStart.IsZero() && task.latestStart.Before(task.windowStart) {
+			logger.Error("Task latest start is before window start")
+			logger.Errorf("Task ID:%v", k)
+		}
+	}
 
 	//Verify double pinning
 	for firstKey, firstTask := range tasksDB {
@@ -312,8 +913,8 @@ func verifyTaskDB() {
 				logger.Error("Task pinned in the past")
 				logger.Errorf("Task ID:%v", firstKey)
 			}
-			//Check if pinned datetime is on the weekend
-			if firstTask.pinnedDateTime.Weekday() == time.Saturday || firstTask.pinnedDateTime.Weekday() == time.Sunday {
+			//Check if pinned datetime is on the weekend, unless the task has its own working weekdays
+			if len(taskSite(firstKey).WorkingWeekdays) == 0 && (firstTask.pinnedDateTime.Weekday() == time.Saturday || firstTask.pinnedDateTime.Weekday() == time.Sunday) {
 				logger.Error("Task pinned on the weekend")
 				logger.Errorf("Task ID:%v", firstKey)
 			}
@@ -322,122 +923,213 @@ func verifyTaskDB() {
 
 }
 
-func readWorkerInfoCSV() map[string]worker {
+func readWorkerInfoCSV() (map[string]worker, error) {
 	var workerTemp worker
 	workersDB := make(map[string]worker)
 	workersDBFile, err := os.Open(workersDBFileName)
 	if err != nil {
-		logger.Fatal("Couldn't open the "+workersDBFileName+" file\r\n", err)
+		return nil, fmt.Errorf("couldn't open the %v file: %v", workersDBFileName, err)
+	}
+	rowErrors := csvErrors{fileName: workersDBFileName}
+	workersData := newCSVReader(workersDBFile)
+	workersColumns, err := readCSVHeader(workersData, []string{"name", "id", "latitude", "longitude"})
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", workersDBFileName, err)
 	}
-	workersData := csv.NewReader(workersDBFile)
-	_, err = workersData.Read() //skip CSV header
+	line := 1
 	for {
 		workersRecord, err := workersData.Read()
+		line++
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			logger.Fatal(err)
+			rowErrors.add(line, workersRecord, "%v", err)
+			continue
 		}
-		workerTemp.name = workersRecord[0]
-		workerTemp.latitude, err = strconv.ParseFloat(workersRecord[2], 64)
+		workerTemp.name = workersColumns.get(workersRecord, "name")
+		workerTemp.latitude, workerTemp.longitude, err = parseLatLon(workersRecord, workersColumns["latitude"], workersColumns["longitude"], "worker")
 		if err != nil {
-			logger.Error("Original record: ", workersRecord)
-			logger.Fatal("Couldn't parse worker longitude value", err)
+			rowErrors.add(line, workersRecord, "%v", err)
+			continue
 		}
-		workerTemp.longitude, err = strconv.ParseFloat(workersRecord[3], 64)
-		if err != nil {
-			logger.Error("Original record: ", workersRecord)
-			logger.Fatal("Couldn't parse worker longitude value", err)
+		//Trades column is optional
+		workerTemp.trades = strings.Fields(workersColumns.get(workersRecord, "trades"))
+		//Daily hours columns are optional, part-time worker works only inside both the task calendar and own daily hours
+		workerTemp.dailyStartTime = time.Time{}
+		workerTemp.dailyEndTime = time.Time{}
+		if workersColumns.get(workersRecord, "daily_start") != "" {
+			workerTemp.dailyStartTime, err = time.Parse(defaultTimeFormat, workersColumns.get(workersRecord, "daily_start"))
+			if err != nil {
+				rowErrors.add(line, workersRecord, "couldn't parse worker daily start time value: %v", err)
+				continue
+			}
+			workerTemp.dailyEndTime, err = time.Parse(defaultTimeFormat, workersColumns.get(workersRecord, "daily_end"))
+			if err != nil {
+				rowErrors.add(line, workersRecord, "couldn't parse worker daily end time value: %v", err)
+				continue
+			}
+			if !workerTemp.dailyStartTime.Before(workerTemp.dailyEndTime) {
+				rowErrors.add(line, workersRecord, "worker daily start time should be before the daily end time")
+				continue
+			}
 		}
-		workersDB[workersRecord[1]] = workerTemp
+		workersDB[workersColumns.get(workersRecord, "id")] = workerTemp
 	}
-	return workersDB
-
+	return workersDB, rowErrors.err()
 }
 
-func readWorkerTimeOffCSV(workers map[string]worker) map[string]worker {
+func readWorkerTimeOffCSV(workers map[string]worker) (map[string]worker, error) {
 	var tempWorker worker
 	var blockedRange dateTimeRange
 	var hours float64
 	workersTimeOffDBFile, err := os.Open(workersTimeOffDBFileName)
 	if err != nil {
-		logger.Fatal("Couldn't open the "+workersTimeOffDBFileName+" file\r\n", err)
+		return nil, fmt.Errorf("couldn't open the %v file: %v", workersTimeOffDBFileName, err)
+	}
+	rowErrors := csvErrors{fileName: workersTimeOffDBFileName}
+	workersTimeOffData := newCSVReader(workersTimeOffDBFile)
+	workersTimeOffColumns, err := readCSVHeader(workersTimeOffData, []string{"start", "hours", "worker"})
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", workersTimeOffDBFileName, err)
 	}
-	workersTimeOffData := csv.NewReader(workersTimeOffDBFile)
-	_, err = workersTimeOffData.Read() //skip CSV header
+	line := 1
 	for {
 		workersTimeOffRecord, err := workersTimeOffData.Read()
+		line++
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			logger.Fatal(err)
+			rowErrors.add(line, workersTimeOffRecord, "%v", err)
+			continue
 		}
 
-		blockedRange.startTime, err = time.ParseInLocation(defaultDateTimeFormat, workersTimeOffRecord[0], scheduleStartTime.Location())
+		blockedRange.startTime, err = time.ParseInLocation(defaultDateTimeFormat, workersTimeOffColumns.get(workersTimeOffRecord, "start"), scheduleStartTime.Location())
 		if err != nil {
-			logger.Error("Original record: ", workersTimeOffRecord)
-			logger.Fatal("Couldn't parse datetime start value", err)
+			rowErrors.add(line, workersTimeOffRecord, "couldn't parse datetime start value: %v", err)
+			continue
 		}
 
-		hours, err = strconv.ParseFloat(workersTimeOffRecord[1], 32)
+		hours, err = strconv.ParseFloat(workersTimeOffColumns.get(workersTimeOffRecord, "hours"), 32)
 		if err != nil {
-			logger.Error("Original record: ", workersTimeOffRecord)
-			logger.Fatal("Couldn't parse hours value", err)
+			rowErrors.add(line, workersTimeOffRecord, "couldn't parse hours value: %v", err)
+			continue
 		}
 		blockedRange.endTime = blockedRange.startTime.Add(time.Duration(hours) * time.Hour)
 
-		tempWorker = workers[workersTimeOffRecord[2]]
+		tempWorker = workers[workersTimeOffColumns.get(workersTimeOffRecord, "worker")]
 		tempWorker.blockedRanges = append(tempWorker.blockedRanges, blockedRange)
-		logger.Debugf("WorkerID=%v, startTime=%v, endTime=%v", workersTimeOffRecord[2], blockedRange.startTime, blockedRange.endTime)
-		workers[workersTimeOffRecord[2]] = tempWorker
+		logger.Debugf("WorkerID=%v, startTime=%v, endTime=%v", workersTimeOffColumns.get(workersTimeOffRecord, "worker"), blockedRange.startTime, blockedRange.endTime)
+		workers[workersTimeOffColumns.get(workersTimeOffRecord, "worker")] = tempWorker
 
 	}
-	return workersDB
+	return workers, rowErrors.err()
 }
 
-func readWorkerProjectHoursCSV() map[string]map[string]float32 {
+func readWorkerProjectHoursCSV() (map[string]map[string]float32, error) {
 	projectFamiliarityDB := make(map[string]map[string]float32)
 	projectFamiliarityDBFile, err := os.Open(projectFamiliarityDBFileName)
 	if err != nil {
-		logger.Fatal("Couldn't open the "+projectFamiliarityDBFileName+" file\r\n", err)
+		return nil, fmt.Errorf("couldn't open the %v file: %v", projectFamiliarityDBFileName, err)
+	}
+	rowErrors := csvErrors{fileName: projectFamiliarityDBFileName}
+	projectFamiliarityData := newCSVReader(projectFamiliarityDBFile)
+	projectFamiliarityColumns, err := readCSVHeader(projectFamiliarityData, []string{"worker", "project", "hours"})
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", projectFamiliarityDBFileName, err)
 	}
-	projectFamiliarityData := csv.NewReader(projectFamiliarityDBFile)
-	_, err = projectFamiliarityData.Read() //skip CSV header
+	line := 1
 	for {
 		projectFamiliarityRecord, err := projectFamiliarityData.Read()
+		line++
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			logger.Fatal(err)
+			rowErrors.add(line, projectFamiliarityRecord, "%v", err)
+			continue
 		}
-		workerProjectHours, err := strconv.ParseFloat(projectFamiliarityRecord[2], 64)
+		workerProjectHours, err := strconv.ParseFloat(projectFamiliarityColumns.get(projectFamiliarityRecord, "hours"), 64)
 		if err != nil {
-			logger.Error("Original record: ", projectFamiliarityRecord)
-			logger.Fatal("Couldn't parse worker hours value", err)
+			rowErrors.add(line, projectFamiliarityRecord, "couldn't parse worker hours value: %v", err)
+			continue
+		}
+		if _, ok := projectFamiliarityDB[projectFamiliarityColumns.get(projectFamiliarityRecord, "project")]; !ok {
+			projectFamiliarityDB[projectFamiliarityColumns.get(projectFamiliarityRecord, "project")] = make(map[string]float32)
+		}
+		projectFamiliarityDB[projectFamiliarityColumns.get(projectFamiliarityRecord, "project")][projectFamiliarityColumns.get(projectFamiliarityRecord, "worker")] = float32(workerProjectHours)
+	}
+	return projectFamiliarityDB, rowErrors.err()
+}
+
+//Find all cycles in the prerequisites graph with DFS, every cycle starts and ends with the same task ID
+func findPrerequisiteCycles() [][]string {
+	const (
+		notVisited = iota
+		inProgress
+		visited
+	)
+	//Sorted task IDs make the reported cycles reproducible
+	taskIDs := make([]string, 0, len(tasksDB))
+	for taskID := range tasksDB {
+		taskIDs = append(taskIDs, taskID)
+	}
+	sort.Strings(taskIDs)
+
+	var cycles [][]string
+	state := make(map[string]int)
+	var path []string
+	var visit func(taskID string)
+	visit = func(taskID string) {
+		state[taskID] = inProgress
+		path = append(path, taskID)
+		prerequisites := make([]string, 0, len(tasksDB[taskID].prerequisites))
+		for prerequisiteID := range tasksDB[taskID].prerequisites {
+			prerequisites = append(prerequisites, prerequisiteID)
+		}
+		sort.Strings(prerequisites)
+		for _, prerequisiteID := range prerequisites {
+			switch state[prerequisiteID] {
+			case notVisited:
+				visit(prerequisiteID)
+			case inProgress:
+				//Back edge, the cycle is the part of the path from the prerequisite to the current task
+				for i := len(path) - 1; i >= 0; i-- {
+					if path[i] == prerequisiteID {
+						cycle := append([]string{}, path[i:]...)
+						cycles = append(cycles, append(cycle, prerequisiteID))
+						break
+					}
+				}
+			}
 		}
-		if _, ok := projectFamiliarityDB[projectFamiliarityRecord[1]]; !ok {
-			projectFamiliarityDB[projectFamiliarityRecord[1]] = make(map[string]float32)
+		path = path[:len(path)-1]
+		state[taskID] = visited
+	}
+	for _, taskID := range taskIDs {
+		if state[taskID] == notVisited {
+			visit(taskID)
 		}
-		projectFamiliarityDB[projectFamiliarityRecord[1]][projectFamiliarityRecord[0]] = float32(workerProjectHours)
 	}
-	return projectFamiliarityDB
+	return cycles
 }
 
+//Calculate worker demand as the share of all tasks the worker is valid for
 func calculateWorkersDemand() map[string]worker {
-	var workerTemp worker
+	//Count valid tasks for every worker first, so the result doesn't depend on the map iteration order
+	validTasksCount := make(map[string]int)
 	for _, task := range tasksDB {
 		for validWorker := range task.validWorkers {
-			workerTemp = workersDB[validWorker]
-			workerTemp.demand++
-			workersDB[validWorker] = workerTemp
+			validTasksCount[validWorker]++
 		}
 	}
 	totalTasks := len(tasksDB)
 	for workerID, worker := range workersDB {
-		worker.demand = float32(worker.demand) / float32(totalTasks)
+		worker.demand = 0
+		if totalTasks > 0 {
+			worker.demand = float32(validTasksCount[workerID]) / float32(totalTasks)
+		}
 		workersDB[workerID] = worker
 	}
 	return workersDB
@@ -445,23 +1137,34 @@ func calculateWorkersDemand() map[string]worker {
 
 //Calculate FNV-1a-64 hash to compare the order of the tasks between 2 individuals
 func calcTasksHash(tasks []scheduledTask) uint64 {
-	var allTasks []string
-	//Gather all tasks into allTasks slice
-	for _, v := range tasks {
-		allTasks = append(allTasks, v.taskID)
-	}
-	//Convert slice into string representation
-	allTasksString := strings.Join(allTasks, ",")
-	logger.Debug("allTasksString=", allTasksString)
-	//Calculate hash
+	//Comma separated task IDs are written to the hash one by one, the buffer is reused to avoid the allocations
 	hashAlg := fnv.New64a()
-	hashAlg.Write([]byte(allTasksString))
+	var buffer []byte
+	for i, v := range tasks {
+		buffer = buffer[:0]
+		if i > 0 {
+			buffer = append(buffer, ',')
+		}
+		buffer = append(buffer, v.taskID...)
+		hashAlg.Write(buffer)
+	}
 	return hashAlg.Sum64()
 }
 
 //Calculate hash for the individual
 func calcIndividualHash(individual individual) uint64 {
-	return calcTasksHash(individual.tasks)
+	if len(individual.droppedTasks) == 0 {
+		return calcTasksHash(individual.tasks)
+	}
+	//Individuals with the same tasks order, but different dropped tasks are different
+	var droppedTaskIDs []string
+	for taskID := range individual.droppedTasks {
+		droppedTaskIDs = append(droppedTaskIDs, taskID)
+	}
+	sort.Strings(droppedTaskIDs)
+	hashAlg := fnv.New64a()
+	hashAlg.Write([]byte(strconv.FormatUint(calcTasksHash(individual.tasks), 10) + ";" + strings.Join(droppedTaskIDs, ",")))
+	return hashAlg.Sum64()
 }
 
 //Calculate hash for the individuals
@@ -476,21 +1179,31 @@ func calcIndividualsHash(individuals []individual) map[uint64]int {
 //Generate individual by randomizing the taskDB
 func generateIndividual() individual {
 	var newIndividual individual
-	taskOrder := rand.Perm(len(tasksDB))
-	newIndividual.tasks = make([]scheduledTask, len(tasksDB))
-	i := 0
-	for k, v := range tasksDB {
+	//Sorted IDs make the individual reproducible with the fixed seed
+	taskIDs := make([]string, 0, len(tasksDB))
+	for taskID := range tasksDB {
+		taskIDs = append(taskIDs, taskID)
+	}
+	sort.Strings(taskIDs)
+	workerIDs := make([]string, 0, len(workersDB))
+	for workerID := range workersDB {
+		workerIDs = append(workerIDs, workerID)
+	}
+	sort.Strings(workerIDs)
+
+	taskOrder := rand.Perm(len(tasksDB))
+	newIndividual.tasks = make([]scheduledTask, len(tasksDB))
+	for i, k := range taskIDs {
 		newIndividual.tasks[taskOrder[i]].taskID = k
 		newIndividual.tasks[taskOrder[i]].startTime = time.Time{}
 		newIndividual.tasks[taskOrder[i]].stopTime = time.Time{}
 		newIndividual.tasks[taskOrder[i]].assignees = make([]string, 0)
-		newIndividual.tasks[taskOrder[i]].numPrerequisites = len(v.prerequisites)
-		i++
+		newIndividual.tasks[taskOrder[i]].numPrerequisites = len(tasksDB[k].prerequisites)
 	}
 
-	i = 0
 	newIndividual.workers = make([]scheduledWorker, len(workersDB))
-	for k, v := range workersDB {
+	for i, k := range workerIDs {
+		v := workersDB[k]
 		newIndividual.workers[i].workerID = k
 		newIndividual.workers[i].availableAt = scheduleStartTime
 		newIndividual.workers[i].latitude = v.latitude
@@ -498,12 +1211,19 @@ func generateIndividual() individual {
 		newIndividual.workers[i].fitness = 0
 		newIndividual.workers[i].valueDelay = 0
 		newIndividual.workers[i].valueDemand = 0
+		newIndividual.workers[i].valueScarcity = 0
+		newIndividual.workers[i].valueTrades = 0
+		newIndividual.workers[i].valueOvertime = 0
+		newIndividual.workers[i].overtimeHours = 0
+		newIndividual.workers[i].drivingHours = 0
+		newIndividual.workers[i].delayHours = 0
+		newIndividual.workers[i].allocations = nil
+		newIndividual.workers[i].started = false
 		newIndividual.workers[i].valueDriving = 0
 		newIndividual.workers[i].valueProjectFamiliarity = 0
-		i++
 	}
 
-	return newIndividual
+	return dropOptionalTasks(newIndividual)
 }
 
 //Reset individual state
@@ -522,10 +1242,18 @@ func resetIndividual(individual individual) individual {
 		individual.workers[i].fitness = 0
 		individual.workers[i].valueDelay = 0
 		individual.workers[i].valueDemand = 0
+		individual.workers[i].valueScarcity = 0
+		individual.workers[i].valueTrades = 0
+		individual.workers[i].valueOvertime = 0
+		individual.workers[i].overtimeHours = 0
+		individual.workers[i].drivingHours = 0
+		individual.workers[i].delayHours = 0
+		individual.workers[i].allocations = nil
+		individual.workers[i].started = false
 		individual.workers[i].valueDriving = 0
 		individual.workers[i].valueProjectFamiliarity = 0
 	}
-	return individual
+	return freezeCompletedTasks(individual)
 }
 
 func generatePopulation() population {
@@ -536,9 +1264,26 @@ func generatePopulation() population {
 	return population
 }
 
+//Calculate inverse driving time from the worker location to the task project
+func calcValueDriving(worker scheduledWorker, task scheduledTask) float32 {
+	//Driving time is directional, from the worker to the task project
+	valueDriving := calcProjectDrivingTime(worker.latitude, worker.longitude, tasksDB[task.taskID].project)
+	//logger.Debug(worker.latitude, worker.longitude, projectsDB[tasksDB[task.taskID].project].latitude, projectsDB[tasksDB[task.taskID].project].longitude)
+
+	if valueDriving == 0 {
+		return maxValueDriving
+	}
+	return 1 / valueDriving
+}
+
 //Calculate fitness for every worker for the current task
-func calculateWorkersFitness(task scheduledTask, workers []scheduledWorker) {
+func calculateWorkersFitness(task scheduledTask, workers []scheduledWorker, workersScarcity map[string]float32, workersDemand map[string]float32) {
 	for i, v := range workers {
+		//Untouched worker keeps the fitness calculated for the current task
+		if !v.tainted {
+			continue
+		}
+		workers[i].tainted = false
 
 		//Caclulate earliest time to do the specific task for the current worker
 		//for
@@ -556,67 +1301,178 @@ func calculateWorkersFitness(task scheduledTask, workers []scheduledWorker) {
 		valueProjectFamiliarity := projectFamiliarityDB[tasksDB[task.taskID].project][v.workerID]
 
 		//Shorter distance => higher number => better fit
-		valueDriving := location.CalcDrivingTime(v.latitude, v.longitude, projectsDB[tasksDB[task.taskID].project].latitude, projectsDB[tasksDB[task.taskID].project].longitude)
-		//logger.Debug(v.latitude, v.longitude, projectsDB[tasksDB[task.taskID].project].latitude, projectsDB[tasksDB[task.taskID].project].longitude)
-
-		if valueDriving == 0 {
-			valueDriving = maxValueDriving
-		} else {
-			valueDriving = 1 / valueDriving
-		}
+		valueDriving := calcValueDriving(v, task)
 
 		//Fewer tasks can be done by worker => higher number => better fit
-		//TODO: Implement recalculation of demand based on the remaining unscheduled tasks
-		valueDemand := workersDB[v.workerID].demand
+		valueDemand := workersDemand[v.workerID]
 		if valueDemand != 0 {
 			valueDemand = 1 / valueDemand
 		}
 
-		/*
-			//TRADES IMPLEMENTATION
-			 		//Fewer trades => higher number => better fit
-			   		valueTrades := float32(0)
-			   		trades := workersDB[v.workerID].trades
-			   		for _, v := range trades {
-			   			if v == trade {
-			   				valueTrades = float32(1) / float32(len(trades))
-			   				break
-			   			}
-			   		}
-		*/
+		//Current task needs the worker more than other remaining tasks => higher number => better fit
+		valueScarcity := workersScarcity[v.workerID]
+
+		//Fewer trades => higher number => better fit, only workers with the required trades are valued
+		var valueTrades float32
+		if len(tasksDB[task.taskID].trades) > 0 && hasRequiredTrades(task, v.workerID) {
+			valueTrades = float32(len(tasksDB[task.taskID].trades)) / float32(len(workersDB[v.workerID].trades))
+		}
+
 		workers[i].valueDelay = valueDelay
 		workers[i].valueProjectFamiliarity = valueProjectFamiliarity
 		workers[i].valueDriving = valueDriving
 		workers[i].valueDemand = valueDemand
-		//v.valueTrades = valueTrades //TRADES IMPLEMENTATION
+		workers[i].valueScarcity = valueScarcity
+		workers[i].valueTrades = valueTrades
+		//Fewer accumulated overtime hours => higher number => better fit
+		valueOvertime := 1 / (1 + v.overtimeHours)
+		workers[i].valueOvertime = valueOvertime
 
+		logger.Debug("Values=", workers[i].workerID, valueDelay, valueProjectFamiliarity, valueDriving, valueDemand, valueScarcity, valueTrades, valueOvertime)
+		//Calculate AHP fitness for the worker, higher number => better fit
+		workers[i].fitness = valueDelay*weightDelay + valueProjectFamiliarity*weightProjectFamiliarity + valueDriving*weightDistance + valueDemand*weightDemand + valueScarcity*weightScarcity + valueTrades*weightTrades + valueOvertime*weightWorkerOvertime
+		logger.Debug("Normalized=", workers[i].workerID, valueDelay*weightDelay, valueProjectFamiliarity*weightProjectFamiliarity, valueDriving*weightDistance, valueDemand*weightDemand, valueScarcity*weightScarcity, valueTrades*weightTrades, valueOvertime*weightWorkerOvertime, workers[i].fitness)
+		//Pinned workers go first regardless of the AHP fitness
 		if _, ok := tasksDB[task.taskID].pinnedWorkerIDs[v.workerID]; ok {
 			workers[i].fitness = float32(math.MaxFloat32)
 		}
-		logger.Debug("Values=", workers[i].workerID, valueDelay, valueProjectFamiliarity, valueDriving, valueDemand)
-		//Calculate AHP fitness for the worker, higher number => better fit
-		workers[i].fitness = valueDelay*weightDelay + valueProjectFamiliarity*weightProjectFamiliarity + valueDriving*weightDistance + valueDemand*weightDemand
-		logger.Debug("Normalized=", workers[i].workerID, valueDelay*weightDelay, valueProjectFamiliarity*weightProjectFamiliarity, valueDriving*weightDistance, valueDemand*weightDemand, workers[i].fitness)
 		logger.Debugf("%v=%v", v.workerID, workers[i].fitness)
-		// + valueTrades*weightTrades //TRADES IMPLEMENTATION
 	}
 
 }
 
-func assignBestWorker(task scheduledTask, workers []scheduledWorker) (scheduledTask, bool) {
+//Calculate share of every valid worker scarcity which belongs to the current task.
+//Task with fewer valid workers puts more pressure on them, and worker pressure is summed across all remaining unscheduled tasks
+func calculateWorkersScarcity(task scheduledTask, tasks []scheduledTask) map[string]float32 {
+	workersPressure := make(map[string]float32)
+	for _, remainingTask := range tasks {
+		if isTaskScheduled(remainingTask) || len(tasksDB[remainingTask.taskID].validWorkers) == 0 {
+			continue
+		}
+		taskPressure := 1 / float32(len(tasksDB[remainingTask.taskID].validWorkers))
+		for workerID := range tasksDB[remainingTask.taskID].validWorkers {
+			workersPressure[workerID] += taskPressure
+		}
+	}
+
+	workersScarcity := make(map[string]float32)
+	if len(tasksDB[task.taskID].validWorkers) == 0 {
+		return workersScarcity
+	}
+	taskPressure := 1 / float32(len(tasksDB[task.taskID].validWorkers))
+	for workerID := range tasksDB[task.taskID].validWorkers {
+		if workersPressure[workerID] > 0 {
+			workersScarcity[workerID] = taskPressure / workersPressure[workerID]
+		}
+	}
+	return workersScarcity
+}
+
+//Calculate worker demand as the share of the remaining unscheduled tasks of the individual the worker is valid for
+func calculateRemainingWorkersDemand(tasks []scheduledTask, droppedTasks map[string]struct{}) map[string]float32 {
+	validTasksCount := make(map[string]int)
+	remainingTasks := 0
+	for _, remainingTask := range tasks {
+		if _, ok := droppedTasks[remainingTask.taskID]; ok || isTaskScheduled(remainingTask) {
+			continue
+		}
+		remainingTasks++
+		for workerID := range tasksDB[remainingTask.taskID].validWorkers {
+			validTasksCount[workerID]++
+		}
+	}
+	workersDemand := make(map[string]float32)
+	for workerID, count := range validTasksCount {
+		workersDemand[workerID] = float32(count) / float32(remainingTasks)
+	}
+	return workersDemand
+}
+
+//Check if the worker can be assigned to the task according to the task pinned workers
+func isPinnedWorkerAllowed(task scheduledTask, workerID string) bool {
+	pinnedWorkerIDs := tasksDB[task.taskID].pinnedWorkerIDs
+	if len(pinnedWorkerIDs) == 0 {
+		return true
+	}
+	if _, ok := pinnedWorkerIDs[workerID]; ok {
+		return true
+	}
+	if tasksDB[task.taskID].pinMode != pinAtLeastOneMode {
+		return false
+	}
+	//Any valid worker can join once at least one pinned worker is assigned
+	for _, assignee := range task.assignees {
+		if _, ok := pinnedWorkerIDs[assignee]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+//Check if the worker has all trades required by the task
+func hasRequiredTrades(task scheduledTask, workerID string) bool {
+	for _, requiredTrade := range tasksDB[task.taskID].trades {
+		hasTrade := false
+		for _, trade := range workersDB[workerID].trades {
+			if trade == requiredTrade {
+				hasTrade = true
+				break
+			}
+		}
+		if !hasTrade {
+			return false
+		}
+	}
+	return true
+}
+
+//Task is scheduled if at least minWorkerCount workers are assigned
+func isTaskScheduled(task scheduledTask) bool {
+	return len(task.assignees) >= tasksDB[task.taskID].minWorkerCount
+}
+
+//Check if the worker is already assigned to the task
+func isWorkerAssigned(task scheduledTask, workerID string) bool {
+	for _, assignee := range task.assignees {
+		if assignee == workerID {
+			return true
+		}
+	}
+	return false
+}
+
+func assignBestWorker(task scheduledTask, workers []scheduledWorker, tasks []scheduledTask, trace *csv.Writer) (scheduledTask, bool) {
 
 	var workerAssigned bool = false
-	//Sort workers in the best fit (descending) order - from largest to smallest
-	sort.Slice(workers, func(i, j int) bool {
-		return workers[i].fitness > workers[j].fitness
-	})
+	//First available strategy keeps the current workers order
+	if assignmentStrategy == bestFitStrategy {
+		//Sort workers in the best fit (descending) order - from largest to smallest
+		sort.Slice(workers, func(i, j int) bool {
+			return workers[i].fitness > workers[j].fitness
+		})
+	}
 	//logger.Debug(task)
+	if trace != nil {
+		traceCandidates(trace, task, workers)
+	}
+	//Workers can't be mixed from the alternative crews
+	crew := selectTaskCrew(task, workers)
 
 	//Scan through the workers slice to find the first available worker
 	for i, worker := range workers {
+		//The same worker can't be assigned twice to the task
+		if isWorkerAssigned(task, worker.workerID) {
+			continue
+		}
 		//Skip the all other workers if pinnedWorker is not empty
-		_, ok := tasksDB[task.taskID].pinnedWorkerIDs[worker.workerID]
-		if len(tasksDB[task.taskID].pinnedWorkerIDs) > 0 && !ok {
+		if !isPinnedWorkerAllowed(task, worker.workerID) {
+			continue
+		}
+		//Worker without the required trades can't do the task regardless of the fitness
+		if !hasRequiredTrades(task, worker.workerID) {
+			continue
+		}
+		if _, ok := crew[worker.workerID]; crew != nil && !ok {
 			continue
 		}
 		//Assign only if worker can be assigned to this task
@@ -624,19 +1480,37 @@ func assignBestWorker(task scheduledTask, workers []scheduledWorker) (scheduledT
 		if _, ok := tasksDB[task.taskID].validWorkers[worker.workerID]; ok {
 			//Worker is a valid worker and can be potentially assigned
 			logger.Debugf("Can be assigned, task:%v, worker:%v, start:%v", task.taskID, worker.workerID, worker.availableAt)
+			//Fitness is not calculated for the first available strategy, driving time is required for the start time only
+			if assignmentStrategy == firstAvailableStrategy {
+				worker.valueDriving = calcValueDriving(worker, task)
+				workers[i].valueDriving = worker.valueDriving
+			}
 
-			//TODO: Ignore first driving time from home
+			//Worker can work only inside own daily hours
+			workerSite, ok := workerTaskSite(worker.workerID, task.taskID)
+			if !ok {
+				continue
+			}
 
 			//Earliest possible task start time
-			newStartTime := projectsDB[tasksDB[task.taskID].project].site.AddHours(worker.availableAt, float32(math.Round(100/float64(worker.valueDriving))/100))
-			//Snapping range for the startTime
-			newStartTimeWithSnap := projectsDB[tasksDB[task.taskID].project].site.AddHours(newStartTime, pinnedDateTimeSnap)
-			newPinnedTimeWithSnap := projectsDB[tasksDB[task.taskID].project].site.AddHours(tasksDB[task.taskID].pinnedDateTime, pinnedDateTimeSnap)
-			//If tasksDB[task.taskID].pinnedDateTime < newStartTime+pinnedDateTimeSnap < newPinnedTimeWithSnap+pinnedDateTimeSnap then task be snapped to the pinned datetime
-			taskCanBeSnapped := newStartTimeWithSnap.After(tasksDB[task.taskID].pinnedDateTime) && newStartTimeWithSnap.Before(newPinnedTimeWithSnap)
+			newStartTime := workerSite.AddHours(worker.availableAt, float32(math.Round(100*float64(discountHomeDriving(worker, 1/worker.valueDriving)))/100))
+			//Snapping range is calculated for the pinned tasks only
+			taskCanBeSnapped := false
+			if !tasksDB[task.taskID].pinnedDateTime.IsZero() {
+				//Snapping range for the startTime is symmetric around the pinned datetime, in the task working hours
+				pinnedTimeSnapStart := taskSite(task.taskID).AddHours(tasksDB[task.taskID].pinnedDateTime, -pinnedDateTimeSnap)
+				pinnedTimeSnapEnd := taskSite(task.taskID).AddHours(tasksDB[task.taskID].pinnedDateTime, pinnedDateTimeSnap)
+				//If pinnedDateTime-pinnedDateTimeSnap <= newStartTime <= pinnedDateTime+pinnedDateTimeSnap then task be snapped to the pinned datetime.
+				//Boundaries are inclusive, so zero pinnedDateTimeSnap means the worker should start exactly at the pinned datetime
+				taskCanBeSnapped = !newStartTime.Before(pinnedTimeSnapStart) && !newStartTime.After(pinnedTimeSnapEnd)
+				logger.Debugf("Pinned task snap range. pinnedTimeSnapStart=%v, pinnedTimeSnapEnd=%v, newStartTime=%v, taskCanBeSnapped=%v", pinnedTimeSnapStart, pinnedTimeSnapEnd, newStartTime, taskCanBeSnapped)
+			}
 
 			//Check if task is not pinned, or pinned and in the snap range
-			if tasksDB[task.taskID].pinnedDateTime.IsZero() || (!tasksDB[task.taskID].pinnedDateTime.IsZero() && taskCanBeSnapped) {
+			if tasksDB[task.taskID].pinnedDateTime.IsZero() || taskCanBeSnapped {
+				previousStartTime := task.startTime
+				previousStopTime := task.stopTime
+				crewMoved := false
 				//Task can be assigned
 				if tasksDB[task.taskID].pinnedDateTime.IsZero() {
 					logger.Debugf("Task is not pinned. task.startTime=%v, newStartTime=%v", task.startTime, newStartTime)
@@ -648,29 +1522,119 @@ func assignBestWorker(task scheduledTask, workers []scheduledWorker) (scheduledT
 					} else if task.stopTime.IsZero() && task.startTime.Before(newStartTime) {
 						//Task was never scheduled, but start time defined by predecessors
 						task.startTime = newStartTime
+					} else if synchronizeCrew && task.startTime.Before(newStartTime) {
+						//Crew can't start the task until the current worker arrives, stop time will be recalculated
+						task.startTime = newStartTime
+						task.stopTime = time.Time{}
+						crewMoved = true
 					}
 				} else {
 					//Task is pinned, so start time should be equal to pinned time
-					logger.Debugf("Task pinned. pinnedDateTime=%v, newStartTimeWithSnap=%v, newPinnedTimeWithSnap=%v, newStartTime=%v", tasksDB[task.taskID].pinnedDateTime, newStartTimeWithSnap, newPinnedTimeWithSnap, newStartTime)
+					logger.Debugf("Task pinned. pinnedDateTime=%v, newStartTime=%v", tasksDB[task.taskID].pinnedDateTime, newStartTime)
 					task.startTime = tasksDB[task.taskID].pinnedDateTime
 				}
 
+				//Move never scheduled task to the start of its window
+				if !tasksDB[task.taskID].windowStart.IsZero() && tasksDB[task.taskID].pinnedDateTime.IsZero() && task.stopTime.IsZero() && task.startTime.Before(tasksDB[task.taskID].windowStart) {
+					task.startTime = taskSite(task.taskID).AddHours(tasksDB[task.taskID].windowStart, 0)
+				}
+
+				//Push never scheduled task after the worker time off, workers joining the task can't move it
+				if len(task.assignees) == 0 && tasksDB[task.taskID].pinnedDateTime.IsZero() {
+					task.startTime = findTimeOffStartTime(worker.workerID, task.taskID, task.startTime)
+					task.startTime = findAllocationStartTime(worker, task.taskID, task.startTime)
+				}
+
+				//Push never scheduled task later until the required equipment is available
+				if len(tasksDB[task.taskID].equipment) > 0 && task.stopTime.IsZero() {
+					equipmentStartTime := findEquipmentStartTime(task.taskID, task.startTime, tasks)
+					if !equipmentStartTime.Equal(task.startTime) && !tasksDB[task.taskID].pinnedDateTime.IsZero() {
+						//Pinned task can't be moved
+						logger.Debugf("Equipment is not available for the pinned task:%v", task.taskID)
+						task.startTime = previousStartTime
+						task.stopTime = previousStopTime
+						continue
+					}
+					task.startTime = equipmentStartTime
+				}
+
+				//Task should start inside its start window, pinned task included
+				if (!tasksDB[task.taskID].windowStart.IsZero() && task.startTime.Before(tasksDB[task.taskID].windowStart)) || (!tasksDB[task.taskID].latestStart.IsZero() && task.startTime.After(tasksDB[task.taskID].latestStart)) {
+					logger.Debugf("Task can't be started inside the window, task:%v, startTime:%v", task.taskID, task.startTime)
+					task.startTime = previousStartTime
+					task.stopTime = previousStopTime
+					continue
+				}
+
+				newStopTime := workerSite.AddTaskHours(task.startTime, tasksDB[task.taskID].duration)
+				//Task should be finished inside its window
+				if !tasksDB[task.taskID].windowEnd.IsZero() && newStopTime.After(tasksDB[task.taskID].windowEnd) {
+					logger.Debugf("Task can't be finished inside the window, task:%v, newStopTime:%v", task.taskID, newStopTime)
+					task.startTime = previousStartTime
+					task.stopTime = previousStopTime
+					continue
+				}
+
+				//Worker can't be assigned if the task overlaps the worker time off
+				workerStopTime := newStopTime
+				if workerStopTime.Before(task.stopTime) {
+					workerStopTime = task.stopTime
+				}
+				if _, ok := findTimeOffOverlap(worker.workerID, task.startTime, workerStopTime); ok {
+					logger.Debugf("Worker is on time off, task:%v, worker:%v", task.taskID, worker.workerID)
+					task.startTime = previousStartTime
+					task.stopTime = previousStopTime
+					continue
+				}
+				//Worker can't be assigned if the task doesn't fit into the worker partial allocations
+				if !hasAllocationCapacity(worker, task.taskID, task.startTime, workerStopTime) {
+					logger.Debugf("Worker is fully allocated, task:%v, worker:%v", task.taskID, worker.workerID)
+					task.startTime = previousStartTime
+					task.stopTime = previousStopTime
+					continue
+				}
+				//Workers already assigned to the moved task should be available in the new time range too
+				if crewMoved && !canMoveCrew(task, workers, workerStopTime) {
+					logger.Debugf("Crew can't be moved, task:%v, worker:%v", task.taskID, worker.workerID)
+					task.startTime = previousStartTime
+					task.stopTime = previousStopTime
+					continue
+				}
+
 				task.assignees = append(task.assignees, worker.workerID)
 
 				//logger.Debug(task)
-				newStopTime := projectsDB[tasksDB[task.taskID].project].site.AddHours(task.startTime, tasksDB[task.taskID].duration)
 				//Extend stop time if current worker can't finish in time
 				if task.stopTime.Before(newStopTime) {
 					task.stopTime = newStopTime
 				}
 				//logger.Debug(task)
 				//Change worker's next start time
-				workers[i].availableAt = task.stopTime
+				allocateWorker(&workers[i], task)
+				workers[i].overtimeHours += workerSite.OvertimeHours(task.startTime, newStopTime)
+				workers[i].drivingHours += discountHomeDriving(worker, calcProjectDrivingTime(worker.latitude, worker.longitude, tasksDB[task.taskID].project))
+				if newStartTime.Before(task.startTime) {
+					workers[i].delayHours += workerSite.WorkingHoursBetween(newStartTime, task.startTime)
+				}
+				//Workers already assigned to the moved task are busy until the new stop time
+				if crewMoved {
+					for j := range workers {
+						for _, workerID := range task.assignees {
+							if workers[j].workerID == workerID {
+								allocateWorker(&workers[j], task)
+							}
+						}
+					}
+				}
 
 				//Change worker's location
 				workers[i].latitude = projectsDB[tasksDB[task.taskID].project].latitude
 				workers[i].longitude = projectsDB[tasksDB[task.taskID].project].longitude
 
+				if trace != nil {
+					traceAssignment(trace, task, worker)
+				}
+
 				//Assign success flag to prevent loops on the calling function
 				workerAssigned = true
 				//Worker assigned, ignore other workers
@@ -684,89 +1648,88 @@ func assignBestWorker(task scheduledTask, workers []scheduledWorker) (scheduledT
 	return task, workerAssigned
 }
 
-/*
-//TRADES IMPLEMENTATION
-//Calculate fitness for every worker for the current task WITH TRADES
-func calculateWorkersFitness(task scheduledTask, trade string, workers []scheduledWorker) {
-	for _, v := range workers {
-
-		//Smaller wait time => higher number => better fit
-		valueDelay := v.availableAt
-		if valueDelay == 0 {
-			valueDelay = maxValueDelay
-		} else {
-			valueDelay = 1 / valueDelay
+//Check if all task assignees have no time off and have the allocation capacity between the task start time and stopTime
+func canMoveCrew(task scheduledTask, workers []scheduledWorker, stopTime time.Time) bool {
+	for _, worker := range workers {
+		for _, workerID := range task.assignees {
+			if worker.workerID != workerID {
+				continue
+			}
+			if _, ok := findTimeOffOverlap(workerID, task.startTime, stopTime); ok {
+				return false
+			}
+			if !hasAllocationCapacity(worker, task.taskID, task.startTime, stopTime) {
+				return false
+			}
 		}
-
-		//More hours in project => higher number => better fit
-		valueProjectFamiliarity := projectFamiliarityDB[tasksDB[task.taskID].project][v.workerID]
-
-		//Shorter distance => higher number => better fit
-		valueDriving := calcDistance(v.latitude, v.longitude, projectsDB[tasksDB[task.taskID].project].latitude, projectsDB[tasksDB[task.taskID].project].longitude)
-		if valueDriving == 0 {
-			valueDriving = maxvalueDriving
-		} else {
-			valueDriving = 1 / valueDriving
-		}
-
-		 		//Fewer trades => higher number => better fit
-		   		valueTrades := float32(0)
-		   		trades := workersDB[v.workerID].trades
-		   		for _, v := range trades {
-		   			if v == trade {
-		   				valueTrades = float32(1) / float32(len(trades))
-		   				break
-		   			}
-		   		}
-
-		v.valueDriving = valueDriving
-		v.valueProjectFamiliarity = valueProjectFamiliarity
-		//		v.valueTrades = valueTrades
-		v.valueDelay = valueDelay
-		//Calculate AHP fitness for the worker, higher number => better fit
-		v.fitness = valueDelay*weightDelay + valueProjectFamiliarity*weightProjectFamiliarity + valueDriving*weightDistance // + valueTrades*weightTrades
 	}
-
+	return true
 }
 
-*/
-
-/*
-//TRADES IMPLEMENTATION
-func assignBestWorker(task scheduledTask, workers []scheduledWorker) (scheduledTask, bool) {
+//Add free workers to the scheduled task up to maxWorkerCount. Worker is free if it can arrive before the task start time, so the task is not delayed
+func assignExtraWorkers(task scheduledTask, workers []scheduledWorker, trace *csv.Writer) scheduledTask {
+	if assignmentStrategy == bestFitStrategy {
+		sort.Slice(workers, func(i, j int) bool {
+			return workers[i].fitness > workers[j].fitness
+		})
+	}
+	crew := selectTaskCrew(task, workers)
+	assigned := make(map[string]struct{})
+	for _, workerID := range task.assignees {
+		assigned[workerID] = struct{}{}
+	}
 
-	var workerAssigned bool = false
-	//Sort workers in the best fit (descending) order - from largest to smallest
-	sort.Slice(workers, func(i, j int) bool {
-		return workers[i].fitness > workers[j].fitness
-	})
-	for i, v := range workers {
-		//Assign only if worker has required trade
-		if v.valueTrades != 0 {
-			task.assignees = append(task.assignees, workers[i].workerID)
-			//TODO: Replace with proper calculation and GMaps API
-			task.startTime = workers[0].availableAt + drivingSpeed/workers[i].valueDriving
-
-			//Keep stop time intact for the multiple trades with different availability
-			if task.stopTime-task.startTime < tasksDB[task.taskID].duration {
-				task.stopTime = task.startTime + tasksDB[task.taskID].duration
-			}
-			//Change worker's next start time
-			workers[i].availableAt = task.startTime + tasksDB[task.taskID].duration
-
-			//Change worker's location
-			workers[i].latitude = projectsDB[task.taskID].latitude
-			workers[i].longitude = projectsDB[task.taskID].longitude
-
-			//Assign success flag to prevent loops on the calling function
-			workerAssigned = true
-			//Worker assigned, ignore other workers
+	for i, worker := range workers {
+		if len(task.assignees) >= tasksDB[task.taskID].maxWorkerCount {
 			break
 		}
+		if _, ok := assigned[worker.workerID]; ok {
+			continue
+		}
+		if _, ok := tasksDB[task.taskID].validWorkers[worker.workerID]; !ok {
+			continue
+		}
+		if !isPinnedWorkerAllowed(task, worker.workerID) {
+			continue
+		}
+		if !hasRequiredTrades(task, worker.workerID) {
+			continue
+		}
+		if _, ok := crew[worker.workerID]; crew != nil && !ok {
+			continue
+		}
+		workerSite, ok := workerTaskSite(worker.workerID, task.taskID)
+		if !ok {
+			continue
+		}
+		arrivalTime := workerSite.AddHours(worker.availableAt, float32(math.Round(100*float64(discountHomeDriving(worker, 1/calcValueDriving(worker, task))))/100))
+		if arrivalTime.After(task.startTime) {
+			continue
+		}
+		//Part-time worker can't finish the task in time
+		if workerSite.AddTaskHours(task.startTime, tasksDB[task.taskID].duration).After(task.stopTime) {
+			continue
+		}
+		if _, ok := findTimeOffOverlap(worker.workerID, task.startTime, task.stopTime); ok {
+			continue
+		}
+		if !hasAllocationCapacity(worker, task.taskID, task.startTime, task.stopTime) {
+			continue
+		}
+		task.assignees = append(task.assignees, worker.workerID)
+		assigned[worker.workerID] = struct{}{}
+		allocateWorker(&workers[i], task)
+		workers[i].overtimeHours += workerSite.OvertimeHours(task.startTime, task.stopTime)
+		workers[i].drivingHours += discountHomeDriving(worker, calcProjectDrivingTime(worker.latitude, worker.longitude, tasksDB[task.taskID].project))
+		workers[i].delayHours += workerSite.WorkingHoursBetween(arrivalTime, task.startTime)
+		workers[i].latitude = projectsDB[tasksDB[task.taskID].project].latitude
+		workers[i].longitude = projectsDB[tasksDB[task.taskID].project].longitude
+		if trace != nil {
+			traceAssignment(trace, task, worker)
+		}
 	}
-	return task, workerAssigned
+	return task
 }
-*/
 
 func copyIndividual(oldIndividual individual) individual {
 	var newIndividual individual
@@ -774,7 +1737,15 @@ func copyIndividual(oldIndividual individual) individual {
 	copy(newIndividual.tasks, oldIndividual.tasks)
 	newIndividual.workers = make([]scheduledWorker, len(oldIndividual.workers))
 	copy(newIndividual.workers, oldIndividual.workers)
+	for i, worker := range oldIndividual.workers {
+		newIndividual.workers[i].allocations = append([]workerAllocation(nil), worker.allocations...)
+	}
+	newIndividual.droppedTasks = make(map[string]struct{}, len(oldIndividual.droppedTasks))
+	for k := range oldIndividual.droppedTasks {
+		newIndividual.droppedTasks[k] = struct{}{}
+	}
 	newIndividual.fitness = oldIndividual.fitness
+	newIndividual.fitnessData = oldIndividual.fitnessData
 	return newIndividual
 }
 
@@ -787,8 +1758,10 @@ func copyIndividuals(oldIndividuals []individual) []individual {
 }
 
 //Apply crossovers and mutations on non-elite individuals
-func transmogrifyPopulation(pop population) population {
-	elitesNum := int(elitismRate * float32(len(pop.individuals)))
+//New population has the same size as the original one, global populationSize is not used, so populations of any size can be transmogrified
+func transmogrifyPopulation(pop population, mutationRate float32) population {
+	populationLen := len(pop.individuals)
+	elitesNum := int(elitismRate * float32(populationLen))
 	//logger.Info("elitesNum=", elitesNum)
 	var newPopulation population
 	var tempIndividuals []individual
@@ -798,23 +1771,48 @@ func transmogrifyPopulation(pop population) population {
 	newPopulation.individuals = copyIndividuals(pop.individuals[:elitesNum])
 	//Recalculate hash for the elites
 	newPopulation.hashes = calcIndividualsHash(newPopulation.individuals)
+	//Add fresh random individuals (immigrants) right after the elites
+	immigrantsNum := int(immigrationRate * float32(populationLen))
+	if immigrantsNum > populationLen-elitesNum {
+		immigrantsNum = populationLen - elitesNum
+	}
+	for i := 0; i < immigrantsNum; i++ {
+		immigrant := generateIndividual()
+		immigrantHash := calcIndividualHash(immigrant)
+		if _, ok := newPopulation.hashes[immigrantHash]; !ok {
+			newPopulation.hashes[immigrantHash] = len(newPopulation.individuals)
+			newPopulation.individuals = append(newPopulation.individuals, immigrant)
+		}
+	}
+	logger.Debug("newPopulation size with immigrants =", len(newPopulation.individuals))
 	//logger.Info("NewElite=", newPopulation[0])
 	logger.Debug("newPopulation size with elites =", len(newPopulation.individuals))
-	logger.Debug("Best elite fitness =", newPopulation.individuals[0].fitness)
+	//Small population can have no elites
+	if len(newPopulation.individuals) > 0 {
+		logger.Debug("Best elite fitness =", newPopulation.individuals[0].fitness)
+	}
 	//loggerFile.Info("ELITES:", newPopulation[0].tasks)
-	remainingIndividualsNumber := len(pop.individuals) - elitesNum
+	remainingIndividualsNumber := populationLen - len(newPopulation.individuals)
 	logger.Debug("remainingIndividualsNumber =", remainingIndividualsNumber)
 	//Generate len(population)-elitesNum additonal individuals
 	for condition := true; condition; condition = remainingIndividualsNumber > 0 {
 		tempIndividuals = make([]individual, crossoverParentsNumber)
 		//Select crossoverParentsNumber from the population with Torunament Selection
-		tempIndividuals = tourneySelect(pop.individuals, crossoverParentsNumber)
+		if selectionMethod == rankSelection {
+			tempIndividuals = rankSelect(pop.individuals, crossoverParentsNumber)
+		} else {
+			tempIndividuals = tourneySelect(pop.individuals, crossoverParentsNumber)
+		}
 		logger.Debug("tempPopulation size after tourney =", len(tempIndividuals))
 		//Apply crossover to the tempPopulation
-		tempIndividuals = crossoverIndividualsOX1(tempIndividuals)
+		if crossoverMethod == pmxCrossover {
+			tempIndividuals = crossoverIndividualsPMX(tempIndividuals)
+		} else {
+			tempIndividuals = crossoverIndividualsOX1(tempIndividuals)
+		}
 		logger.Debug("tempPopulation size after crossover =", len(tempIndividuals))
 		//Apply mutation to the tempPopulation
-		tempIndividuals = mutateIndividuals(tempIndividuals)
+		tempIndividuals = mutateIndividuals(tempIndividuals, mutationRate)
 		logger.Debug("tempPopulation size after mutation =", len(tempIndividuals))
 		//Append tempPopulation to the new population, if indviduals are new
 		for _, v := range tempIndividuals {
@@ -837,7 +1835,7 @@ func transmogrifyPopulation(pop population) population {
 
 	logger.Debug("newPopulation.hashes=", newPopulation.hashes)
 	//Cut extra individuals generated by mutation/crossover
-	newPopulation.individuals = newPopulation.individuals[:len(pop.individuals)]
+	newPopulation.individuals = newPopulation.individuals[:populationLen]
 	return newPopulation
 }
 
@@ -857,8 +1855,20 @@ func tourneySelect(population []individual, number int) []individual {
 		bestIndividualNumber = 0
 		sampleOrderNumber = 0
 		bestIndividualFitness = float32(math.MaxFloat32)
-		//Select best individual number from first tourneySampleSize elements in sampleOrder
-		for j, v := range sampleOrder[:tourneySampleSize] {
+		//All individuals were selected, start over from the whole population
+		if len(sampleOrder) == 0 {
+			sampleOrder = rand.Perm(len(population))
+		}
+		//Sample size can exceed remaining individuals after the reshuffle, but at least one should be sampled
+		sampleSize := tourneySampleSize
+		if sampleSize > len(sampleOrder) {
+			sampleSize = len(sampleOrder)
+		}
+		if sampleSize < 1 {
+			sampleSize = 1
+		}
+		//Select best individual number from first sampleSize elements in sampleOrder
+		for j, v := range sampleOrder[:sampleSize] {
 			logger.Debugf("Processing sample %v, sample value %v", j, v)
 			if population[v].fitness < bestIndividualFitness {
 				bestIndividualNumber = v
@@ -886,6 +1896,38 @@ func tourneySelect(population []individual, number int) []individual {
 	return bestIndividuals
 }
 
+//Linear rank selection for the crossover. Individuals are ranked by fitness (ascending), the best of n individuals has weight n and the worst has weight 1.
+//Every individual is selected once until all individuals were selected
+func rankSelect(population []individual, number int) []individual {
+	rankOrder := make([]int, len(population))
+	for i := range rankOrder {
+		rankOrder[i] = i
+	}
+	sort.SliceStable(rankOrder, func(i, j int) bool {
+		return population[rankOrder[i]].fitness < population[rankOrder[j]].fitness
+	})
+
+	var selectedIndividuals []individual
+	candidates := append([]int(nil), rankOrder...)
+	for i := 0; i < number; i++ {
+		//All individuals were selected, start over from the whole population
+		if len(candidates) == 0 {
+			candidates = append(candidates, rankOrder...)
+		}
+		//Candidates keep the rank order, so weight of the k-th candidate is len(candidates)-k
+		totalWeight := len(candidates) * (len(candidates) + 1) / 2
+		draw := rand.Intn(totalWeight)
+		k := 0
+		for weight := len(candidates); draw >= weight; weight-- {
+			draw -= weight
+			k++
+		}
+		selectedIndividuals = append(selectedIndividuals, population[candidates[k]])
+		candidates = append(candidates[:k], candidates[k+1:]...)
+	}
+	return selectedIndividuals
+}
+
 func displacementMutation(individual individual) individual {
 	//Randomly select number of genes to mutate, but at least 1
 	numOfGenesToMutate := rand.Intn(maxMutatedGenes) + 1
@@ -918,7 +1960,7 @@ func swapMutation(individual individual) individual {
 
 }
 
-func mutateIndividuals(individuals []individual) []individual {
+func mutateIndividuals(individuals []individual, mutationRate float32) []individual {
 	var mutatedIndividuals []individual
 	//var crossoverStart, crossoverEnd, crossoverLen int
 	//Copy parent to child individuals slice
@@ -934,6 +1976,10 @@ func mutateIndividuals(individuals []individual) []individual {
 				//Do the swap mutation
 				mutatedIndividuals[i] = swapMutation(mutatedIndividuals[i])
 			}
+			//Optional tasks are scheduled or dropped independently of the tasks order
+			if rand.Intn(2) == 0 {
+				mutatedIndividuals[i] = toggleOptionalMutation(mutatedIndividuals[i])
+			}
 		}
 	}
 	return mutatedIndividuals
@@ -949,11 +1995,17 @@ func crossoverIndividualsOX1(parentIndividuals []individual) []individual {
 	//Check if we need to crossover
 
 	if rand.Float32() < crossoverRate {
-		crossoverStart := rand.Intn(sizeIndividualTasks)
+		//Crossover is limited to the locality window, tasks outside of it are kept in place
+		windowStart, windowEnd := 0, sizeIndividualTasks
+		if crossoverLocality > 0 && crossoverLocality < sizeIndividualTasks {
+			windowStart = rand.Intn(sizeIndividualTasks - crossoverLocality)
+			windowEnd = windowStart + crossoverLocality + 1
+		}
+		crossoverStart := windowStart + rand.Intn(windowEnd-windowStart)
 		crossoverLen := rand.Intn(maxCrossoverLength)
 		crossoverEnd := crossoverStart + crossoverLen
-		if crossoverEnd > sizeIndividualTasks {
-			crossoverEnd = sizeIndividualTasks
+		if crossoverEnd > windowEnd {
+			crossoverEnd = windowEnd
 		}
 		logger.Debug("crossoverStart=", crossoverStart)
 		logger.Debug("crossoverLen=", crossoverLen)
@@ -970,12 +2022,18 @@ func crossoverIndividualsOX1(parentIndividuals []individual) []individual {
 				childIndividuals[i].tasks[j].taskID = parent.tasks[j].taskID
 				copiedGenes[parent.tasks[j].taskID] = struct{}{}
 			}
+			//Genes outside of the locality window are already in place
+			for j := range parent.tasks {
+				if j < windowStart || j >= windowEnd {
+					copiedGenes[parent.tasks[j].taskID] = struct{}{}
+				}
+			}
 
-			childIndex := 0
+			childIndex := windowStart
 			parentIndex := 0
 
 			//Loop across the last parent and copy non-repeating genes (tasks)
-			for childIndex < sizeIndividualTasks && parentIndex < sizeIndividualTasks {
+			for childIndex < windowEnd && parentIndex < sizeIndividualTasks {
 				parentTask := parentIndividuals[len(parentIndividuals)-i-1].tasks[parentIndex]
 				logger.Debugf("childIndex=%v, parentIndex=%v", childIndex, parentIndex)
 				if childIndex >= crossoverStart && childIndex < crossoverEnd {
@@ -994,31 +2052,36 @@ func crossoverIndividualsOX1(parentIndividuals []individual) []individual {
 	return childIndividuals
 }
 
-func crossoverIndividuals(parentIndividuals []individual) []individual {
-	var childIndividuals []individual
-	//var crossoverStart, crossoverEnd, crossoverLen int
-	//Copy parent to child individuals slice
-	//childIndividuals = make([]individual, len(parentIndividuals))
-	childIndividuals = copyIndividuals(parentIndividuals)
-	//Check if we need to crossover
+//Crossover individuals by Partially Mapped Crossover method (PMX)
+func crossoverIndividualsPMX(parentIndividuals []individual) []individual {
+	childIndividuals := copyIndividuals(parentIndividuals)
+	sizeIndividualTasks := len(childIndividuals[0].tasks)
 	if rand.Float32() < crossoverRate {
-		crossoverStart := rand.Intn(len(childIndividuals[0].tasks))
-		crossoverLen := rand.Intn(maxCrossoverLength)
-		crossoverEnd := crossoverStart + crossoverLen
-		if crossoverEnd > len(childIndividuals[0].tasks) {
-			crossoverEnd = len(childIndividuals[0].tasks)
+		crossoverStart := rand.Intn(sizeIndividualTasks)
+		crossoverEnd := crossoverStart + rand.Intn(maxCrossoverLength)
+		if crossoverEnd > sizeIndividualTasks {
+			crossoverEnd = sizeIndividualTasks
 		}
-		//TODO: Add random selection of the swappable individuals
-		for i := range childIndividuals {
-			//Swap part of the tasks slice between first and second individual
+		logger.Debug("crossoverStart=", crossoverStart)
+		logger.Debug("crossoverEnd=", crossoverEnd)
+		for i, parent := range parentIndividuals {
+			secondParent := parentIndividuals[len(parentIndividuals)-i-1]
+			//Position of every gene copied from the first parent
+			copiedGenes := make(map[string]int)
 			for j := crossoverStart; j < crossoverEnd; j++ {
-				first := i
-				second := i + 1
-				if second == len(childIndividuals) {
-					second = 0
+				childIndividuals[i].tasks[j].taskID = parent.tasks[j].taskID
+				copiedGenes[parent.tasks[j].taskID] = j
+			}
+			//Genes outside of the segment are taken from the second parent, already copied genes are replaced through the segment mapping
+			for j := range secondParent.tasks {
+				if j >= crossoverStart && j < crossoverEnd {
+					continue
 				}
-				//Swap current task between first and second individual
-				childIndividuals[first].tasks[j], childIndividuals[second].tasks[j] = childIndividuals[second].tasks[j], childIndividuals[first].tasks[j]
+				taskID := secondParent.tasks[j].taskID
+				for position, ok := copiedGenes[taskID]; ok; position, ok = copiedGenes[taskID] {
+					taskID = secondParent.tasks[position].taskID
+				}
+				childIndividuals[i].tasks[j].taskID = taskID
 			}
 		}
 	}
@@ -1034,13 +2097,18 @@ func sortPopulation(population []individual) {
 
 func generatePopulationSchedules(population []individual) {
 	//TODO: Slice will be modified in place, need to check
+	populationLen := len(population)
 	//Number of elites
-	elitesNum := int(elitismRate * float32(len(population)))
+	elitesNum := int(elitismRate * float32(populationLen))
 
 	chanIndividualIn := make(chan individual)
 	chanIndividualOut := make(chan individual)
-	//Start go subroutines to handle the calculation
-	for i := 0; i < threadsNum; i++ {
+	//Start go subroutines to handle the calculation, there is no need in more subroutines than individuals
+	poolSize := threadsNum
+	if poolSize > populationLen {
+		poolSize = populationLen
+	}
+	for i := 0; i < poolSize; i++ {
 		go generateIndividualSchedule(chanIndividualIn, chanIndividualOut)
 	}
 
@@ -1053,13 +2121,13 @@ func generatePopulationSchedules(population []individual) {
 		}
 	}
 
-	//Recalculate everyone else
+	//Recalculate everyone else, from elitesNum to the last individual inclusive
 	j := elitesNum
 	remainingThreads := 0
-	for j < populationSize-1 {
-		remainingThreads = populationSize - j - 1
-		if remainingThreads > threadsNum {
-			remainingThreads = threadsNum
+	for j < populationLen {
+		remainingThreads = populationLen - j
+		if remainingThreads > poolSize {
+			remainingThreads = poolSize
 		}
 		for i := 0; i < remainingThreads; i++ {
 			//Push data to the subroutines
@@ -1074,7 +2142,7 @@ func generatePopulationSchedules(population []individual) {
 			//logger.Info("Got result: ", population[j].fitness)
 		}
 		j += remainingThreads
-		logger.Infof("%v individuals completed", j+1)
+		logger.Infof("%v individuals completed", j)
 
 	}
 	close(chanIndividualIn)
@@ -1091,135 +2159,298 @@ func generateIndividualSchedule(chanIndividualIn, chanIndividualOut chan individ
 			//logger.Info("Subroutine stopped")
 			break
 		}
-		individual = resetIndividual(individual)
-		var workerAssigned bool = true
-		//Infinite loop until no workers can be assigned
-		logger.Debug("Infinite loop until no workers can be assigned")
-		for condition := true; condition; condition = workerAssigned {
-			//Prevent loops if no tasks left to process
-			workerAssigned = false
-			//Loop across all tasks
-			for i, task := range individual.tasks {
-				logger.Debug("Processing taskID =", task.taskID)
-				//Process only tasks with remaining worker slots and with all the dependencies met
-				if len(task.assignees) < tasksDB[task.taskID].idealWorkerCount && task.numPrerequisites == 0 {
-					//Assign workers to the task until idealWorkerCount
-					for j := len(individual.tasks[i].assignees); j < tasksDB[task.taskID].idealWorkerCount; j++ {
-						//logger.Debug("worker j =", j)
-						//Calculate fitness of idealWorkerCount workers for specific task
-						//TODO: Add "taint" flag to worker to prevent recalculation of fitness for untouched workers
-						calculateWorkersFitness(task, individual.workers)
-						//logger.Debug(task)
-						//Try to assign worker to task and update worker data
-						//TODO: Multiple bool assignments. Any way to make it better?
-						individual.tasks[i], workerAssigned = assignBestWorker(task, individual.workers)
-						//logger.Debug(individual.tasks[i])
-					}
-					//Modify dependant tasks if idealWorkerCount workers are scheduled
-					if len(individual.tasks[i].assignees) == tasksDB[task.taskID].idealWorkerCount {
-						prerequisiteTask := individual.tasks[i]
-						//Loop over all tasks
-						for i, task := range individual.tasks {
-							if task.numPrerequisites > 0 {
-								//Check if prerequisiteTask.taskID exists in the prerequisites map in tasksDB
-								if _, ok := tasksDB[task.taskID].prerequisites[prerequisiteTask.taskID]; ok {
-									//Remove this task from prerequisites for all other tasks
-									individual.tasks[i].numPrerequisites--
-									//Update task.startTime to match predecessor stop time and account for lag/lead hours
-									newStopTime := projectsDB[tasksDB[task.taskID].project].site.AddHours(prerequisiteTask.stopTime, tasksDB[task.taskID].prerequisites[prerequisiteTask.taskID])
-									if individual.tasks[i].startTime.Before(newStopTime) {
-										individual.tasks[i].startTime = newStopTime
-									}
+		//logger.Info("Sending individual: ", individual.fitness)
+		chanIndividualOut <- scheduleIndividual(individual, nil)
+		//logger.Info("Individual sent: ", individual.fitness)
+	}
+}
 
+//Generate individual schedule and calculate fitness, assignment decisions are recorded to the trace if it's not nil
+func scheduleIndividual(individual individual, trace *csv.Writer) individual {
+	individual = resetIndividual(individual)
+	var workerAssigned bool = true
+	//Infinite loop until no workers can be assigned
+	logger.Debug("Infinite loop until no workers can be assigned")
+	for condition := true; condition; condition = workerAssigned {
+		//Prevent loops if no tasks left to process
+		workerAssigned = false
+		//Loop across all tasks
+		for i, task := range individual.tasks {
+			logger.Debug("Processing taskID =", task.taskID)
+			//Skip dropped optional tasks
+			if _, ok := individual.droppedTasks[task.taskID]; ok {
+				continue
+			}
+			//Process only not scheduled tasks with all the dependencies met
+			if !isTaskScheduled(task) && task.numPrerequisites == 0 {
+				//Dynamic scarcity of the valid workers for the current task
+				var workersScarcity map[string]float32
+				if weightScarcity > 0 {
+					workersScarcity = calculateWorkersScarcity(task, individual.tasks)
+				}
+				//Dynamic demand of the workers for the remaining unscheduled tasks
+				var workersDemand map[string]float32
+				if assignmentStrategy == bestFitStrategy {
+					workersDemand = calculateRemainingWorkersDemand(individual.tasks, individual.droppedTasks)
+				}
+				//Fitness depends on the task, so all workers are recalculated for the new task
+				for j := range individual.workers {
+					individual.workers[j].tainted = true
+				}
+				//Assign workers to the task until idealWorkerCount
+				for j := len(individual.tasks[i].assignees); j < tasksDB[task.taskID].idealWorkerCount; j++ {
+					//logger.Debug("worker j =", j)
+					//Calculate fitness of idealWorkerCount workers for specific task
+					if assignmentStrategy == bestFitStrategy {
+						calculateWorkersFitness(task, individual.workers, workersScarcity, workersDemand)
+					}
+					//logger.Debug(task)
+					//Try to assign worker to task and update worker data
+					var assigned bool
+					individual.tasks[i], assigned = assignBestWorker(individual.tasks[i], individual.workers, individual.tasks, trace)
+					//logger.Debug(individual.tasks[i])
+					//No more workers can be assigned, task is scheduled if minWorkerCount is reached
+					if !assigned {
+						break
+					}
+					workerAssigned = true
+				}
+				//Opportunistically add free workers up to maxWorkerCount
+				if len(individual.tasks[i].assignees) >= tasksDB[task.taskID].idealWorkerCount && len(individual.tasks[i].assignees) < tasksDB[task.taskID].maxWorkerCount {
+					if assignmentStrategy == bestFitStrategy {
+						calculateWorkersFitness(individual.tasks[i], individual.workers, workersScarcity, workersDemand)
+					}
+					individual.tasks[i] = assignExtraWorkers(individual.tasks[i], individual.workers, trace)
+				}
+				//Modify dependant tasks if minWorkerCount workers are scheduled
+				if isTaskScheduled(individual.tasks[i]) {
+					prerequisiteTask := individual.tasks[i]
+					//Loop over all tasks
+					for i, task := range individual.tasks {
+						if task.numPrerequisites > 0 {
+							//Check if prerequisiteTask.taskID exists in the prerequisites map in tasksDB
+							if _, ok := tasksDB[task.taskID].prerequisites[prerequisiteTask.taskID]; ok {
+								//Remove this task from prerequisites for all other tasks
+								individual.tasks[i].numPrerequisites--
+								//Update task.startTime to match predecessor start or stop time and account for lag/lead hours
+								newStopTime := calcDependencyStartTime(task.taskID, prerequisiteTask.taskID, prerequisiteTask.startTime, prerequisiteTask.stopTime)
+								if individual.tasks[i].startTime.Before(newStopTime) {
+									individual.tasks[i].startTime = newStopTime
 								}
 
 							}
 
 						}
+
 					}
 				}
 			}
 		}
+	}
+
+	return calculateIndividualFitness(individual)
+}
 
-		//Default to best individual
-		individual.fitness = 0
-		var unscheduledTasksNumber float32 = 0
-		for _, task := range individual.tasks {
+//Calculate the earliest task start time allowed by the prerequisite dependency type and lag/lead hours
+func calcDependencyStartTime(taskID string, prerequisiteID string, prerequisiteStartTime time.Time, prerequisiteStopTime time.Time) time.Time {
+	site := taskSite(taskID)
+	lagHours := tasksDB[taskID].prerequisites[prerequisiteID]
+	switch tasksDB[taskID].dependencyTypes[prerequisiteID] {
+	case startToStart:
+		return site.AddHours(prerequisiteStartTime, lagHours)
+	case finishToFinish:
+		//Task should stop after the prerequisite, so it starts the task duration earlier
+		return site.AddHours(site.AddHours(prerequisiteStopTime, lagHours), -tasksDB[taskID].duration)
+	default:
+		return site.AddHours(prerequisiteStopTime, lagHours)
+	}
+}
+
+//Calculate individual fitness, every fitness component is stored separately in the fitnessData
+func calculateIndividualFitness(individual individual) individual {
+	//Default to best individual
+	individual.fitnessData = fitnessBreakdown{}
+	for _, task := range individual.tasks {
+		//Optional tasks are rewarded if scheduled instead of the penalty if not
+		if tasksDB[task.taskID].optionalReward > 0 {
+			if isTaskScheduled(task) {
+				individual.fitnessData.optionalReward += tasksDB[task.taskID].optionalReward
+			}
+		} else if !isTaskScheduled(task) {
 			//If we have tasks/trades with no workers assigned, the individual is a dead end
-			if len(task.assignees) != tasksDB[task.taskID].idealWorkerCount {
-				//Individual has unscheduled tasks. Fewer unscheduled tasks => better individual fitness
-				logger.Debug("Can't schedule: ", task)
-				unscheduledTasksNumber++
+			//Individual has unscheduled tasks. Fewer unscheduled tasks => better individual fitness
+			logger.Debug("Can't schedule: ", task)
+			individual.fitnessData.unscheduledTasks++
+			//Penalty is proportional to the missing workers, so nearly staffed task is better than the unstaffed one
+			minWorkerCount := tasksDB[task.taskID].minWorkerCount
+			individual.fitnessData.unscheduledPenalty += deadend * float32(minWorkerCount-len(task.assignees)) / float32(minWorkerCount)
+		}
+		//Earlier stopTime => faster we finish all the tasks => better individual fitness
+		if individual.fitnessData.makespan < float32(task.stopTime.Sub(scheduleStartTime).Hours()) {
+			individual.fitnessData.makespan = float32(task.stopTime.Sub(scheduleStartTime).Hours())
+		}
+		//Finish datetime of the scheduled tasks only, zero if nothing is scheduled
+		if isTaskScheduled(task) && individual.fitnessData.finishDateTime.Before(task.stopTime) {
+			individual.fitnessData.finishDateTime = task.stopTime
+		}
+	}
+	//Fewer project switches between consecutive days => better individual fitness
+	if weightProjectContinuity > 0 {
+		individual.fitnessData.projectSwitches = weightProjectContinuity * float32(countProjectSwitches(individual))
+	}
+	//Fewer idle hours inside the no interruption projects => better individual fitness
+	if weightProjectIdle > 0 {
+		individual.fitnessData.projectIdle = weightProjectIdle * calcProjectsIdleHours(individual)
+	}
+	//Fewer overtime hours => better individual fitness
+	if weightOvertime > 0 {
+		individual.fitnessData.overtime = weightOvertime * calcOvertimeCost(individual)
+	}
+	//Less driving and waiting of all workers => better individual fitness
+	var peakOvertimeHours float32
+	for _, worker := range individual.workers {
+		individual.fitnessData.drivingHours += worker.drivingHours
+		individual.fitnessData.delay += weightDelayHours * worker.delayHours
+		if worker.overtimeHours > peakOvertimeHours {
+			peakOvertimeHours = worker.overtimeHours
+		}
+	}
+	//Overtime spread between the workers => better individual fitness
+	individual.fitnessData.peakOvertime = weightPeakOvertime * peakOvertimeHours
+	individual.fitnessData.driving = weightDrivingHours * individual.fitnessData.drivingHours
+	individual.fitness = weightMakespan*individual.fitnessData.makespan + individual.fitnessData.unscheduledPenalty - individual.fitnessData.optionalReward + individual.fitnessData.projectSwitches + individual.fitnessData.projectIdle + individual.fitnessData.overtime + individual.fitnessData.peakOvertime + individual.fitnessData.driving + individual.fitnessData.delay
+	return individual
+}
+
+//Calculate finish datetime of every project from its scheduled tasks, projects without scheduled tasks are omitted
+func calcProjectsFinishDateTime(individual individual) map[string]time.Time {
+	projectsFinishDateTime := make(map[string]time.Time)
+	for _, task := range individual.tasks {
+		if !isTaskScheduled(task) {
+			continue
+		}
+		projectID := tasksDB[task.taskID].project
+		if projectsFinishDateTime[projectID].Before(task.stopTime) {
+			projectsFinishDateTime[projectID] = task.stopTime
+		}
+	}
+	return projectsFinishDateTime
+}
+
+//Calculate overtime hours of all assignees multiplied by the site overtime multiplier
+func calcOvertimeCost(individual individual) float32 {
+	var overtimeCost float32
+	for _, task := range individual.tasks {
+		if task.stopTime.IsZero() {
+			continue
+		}
+		site := taskSite(task.taskID)
+		overtimeCost += float32(len(task.assignees)) * site.OvertimeHours(task.startTime, task.stopTime) * site.OvertimeMultiplier
+	}
+	return overtimeCost
+}
+
+//Count how many times workers are not continuing any of the previous working day projects on the next working day
+func countProjectSwitches(individual individual) int {
+	//key1 is the worker ID, key2 is the day, key3 is the project ID
+	workerDays := make(map[string]map[time.Time]map[string]struct{})
+	for _, task := range individual.tasks {
+		if task.stopTime.IsZero() {
+			continue
+		}
+		projectID := tasksDB[task.taskID].project
+		site := taskSite(task.taskID)
+		//Task can span several days, only days with the task working hours are marked.
+		//Task starting at the daily end time doesn't work on its start day
+		var taskDays []time.Time
+		day := time.Date(task.startTime.Year(), task.startTime.Month(), task.startTime.Day(), 0, 0, 0, 0, task.startTime.Location())
+		for day.Before(task.stopTime) {
+			nextDay := day.AddDate(0, 0, 1)
+			dayStartTime, dayStopTime := day, nextDay
+			if dayStartTime.Before(task.startTime) {
+				dayStartTime = task.startTime
 			}
-			//Earlier stopTime => faster we finish all the tasks => better individual fitness
-			if individual.fitness < float32(task.stopTime.Sub(scheduleStartTime).Hours()) {
-				individual.fitness = float32(task.stopTime.Sub(scheduleStartTime).Hours())
+			if task.stopTime.Before(dayStopTime) {
+				dayStopTime = task.stopTime
 			}
+			if site.WorkingHoursBetween(dayStartTime, dayStopTime) > 0 {
+				taskDays = append(taskDays, day)
+			}
+			day = nextDay
 		}
-		if unscheduledTasksNumber > 0 {
-			individual.fitness = unscheduledTasksNumber*deadend + individual.fitness
+		for _, workerID := range task.assignees {
+			if _, ok := workerDays[workerID]; !ok {
+				workerDays[workerID] = make(map[time.Time]map[string]struct{})
+			}
+			for _, day := range taskDays {
+				if _, ok := workerDays[workerID][day]; !ok {
+					workerDays[workerID][day] = make(map[string]struct{})
+				}
+				workerDays[workerID][day][projectID] = struct{}{}
+			}
 		}
-		//logger.Info("Sending individual: ", individual.fitness)
-		chanIndividualOut <- individual
-		//logger.Info("Individual sent: ", individual.fitness)
 	}
+
+	projectSwitches := 0
+	for _, days := range workerDays {
+		for day, projects := range days {
+			worked, continued := false, false
+			for projectID := range projects {
+				//Next working day of the project site, skipping weekends and holidays
+				site := projectsDB[projectID].site
+				nextDay := day.AddDate(0, 0, 1)
+				for !site.IsWorkingDay(nextDay) {
+					nextDay = nextDay.AddDate(0, 0, 1)
+				}
+				nextProjects, ok := days[nextDay]
+				if !ok {
+					continue
+				}
+				worked = true
+				if _, ok := nextProjects[projectID]; ok {
+					continued = true
+					break
+				}
+			}
+			if worked && !continued {
+				projectSwitches++
+			}
+		}
+	}
+	return projectSwitches
 }
 
-/*
-//TRADES IMPLEMENTATION
-//Generate individual schedule and calculate fitness WITH TRADES (future version)
-//func generateIndividualScheduleWithTrades(individual individual) individual {
+//Sum idle working hours between the first start and the last stop of every no interruption project
+func calcProjectsIdleHours(individual individual) float32 {
+	projectTasks := make(map[string][]scheduledTask)
+	for _, task := range individual.tasks {
+		projectID := tasksDB[task.taskID].project
+		if !projectsDB[projectID].noInterruption || task.startTime.IsZero() || task.stopTime.IsZero() {
+			continue
+		}
+		projectTasks[projectID] = append(projectTasks[projectID], task)
+	}
 
-	//var workerAssigned bool = true
-	//Infinite loop until no workers can be assigned
-	 	for condition := true; condition; condition = workerAssigned {
-	   		//Prevent loops if no tasks left to process
-	   		workerAssigned = false
-	   		//Loop across all tasks
-	   		for i, task := range individual.tasks {
-	   			//Process only tasks with remaining trades and with all the dependencies met
-	   			if len(task.assignees) < len(tasksDB[task.taskID].trades) && task.numPrerequisites == 0 {
-	   				for _, trade := range tasksDB[task.taskID].trades {
-	   					//Calculate fitness of all workers for specific task and trade
-	   					//TODO: Add "taint" flag to worker to prevent recalculation of fitness for untouched workers
-	   					calculateWorkersFitness(task, trade, individual.workers)
-	   					//Try to assign worker to task and update worker data
-	   					//TODO: Multiple bool assignments. Any way to make it better?
-	   					individual.tasks[i], workerAssigned = assignBestWorker(task, individual.workers)
-	   				}
-	   				//Remove this task from prerequisites for all other tasks if all trades are scheduled
-	   				if len(task.assignees) == len(tasksDB[task.taskID].trades) {
-	   					prerequisiteID := task.taskID
-	   					//Loop over all tasks
-	   					for i, task := range individual.tasks {
-	   						if task.numPrerequisites > 0 {
-	   							//Check if prerequisiteID exists in the prerequisites map in taskDB
-	   							if _, ok := tasksDB[task.taskID].prerequisites[prerequisiteID]; ok {
-	   								individual.tasks[i].numPrerequisites--
-	   							}
-	   						}
-	   					}
-	   				}
-	   			}
-	   		}
-	   	}
-*/
-//Calculate viability and fitness
-
-/* 	for _, task := range individual.tasks {
-	   		//If we have tasks/trades with no workers assigned, the individual is a dead end
-	   		if len(task.assignees) != len(tasksDB[task.taskID].trades) {
-	   			individual.fitness = deadend
-	   			break
-	   		}
-	   		//Earlier stopTime => faster we finish all the tasks => better individual fitness
-	   		if individual.fitness < task.stopTime {
-	   			individual.fitness = task.stopTime
-	   		}
-	   	}
-	return individual
+	var idleHours float32
+	for projectID, tasks := range projectTasks {
+		sort.Slice(tasks, func(i, j int) bool {
+			return tasks[i].startTime.Before(tasks[j].startTime)
+		})
+		site := projectsDB[projectID].site
+		//Gaps between the merged task intervals are the project idle time
+		activeStopTime := tasks[0].stopTime
+		for _, task := range tasks[1:] {
+			if task.startTime.After(activeStopTime) {
+				idleHours += site.WorkingHoursBetween(activeStopTime, task.startTime)
+			}
+			if task.stopTime.After(activeStopTime) {
+				activeStopTime = task.stopTime
+			}
+		}
+	}
+	return idleHours
 }
-*/
+
 func prettyPrintTask(task scheduledTask) {
 	name := tasksDB[task.taskID].name
 	id := strings.Split(task.taskID, ".")[1]
@@ -1247,27 +2478,344 @@ func prettyPrintTask(task scheduledTask) {
 	}
 	pinnedWorkersNames := strings.Join(pinnedWorkers, ",")
 	if !tasksDB[task.taskID].pinnedDateTime.IsZero() {
-		pinnedDateTime = tasksDB[task.taskID].pinnedDateTime.Format("2006/01/02 15:04")
+		pinnedDateTime = formatOutputDateTime(tasksDB[task.taskID].pinnedDateTime, prettyDateTimeFormat)
+	}
+
+	logger.Infof(";%v;%v;%v;%v;%v;%v;%v;%v;%v;%v;%v", formatOutputDateTime(startDateTime, prettyDateTimeFormat), formatOutputDateTime(stopDateTime, prettyDateTimeFormat), projectName, name, workersNames, workersIDs, id, projectID, predecessorsIDs, pinnedWorkersNames, pinnedDateTime)
+}
+
+//float32Value is a flag.Value for the float32 parameters
+type float32Value float32
+
+func newFloat32Value(p *float32) *float32Value {
+	return (*float32Value)(p)
+}
+
+func (f *float32Value) Set(s string) error {
+	v, err := strconv.ParseFloat(s, 32)
+	if err != nil {
+		return err
+	}
+	*f = float32Value(v)
+	return nil
+}
+
+func (f *float32Value) String() string {
+	return strconv.FormatFloat(float64(*f), 'g', -1, 32)
+}
+
+//Check that the rate is in 0-1 range, clamp it with a warning or fail in strict mode
+func validateRate(name string, rate *float32) {
+	if *rate >= 0 && *rate <= 1 {
+		return
+	}
+	if strictRates {
+		logger.Fatalf("%v=%v is out of 0-1 range", name, *rate)
+	}
+	clampedRate := float32(0)
+	if *rate > 1 {
+		clampedRate = 1
+	}
+	logger.Warnf("%v=%v is out of 0-1 range, clamped to %v", name, *rate, clampedRate)
+	*rate = clampedRate
+}
+
+//Validate all GA rates
+func validateRates() {
+	validateRate("crossoverRate", &crossoverRate)
+	validateRate("mutationRate", &mutationRate)
+	validateRate("elitismRate", &elitismRate)
+	validateRate("immigrationRate", &immigrationRate)
+	validateRate("mutationTypePreference", &mutationTypePreference)
+	validateRate("homeDrivingDiscount", &homeDrivingDiscount)
+	validateRate("minMutationRate", &minMutationRate)
+	validateRate("maxMutationRate", &maxMutationRate)
+	validateRate("mutationRateStep", &mutationRateStep)
+	validateRate("diversityThreshold", &diversityThreshold)
+}
+
+func parseFlags() {
+	flag.StringVar(&configFileName, "config", configFileName, "JSON or YAML config file with the tuning parameters, command-line flags take precedence")
+	flag.IntVar(&populationSize, "population", populationSize, "size of the population")
+	flag.IntVar(&generationsLimit, "generations", generationsLimit, "how many generations to generate")
+	flag.Var(newFloat32Value(&crossoverRate), "crossover-rate", "how often to do crossover, 0-1 in decimal")
+	flag.StringVar(&crossoverMethod, "crossover", crossoverMethod, "crossover method: "+ox1Crossover+" or "+pmxCrossover)
+	flag.StringVar(&selectionMethod, "selection", selectionMethod, "parents selection method: "+tournamentSelection+" or "+rankSelection)
+	flag.Var(newFloat32Value(&mutationRate), "mutation-rate", "how often to do mutation, 0-1 in decimal")
+	flag.Var(newFloat32Value(&elitismRate), "elitism-rate", "how many of the best individuals to keep intact, 0-1 in decimal")
+	flag.Var(newFloat32Value(&deadend), "deadend", "fitness penalty for every unscheduled task")
+	flag.IntVar(&tourneySampleSize, "tourney-size", tourneySampleSize, "sample size for the tournament selection, should be less than population size")
+	flag.IntVar(&crossoverParentsNumber, "crossover-parents", crossoverParentsNumber, "number of parents for the crossover")
+	flag.IntVar(&maxCrossoverLength, "crossover-length", maxCrossoverLength, "max number of sequential tasks to cross between individuals")
+	flag.IntVar(&maxMutatedGenes, "mutated-genes", maxMutatedGenes, "maximum number of mutated genes, min=2")
+	flag.Var(newFloat32Value(&mutationTypePreference), "mutation-preference", "preferred mutation type rate, 0 = swap mutation, 1 = displacement mutation")
+	flag.IntVar(&monteCarloTrials, "montecarlo", monteCarloTrials, "number of Monte Carlo trials for the duration risk pass, 0 = disabled")
+	flag.BoolVar(&reportUtilization, "utilization", reportUtilization, "print workers utilization for the best schedule")
+	flag.Var(newFloat32Value(&immigrationRate), "immigration", "rate of fresh random individuals added every generation, 0-1 in decimal")
+	flag.StringVar(&reshuffleLogFileName, "reshuffle-log", reshuffleLogFileName, "CSV file to record the stagnation reshuffle events")
+	flag.Var(newFloat32Value(&weightProjectContinuity), "weight-continuity", "penalty for every worker switching projects between consecutive working days")
+	flag.Var(newFloat32Value(&weightProjectIdle), "weight-project-idle", "penalty for every idle working hour inside the no interruption projects")
+	flag.Var(newFloat32Value(&weightOvertime), "weight-overtime", "penalty for every overtime hour multiplied by the site overtime multiplier")
+	flag.Var(newFloat32Value(&weightPeakOvertime), "weight-peak-overtime", "penalty for every overtime hour of the worker with the most overtime hours")
+	flag.Var(newFloat32Value(&weightMakespan), "weight-makespan", "weight of every makespan hour in the individual fitness")
+	flag.Var(newFloat32Value(&weightDrivingHours), "weight-driving", "penalty for every driving hour of all workers in the individual fitness")
+	flag.Var(newFloat32Value(&weightDelayHours), "weight-delay", "penalty for every working hour workers wait on site for the tasks to start in the individual fitness")
+	flag.Var(newFloat32Value(&weightWorkerOvertime), "weight-worker-overtime", "weight of the worker accumulated overtime in the worker fitness")
+	flag.BoolVar(&allowOvertime, "overtime", allowOvertime, "allow tasks to finish in the site overtime window instead of continuing on the next working day")
+	flag.BoolVar(&reportByProject, "by-project", reportByProject, "print the best schedule grouped by project and sorted by start time")
+	flag.BoolVar(&reportDispatch, "dispatch", reportDispatch, "print per-day dispatch sheets with tasks of every worker for the best schedule")
+	flag.Var(newFloat32Value(&homeDrivingDiscount), "home-driving-discount", "discount of the first driving leg from the worker home, 0-1 in decimal")
+	flag.Var(newFloat32Value(&pinnedDateTimeSnap), "pinned-snap", "max working hours between the worker earliest start and the pinned task start, before or after it, 0 = the worker should start exactly at the pinned datetime")
+	flag.Var(newFloat32Value(&weightScarcity), "weight-scarcity", "weight of the worker dynamic scarcity in the worker fitness, 0 = disabled")
+	flag.Var(newFloat32Value(&weightTrades), "weight-trades", "weight of the worker trades specialization in the worker fitness for the tasks with required trades")
+	flag.BoolVar(&reportWeekly, "weekly", reportWeekly, "print ISO-week summary of projects and hours for every worker in the best schedule")
+	flag.BoolVar(&reportTimeline, "timeline", reportTimeline, "print per-day worker timelines with task, travel and idle blocks for the best schedule")
+	flag.IntVar(&reportFitness, "fitness-breakdown", reportFitness, "print fitness components of the N best individuals, 0 = disabled")
+	flag.BoolVar(&reportUnlimited, "unlimited-workers", reportUnlimited, "print makespan with unlimited workers to compare with the best schedule")
+	flag.BoolVar(&reportNeighbors, "neighbors", reportNeighbors, "print fitness statistics of all single-swap neighbors of the best schedule")
+	flag.IntVar(&reportBottlenecks, "bottlenecks", reportBottlenecks, "print N workers limiting the best schedule makespan, 0 = disabled")
+	flag.BoolVar(&reportDiversity, "diversity", reportDiversity, "print unique genotypes and average task order distance of the population every generation, slow for the large populations")
+	flag.BoolVar(&reportRelaxations, "relax", reportRelaxations, "print constraint relaxations ranked by the unscheduled tasks if the best schedule is infeasible")
+	flag.Int64Var(&randomSeed, "seed", randomSeed, "seed of the random numbers generator to reproduce the run, 0 = random seed")
+	flag.IntVar(&threadsNum, "threads", threadsNum, "number of go routines to generate schedules simultaneously")
+	flag.BoolVar(&adaptiveMutation, "adaptive-mutation", adaptiveMutation, "adjust the mutation rate every generation by the population diversity, every island adjusts its own rate")
+	flag.Var(newFloat32Value(&minMutationRate), "min-mutation-rate", "lower bound of the adaptive mutation rate, 0-1 in decimal")
+	flag.Var(newFloat32Value(&maxMutationRate), "max-mutation-rate", "upper bound of the adaptive mutation rate, 0-1 in decimal")
+	flag.Var(newFloat32Value(&mutationRateStep), "mutation-rate-step", "adaptive mutation rate change per generation, 0-1 in decimal")
+	flag.Var(newFloat32Value(&diversityThreshold), "diversity-threshold", "average task order distance from the best individual below which the adaptive mutation rate is raised, 0-1 in decimal")
+	flag.IntVar(&islandsNumber, "islands", islandsNumber, "number of populations evolved in parallel, the run with more than 1 island is not reproducible with the fixed seed")
+	flag.IntVar(&migrationInterval, "migration-interval", migrationInterval, "generations between the migrations of the best individuals to the next island")
+	flag.IntVar(&migrationSize, "migration-size", migrationSize, "number of the best individuals migrated from every island")
+	flag.IntVar(&stagnationLimit, "stagnation-limit", stagnationLimit, "stop after this number of generations without the best fitness improvement, 0 = run all generations")
+	flag.Var(newFloat32Value(&convergenceEpsilon), "epsilon", "min best fitness decrease counted as the improvement")
+	flag.BoolVar(&reshuffleStagnation, "reshuffle", reshuffleStagnation, "randomize GA parameters after 50 stagnant generations")
+	flag.BoolVar(&strictRates, "strict-rates", strictRates, "fail on the out of range GA rates instead of clamping them")
+	flag.StringVar(&jsonlFileName, "jsonl", jsonlFileName, "JSON Lines file to export the best schedule")
+	flag.StringVar(&jsonFileName, "json", jsonFileName, "JSON file to export the best schedule with the summary")
+	flag.StringVar(&csvFileName, "out", csvFileName, "CSV file to export the best schedule")
+	flag.StringVar(&icsFileName, "ics", icsFileName, "iCalendar file to export the best schedule")
+	flag.StringVar(&outputDateTimeFormat, "datetime-format", outputDateTimeFormat, "Go datetime layout of the pretty printed and exported schedule, empty = default layout of every output")
+	flag.StringVar(&taskInfoFileName, "export-tasks", taskInfoFileName, "task CSV file to export the best schedule with pinned start times and workers")
+	flag.StringVar(&traceFileName, "trace", traceFileName, "CSV file to record every assignment decision of the best schedule replay")
+	flag.IntVar(&crossoverLocality, "crossover-locality", crossoverLocality, "max distance a task can be moved by the crossover, 0 = unlimited")
+	flag.BoolVar(&useGMaps, "gmaps", useGMaps, "request driving times from the Google Distance Matrix API, API key is read from "+location.GMapsAPIKeyEnv)
+	flag.IntVar(&gmapsConcurrency, "gmaps-concurrency", gmapsConcurrency, "max number of concurrent Distance Matrix API requests, 0 = unlimited")
+	flag.Float64Var(&gmapsQPS, "gmaps-qps", gmapsQPS, "max number of Distance Matrix API requests per second, 0 = unlimited")
+	flag.Float64Var(&drivingSpeed, "driving-speed", drivingSpeed, "haversine average driving speed in km/h, 0 = 20 km/h")
+	flag.StringVar(&distanceMetric, "distance-metric", distanceMetric, "distance approximation of the driving times: "+string(location.HaversineDistance)+" or "+string(location.ManhattanDistance))
+	flag.Float64Var(&detourFactor, "detour-factor", detourFactor, "Manhattan distance multiplier to account for the street layout")
+	flag.StringVar(&nowDateTime, "now", nowDateTime, "current datetime to replan from in "+defaultDateTimeFormat+" format")
+	flag.StringVar(&baselineFileName, "baseline", baselineFileName, "JSON Lines baseline schedule to replan, requires -now")
+	flag.BoolVar(&synchronizeCrew, "sync-crew", synchronizeCrew, "start multi-worker tasks when the last assignee arrives")
+	flag.BoolVar(&addPinnedWorkers, "add-pinned-workers", addPinnedWorkers, "add pinned workers missing from the task valid workers instead of failing")
+	flag.StringVar(&assignmentStrategy, "assignment", assignmentStrategy, "worker assignment strategy: "+bestFitStrategy+" or "+firstAvailableStrategy)
+	flag.Parse()
+	if configFileName != "" {
+		applyConfigFile(configFileName)
+	}
+
+	//Best three individuals are tracked to detect the stagnation
+	if populationSize < 3 {
+		logger.Fatal("Population size should be at least 3, got ", populationSize)
+	}
+	if generationsLimit < 1 {
+		logger.Fatal("Number of generations should be positive, got ", generationsLimit)
+	}
+	if tourneySampleSize < 1 || tourneySampleSize >= populationSize {
+		logger.Fatalf("Tournament sample size should be between 1 and population size-1 (%v), got %v", populationSize-1, tourneySampleSize)
+	}
+	if crossoverParentsNumber < 2 || crossoverParentsNumber > populationSize {
+		logger.Fatalf("Number of crossover parents should be between 2 and population size (%v), got %v", populationSize, crossoverParentsNumber)
 	}
+	if maxCrossoverLength < 1 {
+		logger.Fatal("Max crossover length should be positive, got ", maxCrossoverLength)
+	}
+	if maxMutatedGenes < 2 {
+		logger.Fatal("Max number of mutated genes should be at least 2, got ", maxMutatedGenes)
+	}
+	if deadend <= 0 {
+		logger.Fatal("Deadend penalty should be positive, got ", deadend)
+	}
+	validateRates()
+	if pinnedDateTimeSnap < 0 {
+		logger.Fatal("Pinned datetime snap should not be negative, got ", pinnedDateTimeSnap)
+	}
+	if crossoverLocality < 0 {
+		logger.Fatal("Crossover locality should not be negative, got ", crossoverLocality)
+	}
+	if threadsNum < 1 {
+		logger.Fatal("Number of threads should be positive, got ", threadsNum)
+	}
+	if islandsNumber < 1 || migrationInterval < 1 || migrationSize < 0 {
+		logger.Fatalf("Number of islands and migration interval should be positive and migration size should not be negative, got %v, %v and %v", islandsNumber, migrationInterval, migrationSize)
+	}
+	if migrationSize > populationSize {
+		logger.Fatalf("Migration size should not be more than population size (%v), got %v", populationSize, migrationSize)
+	}
+	//GA parameters are shared between the islands, so they can't be reshuffled by every island independently
+	if islandsNumber > 1 && reshuffleStagnation {
+		logger.Warn("Stagnation reshuffle is disabled for the island model")
+		reshuffleStagnation = false
+	}
+	if minMutationRate > maxMutationRate {
+		logger.Fatalf("Min mutation rate should not be more than max mutation rate, got %v and %v", minMutationRate, maxMutationRate)
+	}
+	if stagnationLimit < 0 || convergenceEpsilon < 0 {
+		logger.Fatalf("Stagnation limit and epsilon should not be negative, got %v and %v", stagnationLimit, convergenceEpsilon)
+	}
+	if baselineFileName != "" && nowDateTime == "" {
+		logger.Fatal("Baseline schedule requires the current datetime")
+	}
+	if crossoverMethod != ox1Crossover && crossoverMethod != pmxCrossover {
+		logger.Fatal("Unknown crossover method: ", crossoverMethod)
+	}
+	if selectionMethod != tournamentSelection && selectionMethod != rankSelection {
+		logger.Fatal("Unknown selection method: ", selectionMethod)
+	}
+	if distanceMetric != string(location.HaversineDistance) && distanceMetric != string(location.ManhattanDistance) {
+		logger.Fatal("Unknown distance metric: ", distanceMetric)
+	}
+	if assignmentStrategy != bestFitStrategy && assignmentStrategy != firstAvailableStrategy {
+		logger.Fatal("Unknown assignment strategy: ", assignmentStrategy)
+	}
+	if outputDateTimeFormat != "" {
+		validateDateTimeFormat(outputDateTimeFormat)
+	}
+}
+
+//Evolution state of the population, kept between the runGA calls of the same population
+type gaState struct {
+	generationsNumber          int     //number of completed generations
+	stagnantGenerationsNumber  int     //number of generations without changes of the 3 best fitness values
+	stagnantGenerationsFitness float32 //sum of the 3 best fitness values
+	convergedGenerationsNumber int     //number of generations without the best fitness improvement by more than epsilon
+	bestFitness                float32 //best fitness of the last improvement
+	converged                  bool    //the run was stopped by the stagnation limit
+	mutationRate               float32 //mutation rate of the population, adjusted by the adaptive mutation
+}
+
+//Evolve the population for maxGenerations more generations or until the best fitness converges, starting from the state of the previous run.
+//Returns the population sorted by fitness and the new state
+func runGA(pop population, state gaState, maxGenerations int, stagnationLimit int, epsilon float32, reshuffleLogWriter *csv.Writer) (population, gaState) {
+	state.converged = false
+	//Every population starts with the configured mutation rate
+	if state.generationsNumber == 0 {
+		state.mutationRate = mutationRate
+	}
+	for i := 0; i < maxGenerations; i++ {
+		logger.Info("Generation", state.generationsNumber)
+		//Mutate and crossover population
+		logger.Info("Mutating population...")
+		pop = transmogrifyPopulation(pop, state.mutationRate)
+		//population = transmogrifyPopulation(population)
+		//Generate schedule and calculate fitness
+		logger.Info("Generating schedules...")
+		generatePopulationSchedules(pop.individuals)
+		logger.Info("Sorting individuals...")
+		//Sort population in the fitness order
+		sortPopulation(pop.individuals)
+		logger.Info("Best fitness =", pop.individuals[0].fitness)
+		logger.Info("Second best fitness =", pop.individuals[1].fitness)
+		logger.Info("Third best fitness =", pop.individuals[2].fitness)
+		diversity := calcPopulationDiversity(pop.individuals)
+		if adaptiveMutation {
+			state.mutationRate = adaptMutationRate(state.mutationRate, diversity)
+		}
+		logger.Infof("Population diversity = %v, mutation rate = %v", diversity, state.mutationRate)
+		//Pairwise order distance is quadratic in the population size, so it's calculated on demand only
+		if reportDiversity {
+			stats := diversityStats(pop.individuals)
+			logger.Infof("Unique genotypes = %v, average order distance = %v", stats.uniqueGenotypes, stats.averageOrderDistance)
+		}
+
+		logger.Info("Stagnant generations number =", state.stagnantGenerationsNumber)
+		//Update number of stagnant generations
+		if pop.individuals[0].fitness+pop.individuals[1].fitness+pop.individuals[2].fitness != state.stagnantGenerationsFitness {
+			state.stagnantGenerationsFitness = pop.individuals[0].fitness + pop.individuals[1].fitness + pop.individuals[2].fitness
+			state.stagnantGenerationsNumber = 0
+		} else {
+			state.stagnantGenerationsNumber++
+		}
+		//Add randomness to break the stagnation
+		if reshuffleStagnation && state.stagnantGenerationsNumber > 50 {
+			oldParameters := currentReshuffleParameters()
+			tourneySampleSize = rand.Intn(91) + 10
+			crossoverParentsNumber = rand.Intn(3) + 2
+			maxCrossoverLength = rand.Intn(91) + 10
+			maxMutatedGenes = rand.Intn(91) + 10
+			mutationTypePreference = rand.Float32()
+			validateRates()
+			state.stagnantGenerationsNumber = 0
+			if reshuffleLogWriter != nil {
+				writeReshuffleEvent(reshuffleLogWriter, state.generationsNumber, pop.individuals[0].fitness, oldParameters, currentReshuffleParameters())
+			}
+			logger.Info("================================================")
+			logger.Info("Current GA settings:")
+			logger.Info("populationSize=", populationSize)
+			logger.Info("generationsLimit=", generationsLimit)
+			logger.Info("crossoverRate=", crossoverRate)
+			logger.Info("mutationRate=", mutationRate)
+			logger.Info("elitismRate=", elitismRate)
+			logger.Info("immigrationRate=", immigrationRate)
+			logger.Info("deadend=", deadend)
+			logger.Info("tourneySampleSize=", tourneySampleSize)
+			logger.Info("crossoverParentsNumber=", crossoverParentsNumber)
+			logger.Info("maxCrossoverLength=", maxCrossoverLength)
+			logger.Info("maxMutatedGenes=", maxMutatedGenes)
+			logger.Info("mutationTypePreference=", mutationTypePreference)
+			logger.Info("================================================")
+		}
 
-	logger.Infof(";%v;%v;%v;%v;%v;%v;%v;%v;%v;%v;%v", startDateTime.Format(("2006/01/02 15:04")), stopDateTime.Format(("2006/01/02 15:04")), projectName, name, workersNames, workersIDs, id, projectID, predecessorsIDs, pinnedWorkersNames, pinnedDateTime)
+		//Stop when the best fitness is not improved by more than epsilon over stagnationLimit generations
+		if state.generationsNumber == 0 || state.bestFitness-pop.individuals[0].fitness > epsilon {
+			state.bestFitness = pop.individuals[0].fitness
+			state.convergedGenerationsNumber = 0
+		} else {
+			state.convergedGenerationsNumber++
+		}
+		state.generationsNumber++
+		if stagnationLimit > 0 && state.convergedGenerationsNumber >= stagnationLimit {
+			logger.Infof("Best fitness is not improved by more than %v in %v generations, stopping", epsilon, stagnationLimit)
+			state.converged = true
+			return pop, state
+		}
+	}
+	return pop, state
 }
 
 func main() {
+	parseFlags()
 
 	logger.Info("================================================")
 	logger.Info("Current GA settings:")
+	logger.Info("configFileName=", configFileName)
 	logger.Info("populationSize=", populationSize)
 	logger.Info("generationsLimit=", generationsLimit)
 	logger.Info("crossoverRate=", crossoverRate)
 	logger.Info("mutationRate=", mutationRate)
 	logger.Info("elitismRate=", elitismRate)
+	logger.Info("immigrationRate=", immigrationRate)
 	logger.Info("deadend=", deadend)
+	logger.Info("selectionMethod=", selectionMethod)
 	logger.Info("tourneySampleSize=", tourneySampleSize)
 	logger.Info("crossoverParentsNumber=", crossoverParentsNumber)
+	logger.Info("crossoverMethod=", crossoverMethod)
 	logger.Info("maxCrossoverLength=", maxCrossoverLength)
+	logger.Info("crossoverLocality=", crossoverLocality)
 	logger.Info("maxMutatedGenes=", maxMutatedGenes)
 	logger.Info("mutationTypePreference=", mutationTypePreference)
+	logger.Info("stagnationLimit=", stagnationLimit)
+	logger.Info("threadsNum=", threadsNum)
+	logger.Info("convergenceEpsilon=", convergenceEpsilon)
+	logger.Info("reshuffleStagnation=", reshuffleStagnation)
+	logger.Info("adaptiveMutation=", adaptiveMutation)
+	logger.Info("minMutationRate=", minMutationRate)
+	logger.Info("maxMutationRate=", maxMutationRate)
+	logger.Info("mutationRateStep=", mutationRateStep)
+	logger.Info("diversityThreshold=", diversityThreshold)
+	logger.Info("islandsNumber=", islandsNumber)
+	logger.Info("migrationInterval=", migrationInterval)
+	logger.Info("migrationSize=", migrationSize)
 	logger.Info("================================================")
 	logger.Info("Current workers AHP settings:")
 	logger.Info("weightDistance=", weightDistance)
@@ -1278,87 +2826,240 @@ func main() {
 	logger.Info("maxValueDelay=", maxValueDelay)
 	logger.Info("maxValueDemand=", maxValueDemand)
 	logger.Info("pinnedDateTimeSnap=", pinnedDateTimeSnap)
+	logger.Info("homeDrivingDiscount=", homeDrivingDiscount)
+	logger.Info("weightScarcity=", weightScarcity)
+	logger.Info("weightTrades=", weightTrades)
+	logger.Info("weightWorkerOvertime=", weightWorkerOvertime)
+	logger.Info("assignmentStrategy=", assignmentStrategy)
+	logger.Info("synchronizeCrew=", synchronizeCrew)
+	logger.Info("addPinnedWorkers=", addPinnedWorkers)
+	logger.Info("================================================")
+	logger.Info("Current individual fitness settings:")
+	logger.Info("weightProjectContinuity=", weightProjectContinuity)
+	logger.Info("weightProjectIdle=", weightProjectIdle)
+	logger.Info("weightOvertime=", weightOvertime)
+	logger.Info("weightPeakOvertime=", weightPeakOvertime)
+	logger.Info("weightMakespan=", weightMakespan)
+	logger.Info("weightDrivingHours=", weightDrivingHours)
+	logger.Info("weightDelayHours=", weightDelayHours)
+	logger.Info("allowOvertime=", allowOvertime)
+	logger.Info("================================================")
+	logger.Info("Current risk analysis settings:")
+	logger.Info("monteCarloTrials=", monteCarloTrials)
 	logger.Info("================================================")
 
 	var population population
-	rand.Seed(time.Now().UnixNano())
+	//Random seed is logged, so the run can be reproduced. Schedules are generated in parallel without random numbers,
+	//so the global source is enough for the reproducible runs
+	if randomSeed == 0 {
+		randomSeed = time.Now().UnixNano()
+	}
+	rand.Seed(randomSeed)
+	logger.Info("randomSeed=", randomSeed)
 
 	currentTime := time.Now()
 	scheduleStartTime = time.Date(2020, 12, 18, 0, 0, 0, 0, currentTime.Location())
+	if nowDateTime != "" {
+		var err error
+		scheduleStartTime, err = time.ParseInLocation(defaultDateTimeFormat, nowDateTime, currentTime.Location())
+		if err != nil {
+			logger.Fatal("Couldn't parse the current datetime value", err)
+		}
+	}
 
 	//projectsDB = make(map[string]project)
 	//projectsDB, projectFamiliarityDB, tasksDB, workersDB, workersTimeOffDB = readCSVs()
 
 	//Global DB vars can be accessed directly, but to follow the standard approach used as a func output
-	projectsDB = readProjectInfoCSV()
-	tasksDB = readTaskInfoCSV()
-	workersDB = readWorkerInfoCSV()
-	projectFamiliarityDB = readWorkerProjectHoursCSV()
-	workersDB = readWorkerTimeOffCSV(workersDB)
+	var err error
+	projectsDB, err = readProjectInfoCSV()
+	if err != nil {
+		logger.Fatal(err)
+	}
+	//Holidays are optional, only weekends are skipped by default
+	if _, err := os.Stat(siteHolidaysDBFileName); err == nil {
+		projectsDB, err = readSiteHolidaysCSV(projectsDB)
+		if err != nil {
+			logger.Fatal(err)
+		}
+	}
+	tasksDB, err = readTaskInfoCSV()
+	if err != nil {
+		logger.Fatal(err)
+	}
+	workersDB, err = readWorkerInfoCSV()
+	if err != nil {
+		logger.Fatal(err)
+	}
+	projectFamiliarityDB, err = readWorkerProjectHoursCSV()
+	if err != nil {
+		logger.Fatal(err)
+	}
+	workersDB, err = readWorkerTimeOffCSV(workersDB)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if monteCarloTrials > 0 {
+		taskDurationRiskDB, err = readTaskDurationRiskCSV()
+		if err != nil {
+			logger.Fatal(err)
+		}
+	}
+
+	//Equipment is optional, schedule with workers only if equipment isn't defined
+	equipmentDB = make(map[string]equipment)
+	if _, err := os.Stat(equipmentDBFileName); err == nil {
+		equipmentDB, err = readEquipmentInfoCSV()
+		if err != nil {
+			logger.Fatal(err)
+		}
+		tasksDB, err = readTaskEquipmentCSV(tasksDB)
+		if err != nil {
+			logger.Fatal(err)
+		}
+	}
+
+	//Driving times are optional, haversine is used by default
+	drivingTimeProvider = location.HaversineProvider{Speed: float32(drivingSpeed), Metric: location.DistanceMetric(distanceMetric), DetourFactor: float32(detourFactor)}
+	location.DefaultProvider = drivingTimeProvider
+	if useGMaps {
+		drivingTimeProvider = newGMapsProvider()
+	}
+	if _, err := os.Stat(drivingTimeDBFileName); err == nil {
+		drivingTimeMatrix, err := readDrivingTimeCSV()
+		if err != nil {
+			logger.Fatal(err)
+		}
+		drivingTimeMatrix.Fallback = drivingTimeProvider
+		drivingTimeProvider = drivingTimeMatrix
+	}
+
+	//Task calendars are optional, tasks follow the project site hours by default
+	if _, err := os.Stat(taskCalendarDBFileName); err == nil {
+		tasksDB, err = readTaskCalendarCSV(tasksDB)
+		if err != nil {
+			logger.Fatal(err)
+		}
+	}
+
+	//Recurring tasks are replaced by their instances after all task data is loaded
+	tasksDB = expandRecurringTasks(tasksDB)
+
+	//Replan the baseline schedule from now
+	if baselineFileName != "" {
+		applyRollingHorizon(readBaselineJSONL(baselineFileName), scheduleStartTime)
+	}
 
 	verifyTaskDB()
+	verifyTaskEquipment()
+	verifyOptionalTasks()
 
-	workersDB = calculateWorkersDemand() //not neeeded if trades would be implemented
+	workersDB = calculateWorkersDemand()
 	//projectsDB = readProjectInfoCSV()
 	//fmt.Println(projectsDB)
 	//fmt.Println(tasksDB)
 	//fmt.Println(workersDB)
 	//fmt.Println(projectFamiliarityDB)
-	population = generatePopulation()
-
-	var stagnantGenerationsNumber int
-	var stagnantGenerationsFitness float32
-	for i := 0; i < generationsLimit; i++ {
-		logger.Info("Generation", i)
-		//Mutate and crossover population
-		logger.Info("Mutating population...")
-		population = transmogrifyPopulation(population)
-		//population = transmogrifyPopulation(population)
-		//Generate schedule and calculate fitness
-		logger.Info("Generating schedules...")
-		generatePopulationSchedules(population.individuals)
-		logger.Info("Sorting individuals...")
-		//Sort population in the fitness order
-		sortPopulation(population.individuals)
-		logger.Info("Best fitness =", population.individuals[0].fitness)
-		logger.Info("Second best fitness =", population.individuals[1].fitness)
-		logger.Info("Third best fitness =", population.individuals[2].fitness)
+	var reshuffleLogWriter *csv.Writer
+	if reshuffleLogFileName != "" {
+		var reshuffleLogFile *os.File
+		reshuffleLogFile, reshuffleLogWriter = createReshuffleLogCSV(reshuffleLogFileName)
+		defer reshuffleLogFile.Close()
+	}
 
-		logger.Info("Stagnant generations number =", stagnantGenerationsNumber)
-		//Update number of stagnant generations
-		if population.individuals[0].fitness+population.individuals[1].fitness+population.individuals[2].fitness != stagnantGenerationsFitness {
-			stagnantGenerationsFitness = population.individuals[0].fitness + population.individuals[1].fitness + population.individuals[2].fitness
-			stagnantGenerationsNumber = 0
-		} else {
-			stagnantGenerationsNumber++
-		}
-		//Add randomness to break the stagnation
-		if stagnantGenerationsNumber > 50 {
-			tourneySampleSize = rand.Intn(91) + 10
-			crossoverParentsNumber = rand.Intn(3) + 2
-			maxCrossoverLength = rand.Intn(91) + 10
-			maxMutatedGenes = rand.Intn(91) + 10
-			mutationTypePreference = rand.Float32()
-			stagnantGenerationsNumber = 0
-			logger.Info("================================================")
-			logger.Info("Current GA settings:")
-			logger.Info("populationSize=", populationSize)
-			logger.Info("generationsLimit=", generationsLimit)
-			logger.Info("crossoverRate=", crossoverRate)
-			logger.Info("mutationRate=", mutationRate)
-			logger.Info("elitismRate=", elitismRate)
-			logger.Info("deadend=", deadend)
-			logger.Info("tourneySampleSize=", tourneySampleSize)
-			logger.Info("crossoverParentsNumber=", crossoverParentsNumber)
-			logger.Info("maxCrossoverLength=", maxCrossoverLength)
-			logger.Info("maxMutatedGenes=", maxMutatedGenes)
-			logger.Info("mutationTypePreference=", mutationTypePreference)
-			logger.Info("================================================")
+	var generationsNumber int
+	if islandsNumber > 1 {
+		population, generationsNumber = runIslandsGA(generationsLimit, stagnationLimit, convergenceEpsilon)
+	} else {
+		var state gaState
+		population, state = runGA(generatePopulation(), state, generationsLimit, stagnationLimit, convergenceEpsilon, reshuffleLogWriter)
+		generationsNumber = state.generationsNumber
+	}
+	logger.Info("Generations completed =", generationsNumber)
+	logger.Info("Best schedule total driving hours =", population.individuals[0].fitnessData.drivingHours)
+	logger.Info("Best schedule")
+	if reportByProject {
+		prettyPrintScheduleByProject(population.individuals[0])
+	} else {
+		for _, task := range population.individuals[0].tasks {
+			prettyPrintTask(task)
 		}
+	}
 
+	if reportDispatch {
+		logger.Info("Dispatch sheets")
+		prettyPrintDispatchSheets(population.individuals[0])
 	}
-	logger.Info("Best schedule")
-	for _, task := range population.individuals[0].tasks {
-		prettyPrintTask(task)
+
+	if reportWeekly {
+		logger.Info("Weekly deployment summary")
+		prettyPrintWeekSummaries(population.individuals[0])
+	}
+
+	if reportTimeline {
+		logger.Info("Worker timelines")
+		prettyPrintWorkerTimelines(population.individuals[0])
+	}
+
+	if reportFitness > 0 {
+		logger.Info("Fitness breakdown")
+		prettyPrintFitnessBreakdown(population.individuals, reportFitness)
+	}
+
+	if jsonlFileName != "" {
+		writeScheduleJSONL(jsonlFileName, population.individuals[0])
+	}
+
+	if jsonFileName != "" {
+		writeScheduleJSON(jsonFileName, population.individuals[0])
+	}
+
+	if csvFileName != "" {
+		writeScheduleCSV(csvFileName, population.individuals[0])
+	}
+
+	if icsFileName != "" {
+		exportICS(icsFileName, population.individuals[0])
+	}
+
+	if taskInfoFileName != "" {
+		writeTaskInfoCSV(taskInfoFileName, population.individuals[0])
+	}
+
+	if traceFileName != "" {
+		writeScheduleTrace(traceFileName, population.individuals[0])
+	}
+
+	if reportNeighbors {
+		logger.Info("Best schedule fitness landscape")
+		prettyPrintNeighborsFitness(population.individuals[0])
+	}
+
+	if reportUnlimited {
+		logger.Info("Best schedule makespan (hours) =", calcMakespan(population.individuals[0]))
+		logger.Info("Unlimited workers makespan (hours) =", calcUnlimitedWorkersMakespan())
+	}
+
+	if reportBottlenecks > 0 {
+		logger.Info("Bottleneck workers")
+		prettyPrintBottleneckWorkers(population.individuals[0], reportBottlenecks)
+	}
+
+	if reportRelaxations {
+		logger.Info("Constraint relaxations")
+		prettyPrintConstraintRelaxations(population.individuals[0])
+	}
+
+	if reportUtilization {
+		logger.Info("Workers utilization")
+		prettyPrintWorkersUtilization(population.individuals[0])
+	}
+
+	if monteCarloTrials > 0 {
+		logger.Infof("Running %v Monte Carlo trials...", monteCarloTrials)
+		makespans := runMonteCarlo(population.individuals[0], monteCarloTrials)
+		logger.Info("Makespan P50 (hours) =", percentile(makespans, 50))
+		logger.Info("Makespan P80 (hours) =", percentile(makespans, 80))
+		logger.Info("Makespan P95 (hours) =", percentile(makespans, 95))
 	}
 }
@@ -0,0 +1,2494 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"hash/fnv"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/alex.skylight/sambo/calendar"
+	"gitlab.com/alex.skylight/sambo/go-log"
+	"gitlab.com/alex.skylight/sambo/location"
+)
+
+//Test site working from 8:00 to 16:00 Monday to Friday without lunch
+func newTestSite() calendar.Site {
+	return calendar.Site{DailyStartTime: time.Date(0, 1, 1, 8, 0, 0, 0, time.UTC), DailyEndTime: time.Date(0, 1, 1, 16, 0, 0, 0, time.UTC)}
+}
+
+//Test datetime in December 2020, 21st is Monday
+func testDateTime(day, hour int) time.Time {
+	return time.Date(2020, 12, day, hour, 0, 0, 0, time.UTC)
+}
+
+//Write the input files into a temporary working directory, the returned function restores the original one
+func chdirTestFiles(t *testing.T, files map[string]string) func() {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	workingDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	return func() { os.Chdir(workingDir) }
+}
+
+//Test individual with the tasks in the given order
+func newTestIndividual(fitness float32, taskIDs ...string) individual {
+	newIndividual := individual{fitness: fitness}
+	for _, v := range taskIDs {
+		newIndividual.tasks = append(newIndividual.tasks, scheduledTask{taskID: v})
+	}
+	return newIndividual
+}
+
+//Route provider with the same driving time for all routes
+type testRouteProvider float32
+
+func (provider testRouteProvider) DrivingTime(originLatitude, originLongitude, destinationLatitude, destinationLongitude float64) (float32, error) {
+	return float32(provider), nil
+}
+
+//Set the test DBs with the project P1 on the test site and the schedule starting on Monday at 8:00.
+//Zero driving time is valued as maxValueDriving, so the driving time is too short to change the rounded start time instead
+func setTestDB(tasks map[string]task, workers map[string]worker) {
+	tasksDB = tasks
+	workersDB = workers
+	projectsDB = map[string]project{"P1": {site: newTestSite()}}
+	projectFamiliarityDB = nil
+	frozenTasks = nil
+	drivingTimeProvider = testRouteProvider(0.001)
+	scheduleStartTime = testDateTime(21, 8)
+}
+
+//Test task of the project P1 with the same ideal, min and max worker count
+func newTestTask(duration float32, workerCount int, validWorkers ...string) task {
+	newTask := task{project: "P1", duration: duration, idealWorkerCount: workerCount, minWorkerCount: workerCount, maxWorkerCount: workerCount, allocation: 1, validWorkers: make(map[string]struct{})}
+	for _, v := range validWorkers {
+		newTask.validWorkers[v] = struct{}{}
+	}
+	return newTask
+}
+
+//Schedule the tasks in the given order with all workers of the test DB
+func scheduleTestTasks(taskIDs ...string) individual {
+	newIndividual := newTestIndividual(0, taskIDs...)
+	var workerIDs []string
+	for workerID := range workersDB {
+		workerIDs = append(workerIDs, workerID)
+	}
+	sort.Strings(workerIDs)
+	for _, workerID := range workerIDs {
+		newIndividual.workers = append(newIndividual.workers, scheduledWorker{workerID: workerID})
+	}
+	return scheduleIndividual(newIndividual, nil)
+}
+
+//Test DB with the independent tasks P1.T1-P1.T<tasksNumber> of 1 hour, any of 2 workers can do every task
+func setTestDBIndependentTasks(tasksNumber int) {
+	tasks := make(map[string]task)
+	for i := 1; i <= tasksNumber; i++ {
+		tasks["P1.T"+strconv.Itoa(i)] = newTestTask(1, 1, "W1", "W2")
+	}
+	setTestDB(tasks, map[string]worker{"W1": {}, "W2": {}})
+}
+
+//Test individual with the tasks T0-T<tasksNumber-1> in a random order
+func newRandomTestIndividual(tasksNumber int) individual {
+	var taskIDs []string
+	for _, v := range rand.Perm(tasksNumber) {
+		taskIDs = append(taskIDs, "T"+strconv.Itoa(v))
+	}
+	return newTestIndividual(0, taskIDs...)
+}
+
+//Task positions in the individual, key is the task ID
+func taskPositions(individual individual) map[string]int {
+	positions := make(map[string]int)
+	for i, task := range individual.tasks {
+		positions[task.taskID] = i
+	}
+	return positions
+}
+
+//Find the scheduled task by ID
+func findTestTask(individual individual, taskID string) scheduledTask {
+	for _, task := range individual.tasks {
+		if task.taskID == taskID {
+			return task
+		}
+	}
+	return scheduledTask{}
+}
+
+//Compare float values with the calendar rounding precision
+func almostEqual(a, b float32) bool {
+	return a-b < 0.001 && b-a < 0.001
+}
+
+func TestPeakOvertimeFitness(t *testing.T) {
+	tasksDB = map[string]task{}
+	defer func(weight float32) { weightPeakOvertime = weight }(weightPeakOvertime)
+	//Same total overtime and makespan, piled on one worker or spread between two
+	piled := individual{workers: []scheduledWorker{{workerID: "W1", overtimeHours: 4}, {workerID: "W2"}}}
+	spread := individual{workers: []scheduledWorker{{workerID: "W1", overtimeHours: 2}, {workerID: "W2", overtimeHours: 2}}}
+
+	weightPeakOvertime = 0
+	if piledFitness, spreadFitness := calculateIndividualFitness(piled).fitness, calculateIndividualFitness(spread).fitness; piledFitness != spreadFitness {
+		t.Errorf("Disabled peak overtime changes fitness: piled %v, spread %v", piledFitness, spreadFitness)
+	}
+	weightPeakOvertime = 1
+	piled, spread = calculateIndividualFitness(piled), calculateIndividualFitness(spread)
+	if piled.fitness <= spread.fitness {
+		t.Errorf("Piled overtime fitness %v should be worse than spread overtime fitness %v", piled.fitness, spread.fitness)
+	}
+	if piled.fitnessData.peakOvertime != 4 || spread.fitnessData.peakOvertime != 2 {
+		t.Errorf("Peak overtime penalty = %v and %v, expected 4 and 2", piled.fitnessData.peakOvertime, spread.fitnessData.peakOvertime)
+	}
+}
+
+func TestPeakOvertimeSchedule(t *testing.T) {
+	defer currentTuningConfig().apply()
+	weightWorkerOvertime = 0
+	//P1.T1 and P1.T2 finish in 1 hour of overtime, P1.T4 of W3 only sets the same makespan for all task orders
+	setTestDB(map[string]task{
+		"P1.T1": newTestTask(9, 1, "W1", "W2"),
+		"P1.T2": newTestTask(9, 1, "W1"),
+		"P1.T3": newTestTask(8, 1, "W2"),
+		"P1.T4": newTestTask(24, 1, "W3"),
+	}, map[string]worker{"W1": {}, "W2": {}, "W3": {}})
+	site := newTestSite()
+	site.MaxOvertimeHours = 1
+	projectsDB["P1"] = project{site: site}
+	peakOvertimeHours := func(individual individual) float32 {
+		var peak float32
+		for _, worker := range individual.workers {
+			if worker.overtimeHours > peak {
+				peak = worker.overtimeHours
+			}
+		}
+		return peak
+	}
+	//Overtime tasks are done by different workers on Monday or by the same worker on Monday and Tuesday
+	schedule := func() (individual, individual) {
+		return scheduleTestTasks("P1.T2", "P1.T1", "P1.T3", "P1.T4"), scheduleTestTasks("P1.T1", "P1.T2", "P1.T3", "P1.T4")
+	}
+	weightPeakOvertime = 0
+	spread, piled := schedule()
+	if peakOvertimeHours(spread) != 1 || peakOvertimeHours(piled) != 2 {
+		t.Fatalf("Peak overtime = %v and %v hours, expected 1 and 2", peakOvertimeHours(spread), peakOvertimeHours(piled))
+	}
+	if !spread.fitnessData.finishDateTime.Equal(piled.fitnessData.finishDateTime) || spread.fitness != piled.fitness {
+		t.Errorf("Disabled peak overtime schedules finish at %v and %v with fitness %v and %v, expected equal", spread.fitnessData.finishDateTime, piled.fitnessData.finishDateTime, spread.fitness, piled.fitness)
+	}
+	weightPeakOvertime = 1
+	if spread, piled = schedule(); spread.fitness >= piled.fitness {
+		t.Errorf("Spread overtime fitness %v should be better than piled overtime fitness %v", spread.fitness, piled.fitness)
+	}
+	//GA picks the schedule with the lower peak overtime at the same makespan
+	rand.Seed(1)
+	populationSize = 8
+	population, _ := runGA(generatePopulation(), gaState{}, 10, 0, 0, nil)
+	if best := population.individuals[0]; peakOvertimeHours(best) != 1 || !best.fitnessData.finishDateTime.Equal(testDateTime(23, 16)) {
+		t.Errorf("Best schedule peak overtime = %v hours, finish = %v, expected 1 hour and %v", peakOvertimeHours(best), best.fitnessData.finishDateTime, testDateTime(23, 16))
+	}
+}
+
+func TestWorkersUtilization(t *testing.T) {
+	scheduleStartTime = testDateTime(21, 0)
+	projectsDB = map[string]project{"P1": {site: newTestSite()}}
+	workersDB = map[string]worker{
+		"W1": {},
+		"W2": {blockedRanges: []dateTimeRange{{startTime: testDateTime(22, 0), endTime: testDateTime(23, 0)}}},
+	}
+	tasksDB = map[string]task{
+		"P1.T1": {project: "P1", duration: 12, minWorkerCount: 1, allocation: 1},
+		"P1.T2": {project: "P1", duration: 8, minWorkerCount: 1, allocation: 0.5},
+		"P1.T3": {project: "P1", duration: 8, minWorkerCount: 2, allocation: 1},
+	}
+	individual := individual{tasks: []scheduledTask{
+		{taskID: "P1.T1", startTime: testDateTime(21, 8), stopTime: testDateTime(22, 12), assignees: []string{"W1"}},
+		{taskID: "P1.T2", startTime: testDateTime(23, 8), stopTime: testDateTime(23, 16), assignees: []string{"W2"}},
+		//Unscheduled task is not counted
+		{taskID: "P1.T3", assignees: []string{"W1"}},
+	}}
+
+	//3 working days from Monday to Wednesday, W2 has Tuesday off
+	utilizations := calculateWorkersUtilization(individual, testDateTime(23, 16))
+	expected := []workerUtilization{
+		{workerID: "W1", assignedHours: 12, availableHours: 24, utilization: 50},
+		{workerID: "W2", assignedHours: 4, availableHours: 16, utilization: 25},
+	}
+	if len(utilizations) != len(expected) {
+		t.Fatalf("Utilizations = %+v, expected %+v", utilizations, expected)
+	}
+	for i := range expected {
+		if utilizations[i].workerID != expected[i].workerID || utilizations[i].assignedHours != expected[i].assignedHours || !almostEqual(utilizations[i].availableHours, expected[i].availableHours) || !almostEqual(utilizations[i].utilization, expected[i].utilization) {
+			t.Errorf("Utilization = %+v, expected %+v", utilizations[i], expected[i])
+		}
+	}
+}
+
+func TestReadWorkerInfoCSVReportsAllBadRows(t *testing.T) {
+	defer chdirTestFiles(t, map[string]string{workersDBFileName: "name,id,latitude,longitude\n" +
+		"Ann,W1,49.25,-123.10\n" +
+		"Bob,W2,north,-123.05\n" +
+		"Cid,W3,49.30,-123.00\n" +
+		"Dan,W4,49.35,west\n"})()
+	workers, err := readWorkerInfoCSV()
+	if err == nil {
+		t.Fatal("Bad rows are not reported")
+	}
+	for _, expected := range []string{workersDBFileName + ":3:", workersDBFileName + ":5:"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("Error %q doesn't report %v", err, expected)
+		}
+	}
+	if strings.Count(err.Error(), "\n") != 1 {
+		t.Errorf("Error %q should report exactly two rows", err)
+	}
+	if _, ok := workers["W3"]; !ok || len(workers) != 2 {
+		t.Errorf("Valid rows are not read after the bad rows: %v", workers)
+	}
+}
+
+func TestReadTaskInfoCSVTruncatedRow(t *testing.T) {
+	defer chdirTestFiles(t, map[string]string{tasksDBFileName: "project,id,name,valid_workers,prerequisites,ideal_worker_count,min_worker_count,max_worker_count,duration,lag_hours,pinned_datetime,pinned_workers\n" +
+		"P1,T1,Dig,W1 W2,,2,1,3,12,,,\n" +
+		"P1,T2,Pour,W1\n"})()
+	_, err := readTaskInfoCSV()
+	if err == nil {
+		t.Fatal("Truncated row is not reported")
+	}
+	if !strings.Contains(err.Error(), tasksDBFileName+":3:") || !strings.Contains(err.Error(), "wrong number of fields") {
+		t.Errorf("Error %q doesn't report the truncated row line and column count", err)
+	}
+}
+
+func TestReadTaskInfoCSVReorderedColumns(t *testing.T) {
+	restore := chdirTestFiles(t, map[string]string{tasksDBFileName: "project,id,name,valid_workers,prerequisites,ideal_worker_count,min_worker_count,max_worker_count,duration,lag_hours,pinned_datetime,pinned_workers\n" +
+		"P1,T1,Dig,W1 W2,,2,1,3,12,,,\n" +
+		"P1,T2,Pour,W1,T1,1,1,2,6,2,2020-12-21T08:00,W1\n"})
+	tasks, err := readTaskInfoCSV()
+	restore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	//Same rows with the columns in a different order, unknown column is ignored
+	defer chdirTestFiles(t, map[string]string{tasksDBFileName: "Duration,pinned_workers,ID,notes,lag_hours,name,Project,max_worker_count,prerequisites,min_worker_count,pinned_datetime,valid_workers,ideal_worker_count\n" +
+		"12,,T1,first,,Dig,P1,3,,1,,W1 W2,2\n" +
+		"6,W1,T2,second,2,Pour,P1,2,T1,1,2020-12-21T08:00,W1,1\n"})()
+	reorderedTasks, err := readTaskInfoCSV()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tasks, reorderedTasks) {
+		t.Errorf("Reordered columns are parsed differently:\n%+v\n%+v", tasks, reorderedTasks)
+	}
+}
+
+func TestRunIslandsGA(t *testing.T) {
+	defer currentTuningConfig().apply()
+	defer func(reshuffle bool) { reshuffleStagnation = reshuffle }(reshuffleStagnation)
+	reshuffleStagnation = false
+	//All schedules of the equal independent tasks have the same fitness, so the best fitness never improves
+	setTestDBIndependentTasks(4)
+	populationSize = 5
+	islandsNumber = 2
+	migrationInterval = 3
+	migrationSize = 1
+	//Convergence state is kept between the migration rounds, so the stagnation limit longer than the interval stops the run
+	if _, generations := runIslandsGA(100, 5, 0.01); generations != 6 {
+		t.Errorf("Stagnation limit 5 stopped after %v generations, expected 6", generations)
+	}
+	//The last round is shorter than the migration interval
+	merged, generations := runIslandsGA(7, 0, 0.01)
+	if generations != 7 {
+		t.Errorf("Zero stagnation limit stopped after %v generations, expected 7", generations)
+	}
+	if len(merged.hashes) != len(merged.individuals) || len(merged.individuals) > islandsNumber*populationSize {
+		t.Errorf("Merged population has %v individuals and %v hashes, expected unique individuals of %v islands", len(merged.individuals), len(merged.hashes), islandsNumber)
+	}
+	for i := 1; i < len(merged.individuals); i++ {
+		if merged.individuals[i].fitness < merged.individuals[i-1].fitness {
+			t.Fatalf("Merged population is not sorted by fitness at %v", i)
+		}
+	}
+}
+
+func TestMigrateIndividuals(t *testing.T) {
+	defer func(size int) { migrationSize = size }(migrationSize)
+	migrationSize = 1
+	islands := []population{
+		{individuals: []individual{newTestIndividual(1, "A", "B", "C"), newTestIndividual(2, "A", "C", "B"), newTestIndividual(3, "B", "A", "C")}},
+		{individuals: []individual{newTestIndividual(5, "C", "B", "A"), newTestIndividual(6, "C", "A", "B"), newTestIndividual(7, "B", "C", "A")}},
+	}
+	migrateIndividuals(islands)
+	//Best individual of every island replaces the worst individual of the next island in the ring
+	if best := islands[1].individuals[0]; best.fitness != 1 || calcIndividualHash(best) != calcTasksHash(newTestIndividual(1, "A", "B", "C").tasks) {
+		t.Errorf("Best individual of the island 0 is not migrated to the island 1: %+v", islands[1].individuals)
+	}
+	if worst := islands[1].individuals[2]; worst.fitness != 6 {
+		t.Errorf("Worst individual of the island 1 is not replaced: %+v", islands[1].individuals)
+	}
+	if worst := islands[0].individuals[2]; worst.fitness != 5 {
+		t.Errorf("Best individual of the island 1 is not migrated to the island 0: %+v", islands[0].individuals)
+	}
+	for i, island := range islands {
+		if len(island.hashes) != len(island.individuals) {
+			t.Errorf("Island %v hashes are not recalculated: %v", i, island.hashes)
+		}
+		for hash, index := range island.hashes {
+			if calcIndividualHash(island.individuals[index]) != hash {
+				t.Errorf("Island %v hash %v points to the wrong individual %v", i, hash, index)
+			}
+		}
+	}
+}
+
+func TestAdaptMutationRate(t *testing.T) {
+	//All individuals have the task order of the best individual
+	lowDiversity := calcPopulationDiversity([]individual{newTestIndividual(1, "A", "B", "C"), newTestIndividual(2, "A", "B", "C"), newTestIndividual(3, "A", "B", "C")})
+	if lowDiversity != 0 {
+		t.Errorf("Identical task orders diversity = %v, expected 0", lowDiversity)
+	}
+	rate := adaptMutationRate(0.6, lowDiversity)
+	if !almostEqual(rate, 0.6+mutationRateStep) {
+		t.Errorf("Mutation rate = %v after diversity %v, expected to rise to %v", rate, lowDiversity, 0.6+mutationRateStep)
+	}
+	//Individuals disagree with the best individual on all 3 and 1 of 3 task pairs
+	highDiversity := calcPopulationDiversity([]individual{newTestIndividual(1, "A", "B", "C"), newTestIndividual(2, "C", "B", "A"), newTestIndividual(3, "B", "A", "C")})
+	if !almostEqual(highDiversity, 2.0/3) {
+		t.Errorf("Distinct task orders diversity = %v, expected %v", highDiversity, 2.0/3)
+	}
+	rate = adaptMutationRate(rate, highDiversity)
+	if !almostEqual(rate, 0.6) {
+		t.Errorf("Mutation rate = %v after diversity %v, expected to drop to 0.6", rate, highDiversity)
+	}
+	if rate = adaptMutationRate(maxMutationRate, lowDiversity); rate != maxMutationRate {
+		t.Errorf("Mutation rate = %v is above the upper bound %v", rate, maxMutationRate)
+	}
+}
+
+func TestRunGAAdaptiveMutation(t *testing.T) {
+	defer currentTuningConfig().apply()
+	defer func(adaptive bool, minRate, maxRate float32) {
+		adaptiveMutation, minMutationRate, maxMutationRate = adaptive, minRate, maxRate
+	}(adaptiveMutation, minMutationRate, maxMutationRate)
+	setTestDBIndependentTasks(4)
+	populationSize = 5
+	mutationRate = 0.9
+	adaptiveMutation = true
+	minMutationRate, maxMutationRate = 0.6, 0.6
+	//Adapted rate is kept in the population state, the configured rate is not changed
+	_, state := runGA(generatePopulation(), gaState{}, 2, 0, 0, nil)
+	if state.mutationRate != 0.6 || mutationRate != 0.9 {
+		t.Errorf("State mutation rate = %v, configured mutation rate = %v, expected 0.6 and 0.9", state.mutationRate, mutationRate)
+	}
+	//Every island adapts its own rate
+	islandsNumber = 2
+	migrationInterval = 1
+	if _, generations := runIslandsGA(2, 0, 0); generations != 2 || mutationRate != 0.9 {
+		t.Errorf("Islands completed %v generations, configured mutation rate = %v, expected 2 and 0.9", generations, mutationRate)
+	}
+}
+
+func TestDiversityStats(t *testing.T) {
+	identical := []individual{newTestIndividual(1, "A", "B", "C", "D"), newTestIndividual(1, "A", "B", "C", "D"), newTestIndividual(1, "A", "B", "C", "D")}
+	if stats := diversityStats(identical); stats.uniqueGenotypes != 1 || stats.averageOrderDistance != 0 {
+		t.Errorf("Identical population stats = %+v, expected 1 genotype and 0 distance", stats)
+	}
+	//Reversed orders disagree on all task pairs
+	reversed := []individual{newTestIndividual(1, "A", "B", "C", "D"), newTestIndividual(1, "D", "C", "B", "A")}
+	if stats := diversityStats(reversed); stats.uniqueGenotypes != 2 || stats.averageOrderDistance != 1 {
+		t.Errorf("Reversed population stats = %+v, expected 2 genotypes and 1 distance", stats)
+	}
+	distinct := []individual{newTestIndividual(1, "A", "B", "C", "D"), newTestIndividual(1, "B", "A", "C", "D"), newTestIndividual(1, "A", "B", "D", "C")}
+	stats := diversityStats(distinct)
+	//Individual pairs disagree on 1, 1 and 2 of 6 task pairs
+	if stats.uniqueGenotypes != 3 || !almostEqual(stats.averageOrderDistance, 4.0/18) {
+		t.Errorf("Distinct population stats = %+v, expected 3 genotypes and %v distance", stats, 4.0/18)
+	}
+}
+
+func TestCalcTasksHash(t *testing.T) {
+	for _, taskIDs := range [][]string{
+		{},
+		{"P1.T1"},
+		{"P1.T1", "P1.T2", "P2.T1"},
+		{"P2.T1", "P1.T2", "P1.T1"},
+		{"P1.T2", "P2.T1", "P1.T1"},
+	} {
+		//Hash of the comma separated task IDs
+		hashAlg := fnv.New64a()
+		hashAlg.Write([]byte(strings.Join(taskIDs, ",")))
+		if hash := calcTasksHash(newTestIndividual(0, taskIDs...).tasks); hash != hashAlg.Sum64() {
+			t.Errorf("Hash of %v = %v, expected %v", taskIDs, hash, hashAlg.Sum64())
+		}
+	}
+}
+
+func BenchmarkCalcTasksHash(b *testing.B) {
+	var taskIDs []string
+	for i := 0; i < 100; i++ {
+		taskIDs = append(taskIDs, "P1.T"+strconv.Itoa(i))
+	}
+	tasks := newTestIndividual(0, taskIDs...).tasks
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		calcTasksHash(tasks)
+	}
+}
+
+func TestMonteCarloSpread(t *testing.T) {
+	setTestDB(map[string]task{
+		"P1.T1": newTestTask(8, 1, "W1"),
+		"P1.T2": newTestTask(8, 1, "W1"),
+	}, map[string]worker{"W1": {}})
+	best := scheduleTestTasks("P1.T1", "P1.T2")
+	defer func(risk map[string]durationEstimate) { taskDurationRiskDB = risk }(taskDurationRiskDB)
+	calcSpread := func(estimate durationEstimate) float32 {
+		taskDurationRiskDB = map[string]durationEstimate{"P1.T1": estimate, "P1.T2": estimate}
+		rand.Seed(1)
+		makespans := runMonteCarlo(best, 200)
+		return percentile(makespans, 90) - percentile(makespans, 10)
+	}
+	narrowSpread := calcSpread(durationEstimate{min: 7, mode: 8, max: 9})
+	wideSpread := calcSpread(durationEstimate{min: 4, mode: 8, max: 16})
+	if narrowSpread <= 0 || wideSpread <= narrowSpread {
+		t.Errorf("P10-P90 makespan spread = %v for the wide distribution, expected more than %v for the narrow one", wideSpread, narrowSpread)
+	}
+}
+
+func TestImmigrationKeepsDiversity(t *testing.T) {
+	defer currentTuningConfig().apply()
+	setTestDBIndependentTasks(6)
+	populationSize = 10
+	elitismRate = 0.2
+	immigrationRate = 0.5
+	rand.Seed(1)
+	pop := generatePopulation()
+	generatePopulationSchedules(pop.individuals)
+	sortPopulation(pop.individuals)
+	for i := 0; i < 10; i++ {
+		pop = transmogrifyPopulation(pop, mutationRate)
+		generatePopulationSchedules(pop.individuals)
+		sortPopulation(pop.individuals)
+		stats := diversityStats(pop.individuals)
+		if stats.uniqueGenotypes != populationSize || stats.averageOrderDistance < 0.2 {
+			t.Errorf("Generation %v diversity = %+v, expected %v unique genotypes and at least 0.2 average order distance", i, stats, populationSize)
+		}
+	}
+}
+
+func TestReshuffleEventLog(t *testing.T) {
+	defer currentTuningConfig().apply()
+	defer func(reshuffle bool) { reshuffleStagnation = reshuffle }(reshuffleStagnation)
+	reshuffleStagnation = true
+	//All schedules of the equal independent tasks have the same fitness, so every generation is stagnant
+	setTestDBIndependentTasks(4)
+	populationSize = 5
+	rand.Seed(1)
+	oldParameters := currentReshuffleParameters()
+	var reshuffleLog bytes.Buffer
+	runGA(generatePopulation(), gaState{}, 52, 0, 0, csv.NewWriter(&reshuffleLog))
+	records, err := csv.NewReader(&reshuffleLog).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("Reshuffle log has %v events, expected 1: %v", len(records), records)
+	}
+	expected := append([]string{"51"}, oldParameters.toStrings()...)
+	expected = append(expected, currentReshuffleParameters().toStrings()...)
+	if record := append(records[0][:1], records[0][2:]...); !reflect.DeepEqual(record, expected) {
+		t.Errorf("Reshuffle event = %v, expected generation and parameters %v", records[0], expected)
+	}
+}
+
+func TestProjectContinuityFitness(t *testing.T) {
+	defer func(weight float32) { weightProjectContinuity = weight }(weightProjectContinuity)
+	secondProjectTask := func() task {
+		newTask := newTestTask(8, 1, "W1")
+		newTask.project = "P2"
+		return newTask
+	}
+	setTestDB(map[string]task{
+		"P1.T1": newTestTask(8, 1, "W1"),
+		"P1.T2": newTestTask(8, 1, "W1"),
+		"P2.T1": secondProjectTask(),
+		"P2.T2": secondProjectTask(),
+	}, map[string]worker{"W1": {}})
+	projectsDB["P2"] = project{site: newTestSite()}
+	weightProjectContinuity = 1
+	//One task per day, so the task order is the worker project run order
+	alternating := scheduleTestTasks("P1.T1", "P2.T1", "P1.T2", "P2.T2")
+	grouped := scheduleTestTasks("P1.T1", "P1.T2", "P2.T1", "P2.T2")
+	if alternating.fitnessData.makespan != grouped.fitnessData.makespan {
+		t.Fatalf("Makespan = %v and %v, expected equal makespan", alternating.fitnessData.makespan, grouped.fitnessData.makespan)
+	}
+	if switches := countProjectSwitches(alternating); switches != 3 {
+		t.Errorf("Alternating projects have %v switches, expected 3", switches)
+	}
+	if switches := countProjectSwitches(grouped); switches != 1 {
+		t.Errorf("Grouped projects have %v switches, expected 1", switches)
+	}
+	if grouped.fitness >= alternating.fitness {
+		t.Errorf("Grouped projects fitness %v should be better than alternating projects fitness %v", grouped.fitness, alternating.fitness)
+	}
+
+	//Tuesday is a holiday at both sites, so Wednesday is the next working day after Monday
+	for _, projectID := range []string{"P1", "P2"} {
+		site := newTestSite()
+		site.Holidays = map[time.Time]struct{}{testDateTime(22, 0): {}}
+		projectsDB[projectID] = project{site: site}
+	}
+	overHoliday := individual{tasks: []scheduledTask{
+		{taskID: "P1.T1", startTime: testDateTime(21, 8), stopTime: testDateTime(21, 16), assignees: []string{"W1"}},
+		{taskID: "P2.T1", startTime: testDateTime(23, 8), stopTime: testDateTime(23, 16), assignees: []string{"W1"}},
+	}}
+	if switches := countProjectSwitches(overHoliday); switches != 1 {
+		t.Errorf("Projects switched over the holiday have %v switches, expected 1", switches)
+	}
+}
+
+func TestGroupTasksByProject(t *testing.T) {
+	tasksDB = map[string]task{"P1.T1": {project: "P1"}, "P1.T2": {project: "P1"}, "P1.T3": {project: "P1"}, "P2.T1": {project: "P2"}, "P2.T2": {project: "P2"}}
+	individual := individual{tasks: []scheduledTask{
+		{taskID: "P2.T2", startTime: testDateTime(21, 8)},
+		{taskID: "P1.T1"},
+		{taskID: "P1.T2", startTime: testDateTime(23, 8)},
+		{taskID: "P2.T1", startTime: testDateTime(22, 8)},
+		{taskID: "P1.T3", startTime: testDateTime(21, 12)},
+	}}
+	projectIDs, projectTasks := groupTasksByProject(individual)
+	if !reflect.DeepEqual(projectIDs, []string{"P1", "P2"}) {
+		t.Errorf("Projects = %v, expected [P1 P2]", projectIDs)
+	}
+	//Unscheduled tasks go last
+	expected := map[string][]string{"P1": {"P1.T3", "P1.T2", "P1.T1"}, "P2": {"P2.T2", "P2.T1"}}
+	for projectID, taskIDs := range expected {
+		var groupedTaskIDs []string
+		for _, task := range projectTasks[projectID] {
+			groupedTaskIDs = append(groupedTaskIDs, task.taskID)
+		}
+		if !reflect.DeepEqual(groupedTaskIDs, taskIDs) {
+			t.Errorf("Project %v tasks = %v, expected %v", projectID, groupedTaskIDs, taskIDs)
+		}
+	}
+}
+
+func TestWorkersScarcity(t *testing.T) {
+	defer currentTuningConfig().apply()
+	//Only W1 can do T2, so W2 should do T1
+	setTestDB(map[string]task{
+		"P1.T1": newTestTask(8, 1, "W1", "W2"),
+		"P1.T2": newTestTask(8, 1, "W1"),
+	}, map[string]worker{"W1": {}, "W2": {}})
+	scarcity := calculateWorkersScarcity(scheduledTask{taskID: "P1.T1"}, []scheduledTask{{taskID: "P1.T1"}, {taskID: "P1.T2"}})
+	if !almostEqual(scarcity["W1"], 1.0/3) || !almostEqual(scarcity["W2"], 1) {
+		t.Errorf("Scarcity = %v, expected W1 = 0.33 and W2 = 1", scarcity)
+	}
+	//W1 is more familiar with the project, so it's the best fit for T1 without the scarcity
+	projectFamiliarityDB = map[string]map[string]float32{"P1": {"W1": 10}}
+	weightDemand = 0
+	weightScarcity = 0
+	if assignees := findTestTask(scheduleTestTasks("P1.T1", "P1.T2"), "P1.T1").assignees; !reflect.DeepEqual(assignees, []string{"W1"}) {
+		t.Errorf("Task P1.T1 assignees without the scarcity = %v, expected the familiar W1", assignees)
+	}
+	weightScarcity = 10
+	individual := scheduleTestTasks("P1.T1", "P1.T2")
+	if assignees := findTestTask(individual, "P1.T1").assignees; !reflect.DeepEqual(assignees, []string{"W2"}) {
+		t.Errorf("Task P1.T1 assignees = %v, expected the less scarce W2", assignees)
+	}
+	if individual.fitnessData.makespan != 8 {
+		t.Errorf("Makespan = %v, expected both tasks in parallel for 8 hours", individual.fitnessData.makespan)
+	}
+}
+
+//Flags can be defined once, so parseFlags is called by this test only
+func TestGenerationsFlag(t *testing.T) {
+	defer currentTuningConfig().apply()
+	defer func(args []string) { os.Args = args }(os.Args)
+	//Flags are defined on the fresh flag set, so the test can be repeated
+	defer func(commandLine *flag.FlagSet) { flag.CommandLine = commandLine }(flag.CommandLine)
+	flag.CommandLine = flag.NewFlagSet("sambo", flag.ExitOnError)
+	os.Args = []string{"sambo", "-generations=7"}
+	parseFlags()
+	if generationsLimit != 7 {
+		t.Fatalf("Generations limit = %v, expected the flag value 7", generationsLimit)
+	}
+	setTestDBIndependentTasks(4)
+	rand.Seed(1)
+	if _, state := runGA(generatePopulation(), gaState{}, generationsLimit, 0, 0, nil); state.generationsNumber != 7 {
+		t.Errorf("GA completed %v generations, expected 7", state.generationsNumber)
+	}
+}
+
+func TestEquipmentSerializesTasks(t *testing.T) {
+	defer func(equipment map[string]equipment) { equipmentDB = equipment }(equipmentDB)
+	craneTask := func() task {
+		newTask := newTestTask(8, 1, "W1", "W2")
+		newTask.equipment = map[string]int{"C1": 1}
+		return newTask
+	}
+	setTestDB(map[string]task{"P1.T1": craneTask(), "P1.T2": craneTask()}, map[string]worker{"W1": {}, "W2": {}})
+	equipmentDB = map[string]equipment{"C1": {name: "Crane", quantity: 1}}
+	individual := scheduleTestTasks("P1.T1", "P1.T2")
+	first, second := findTestTask(individual, "P1.T1"), findTestTask(individual, "P1.T2")
+	if !isTaskScheduled(first) || !isTaskScheduled(second) {
+		t.Fatalf("Tasks are not scheduled: %+v, %+v", first, second)
+	}
+	if second.startTime.Before(first.stopTime) {
+		t.Errorf("Task P1.T2 starts at %v before the crane is released by P1.T1 at %v", second.startTime, first.stopTime)
+	}
+	//Two cranes let the free workers do both tasks at once
+	equipmentDB["C1"] = equipment{name: "Crane", quantity: 2}
+	if makespan := scheduleTestTasks("P1.T1", "P1.T2").fitnessData.makespan; makespan != 8 {
+		t.Errorf("Makespan with 2 cranes = %v, expected 8", makespan)
+	}
+}
+
+func TestUnlimitedWorkersMakespan(t *testing.T) {
+	tasks := make(map[string]task)
+	for i := 1; i <= 6; i++ {
+		tasks["P1.T"+strconv.Itoa(i)] = newTestTask(8, 1, "W1")
+	}
+	setTestDB(tasks, map[string]worker{"W1": {}})
+	unlimitedMakespan := calcUnlimitedWorkersMakespan()
+	if unlimitedMakespan != 8 {
+		t.Errorf("Unlimited workers makespan = %v, expected all tasks in parallel for 8 hours", unlimitedMakespan)
+	}
+	//Single worker does one task per day from Monday to the next Monday
+	if makespan := scheduleTestTasks("P1.T1", "P1.T2", "P1.T3", "P1.T4", "P1.T5", "P1.T6").fitnessData.makespan; makespan != 7*24+8 {
+		t.Errorf("Single worker makespan = %v, expected %v", makespan, 7*24+8)
+	}
+}
+
+func TestValidateRatesClamping(t *testing.T) {
+	defer currentTuningConfig().apply()
+	defer func(testLogger *log.Logger) { logger = testLogger }(logger)
+	logFile, err := ioutil.TempFile(t.TempDir(), "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logFile.Close()
+	logger = log.New(logFile)
+	crossoverRate = 1.5
+	mutationRate = -0.2
+	validateRates()
+	if crossoverRate != 1 || mutationRate != 0 {
+		t.Errorf("Crossover and mutation rates = %v and %v, expected to be clamped to 1 and 0", crossoverRate, mutationRate)
+	}
+	output, err := ioutil.ReadFile(logFile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"crossoverRate=1.5 is out of 0-1 range, clamped to 1", "mutationRate=-0.2 is out of 0-1 range, clamped to 0"} {
+		if !strings.Contains(string(output), expected) {
+			t.Errorf("Log %q doesn't report %q", output, expected)
+		}
+	}
+}
+
+func TestWriteScheduleJSONL(t *testing.T) {
+	setTestDB(map[string]task{
+		"P1.T1": newTestTask(8, 2, "W1", "W2"),
+		"P1.T2": newTestTask(4, 1, "W1"),
+	}, map[string]worker{"W1": {name: "Ann"}, "W2": {name: "Bob"}})
+	individual := scheduleTestTasks("P1.T1", "P1.T2")
+	dir := t.TempDir()
+	writeScheduleJSONL(filepath.Join(dir, "schedule.jsonl"), individual)
+	writeScheduleJSON(filepath.Join(dir, "schedule.json"), individual)
+
+	scheduleLines, err := ioutil.ReadFile(filepath.Join(dir, "schedule.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tasks []scheduledTaskJSON
+	for _, line := range strings.Split(strings.TrimSuffix(string(scheduleLines), "\n"), "\n") {
+		var task scheduledTaskJSON
+		if err := json.Unmarshal([]byte(line), &task); err != nil {
+			t.Fatalf("Couldn't parse the line %q: %v", line, err)
+		}
+		tasks = append(tasks, task)
+	}
+	scheduleDocument, err := ioutil.ReadFile(filepath.Join(dir, "schedule.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schedule scheduleJSON
+	if err := json.Unmarshal(scheduleDocument, &schedule); err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 2 || !reflect.DeepEqual(tasks, schedule.Tasks) {
+		t.Errorf("JSON Lines tasks %+v don't match the JSON document tasks %+v", tasks, schedule.Tasks)
+	}
+}
+
+func TestWriteScheduleCSVUnscheduledTask(t *testing.T) {
+	//Only the missing worker W9 can do the second task, so it stays unscheduled
+	setTestDB(map[string]task{
+		"P1.T1": newTestTask(4, 1, "W1"),
+		"P1.T2": newTestTask(4, 1, "W9"),
+	}, map[string]worker{"W1": {name: "Ann"}})
+	individual := scheduleTestTasks("P1.T1", "P1.T2")
+	fileName := filepath.Join(t.TempDir(), "schedule.csv")
+	writeScheduleCSV(fileName, individual)
+
+	scheduleCSV, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(bytes.NewReader(scheduleCSV)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("Schedule CSV has %v records, expected the header and 2 tasks", len(records))
+	}
+	for _, record := range records[1:] {
+		if record[6] != "T2" {
+			continue
+		}
+		//start, stop, workerNames, workerIDs, pinnedWorkers and pinnedDateTime columns
+		for _, column := range []int{0, 1, 4, 5, 9, 10} {
+			if record[column] != "" {
+				t.Errorf("Unscheduled task column %v = %q, expected empty", records[0][column], record[column])
+			}
+		}
+		return
+	}
+	t.Errorf("Schedule CSV %v doesn't have the unscheduled task", records)
+}
+
+func TestFirstAvailableStrategy(t *testing.T) {
+	defer func(strategy string) { assignmentStrategy = strategy }(assignmentStrategy)
+	assignmentStrategy = firstAvailableStrategy
+	setTestDBIndependentTasks(6)
+	individual := scheduleTestTasks("P1.T1", "P1.T2", "P1.T3", "P1.T4", "P1.T5", "P1.T6")
+	if individual.fitnessData.unscheduledTasks != 0 {
+		t.Errorf("First available strategy left %v unscheduled tasks", individual.fitnessData.unscheduledTasks)
+	}
+	//Workers can't do two tasks at once
+	for _, first := range individual.tasks {
+		for _, second := range individual.tasks {
+			if first.taskID != second.taskID && first.assignees[0] == second.assignees[0] && first.startTime.Before(second.stopTime) && second.startTime.Before(first.stopTime) {
+				t.Errorf("Worker %v tasks %v and %v overlap", first.assignees[0], first.taskID, second.taskID)
+			}
+		}
+	}
+}
+
+func BenchmarkAssignmentStrategy(b *testing.B) {
+	defer func(strategy string) { assignmentStrategy = strategy }(assignmentStrategy)
+	setTestDBIndependentTasks(50)
+	rand.Seed(1)
+	individual := generateIndividual()
+	for _, strategy := range []string{bestFitStrategy, firstAvailableStrategy} {
+		b.Run(strategy, func(b *testing.B) {
+			assignmentStrategy = strategy
+			for i := 0; i < b.N; i++ {
+				scheduleIndividual(individual, nil)
+			}
+		})
+	}
+}
+
+//Schedule generation goroutines don't use the random numbers, so the throughput scales with the threads without the global source contention
+func BenchmarkGeneratePopulationSchedules(b *testing.B) {
+	defer currentTuningConfig().apply()
+	defer func(threads int) { threadsNum = threads }(threadsNum)
+	setTestDBIndependentTasks(50)
+	populationSize = 256
+	rand.Seed(1)
+	population := generatePopulation()
+	for _, threads := range []int{1, 4, 16} {
+		b.Run("threads-"+strconv.Itoa(threads), func(b *testing.B) {
+			threadsNum = threads
+			for i := 0; i < b.N; i++ {
+				generatePopulationSchedules(population.individuals)
+			}
+		})
+	}
+}
+
+func TestTaskWindow(t *testing.T) {
+	permitTask := newTestTask(8, 1, "W1")
+	permitTask.windowStart = testDateTime(23, 8)
+	permitTask.windowEnd = testDateTime(23, 16)
+	setTestDB(map[string]task{"P1.T1": permitTask}, map[string]worker{"W1": {}})
+	task := findTestTask(scheduleTestTasks("P1.T1"), "P1.T1")
+	if !task.startTime.Equal(testDateTime(23, 8)) || !task.stopTime.Equal(testDateTime(23, 16)) {
+		t.Errorf("Task is scheduled from %v to %v, expected inside the window on Wednesday", task.startTime, task.stopTime)
+	}
+	//Task can't fit into the shorter window
+	permitTask.windowEnd = testDateTime(23, 12)
+	tasksDB["P1.T1"] = permitTask
+	if task := findTestTask(scheduleTestTasks("P1.T1"), "P1.T1"); isTaskScheduled(task) {
+		t.Errorf("Task is scheduled from %v to %v outside the window", task.startTime, task.stopTime)
+	}
+}
+
+func TestWriteScheduleTrace(t *testing.T) {
+	setTestDB(map[string]task{
+		"P1.T1": newTestTask(8, 2, "W1", "W2"),
+		"P1.T2": newTestTask(4, 1, "W1"),
+	}, map[string]worker{"W1": {}, "W2": {}})
+	individual := scheduleTestTasks("P1.T1", "P1.T2")
+	fileName := filepath.Join(t.TempDir(), "trace.csv")
+	writeScheduleTrace(fileName, individual)
+	traceFile, err := os.Open(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer traceFile.Close()
+	records, err := csv.NewReader(traceFile).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	//Every task assignee is traced once
+	assignments := make(map[string][]string)
+	for _, record := range records[1:] {
+		if record[0] == "assigned" {
+			assignments[record[1]] = append(assignments[record[1]], record[2])
+		}
+	}
+	for _, task := range individual.tasks {
+		traced, assignees := assignments[task.taskID], append([]string(nil), task.assignees...)
+		sort.Strings(traced)
+		sort.Strings(assignees)
+		if !reflect.DeepEqual(traced, assignees) {
+			t.Errorf("Task %v traced assignments = %v, expected %v", task.taskID, traced, assignees)
+		}
+	}
+}
+
+func TestWeekendOnlyTask(t *testing.T) {
+	weekendSite := newTestSite()
+	weekendSite.WorkingWeekdays = map[time.Weekday]struct{}{time.Saturday: {}, time.Sunday: {}}
+	weekendTask := newTestTask(4, 1, "W1")
+	weekendTask.site = &weekendSite
+	setTestDB(map[string]task{"P1.T1": weekendTask, "P1.T2": newTestTask(4, 1, "W1")}, map[string]worker{"W1": {}})
+	individual := scheduleTestTasks("P1.T1", "P1.T2")
+	if task := findTestTask(individual, "P1.T1"); !task.startTime.Equal(testDateTime(26, 8)) || !task.stopTime.Equal(testDateTime(26, 12)) {
+		t.Errorf("Weekend task is scheduled from %v to %v, expected on Saturday from 8:00 to 12:00", task.startTime, task.stopTime)
+	}
+	//Project task continues on the project working days
+	if task := findTestTask(individual, "P1.T2"); !task.startTime.Equal(testDateTime(28, 8)) {
+		t.Errorf("Project task starts at %v, expected on Monday after the weekend task", task.startTime)
+	}
+}
+
+func TestCrossoverLocality(t *testing.T) {
+	defer currentTuningConfig().apply()
+	crossoverRate = 1
+	maxCrossoverLength = 5
+	crossoverLocality = 3
+	rand.Seed(1)
+	for i := 0; i < 200; i++ {
+		parents := []individual{newRandomTestIndividual(20), newRandomTestIndividual(20)}
+		for j, child := range crossoverIndividualsOX1(parents) {
+			parentPositions, childPositions := taskPositions(parents[j]), taskPositions(child)
+			if len(childPositions) != 20 {
+				t.Fatalf("Child %v is not a permutation of the parent tasks", child.tasks)
+			}
+			for taskID, position := range childPositions {
+				if distance := position - parentPositions[taskID]; distance > crossoverLocality || distance < -crossoverLocality {
+					t.Fatalf("Task %v is moved by %v positions, expected at most %v", taskID, distance, crossoverLocality)
+				}
+			}
+		}
+	}
+}
+
+func TestBuildDispatchSheets(t *testing.T) {
+	setTestDB(map[string]task{"P1.T1": newTestTask(4, 1), "P1.T2": newTestTask(8, 1), "P1.T3": newTestTask(3, 1)}, map[string]worker{"W1": {}, "W2": {}})
+	individual := individual{tasks: []scheduledTask{
+		{taskID: "P1.T1", startTime: testDateTime(21, 8), stopTime: testDateTime(21, 12), assignees: []string{"W1"}},
+		{taskID: "P1.T2", startTime: testDateTime(21, 12), stopTime: testDateTime(22, 12), assignees: []string{"W2"}},
+		{taskID: "P1.T3", startTime: testDateTime(22, 13), stopTime: testDateTime(22, 16), assignees: []string{"W1"}},
+	}}
+	days, sheets := buildDispatchSheets(individual)
+	if !reflect.DeepEqual(days, []time.Time{testDateTime(21, 0), testDateTime(22, 0)}) {
+		t.Fatalf("Dispatch days = %v, expected Monday and Tuesday", days)
+	}
+	//Task spanning two days is split by the site working hours
+	expected := map[time.Time]map[string][]string{
+		testDateTime(21, 0): {"W1": {"P1.T1 08:00-12:00"}, "W2": {"P1.T2 12:00-16:00"}},
+		testDateTime(22, 0): {"W1": {"P1.T3 13:00-16:00"}, "W2": {"P1.T2 08:00-12:00"}},
+	}
+	for day, workers := range expected {
+		for workerID, expectedEntries := range workers {
+			var entries []string
+			for _, entry := range sheets[day][workerID] {
+				entries = append(entries, entry.task.taskID+" "+entry.startTime.Format(defaultTimeFormat)+"-"+entry.stopTime.Format(defaultTimeFormat))
+			}
+			if !reflect.DeepEqual(entries, expectedEntries) {
+				t.Errorf("Dispatch sheet %v of %v = %v, expected %v", day.Format(defaultDateFormat), workerID, entries, expectedEntries)
+			}
+		}
+	}
+}
+
+func TestOptionalTask(t *testing.T) {
+	defer currentTuningConfig().apply()
+	weightMakespan = 1
+	//Fitness of the schedule with and without the optional task
+	scheduleOptional := func(optionalWorkerID string) (scheduled, dropped individual) {
+		optionalTask := newTestTask(4, 1, optionalWorkerID)
+		optionalTask.optionalReward = 2
+		setTestDB(map[string]task{"P1.T1": optionalTask, "P1.T2": newTestTask(8, 1, "W1")}, map[string]worker{"W1": {}, "W2": {}})
+		scheduled = scheduleTestTasks("P1.T1", "P1.T2")
+		dropped = newTestIndividual(0, "P1.T1", "P1.T2")
+		dropped.droppedTasks = map[string]struct{}{"P1.T1": {}}
+		for _, workerID := range []string{"W1", "W2"} {
+			dropped.workers = append(dropped.workers, scheduledWorker{workerID: workerID})
+		}
+		dropped = scheduleIndividual(dropped, nil)
+		return scheduled, dropped
+	}
+
+	//Spare worker has the slack for the optional task
+	scheduled, dropped := scheduleOptional("W2")
+	if !isTaskScheduled(findTestTask(scheduled, "P1.T1")) || scheduled.fitnessData.makespan != 8 {
+		t.Fatalf("Optional task with slack scheduled = %v, makespan = %v, expected scheduled task and makespan 8", isTaskScheduled(findTestTask(scheduled, "P1.T1")), scheduled.fitnessData.makespan)
+	}
+	if scheduled.fitness >= dropped.fitness {
+		t.Errorf("Fitness with optional task = %v, without = %v, expected optional task to be preferred", scheduled.fitness, dropped.fitness)
+	}
+
+	//Same worker has to delay the mandatory task for the optional one
+	scheduled, dropped = scheduleOptional("W1")
+	if dropped.fitnessData.unscheduledTasks != 0 || dropped.fitnessData.unscheduledPenalty != 0 {
+		t.Errorf("Dropped optional task unscheduled = %v, penalty = %v, expected no penalty", dropped.fitnessData.unscheduledTasks, dropped.fitnessData.unscheduledPenalty)
+	}
+	if dropped.fitnessData.makespan != 8 || dropped.fitness >= scheduled.fitness {
+		t.Errorf("Fitness without optional task = %v (makespan %v), with = %v, expected dropped optional task to be preferred", dropped.fitness, dropped.fitnessData.makespan, scheduled.fitness)
+	}
+}
+
+func TestTourneySelectLargeSample(t *testing.T) {
+	defer func(size int) { tourneySampleSize = size }(tourneySampleSize)
+	var individuals []individual
+	for i := 0; i < 5; i++ {
+		individuals = append(individuals, newTestIndividual(float32(i+1), "T"+strconv.Itoa(i)))
+	}
+	for _, tourneySampleSize = range []int{0, 100} {
+		//More selections than individuals drain the sample order and start it over
+		selected := tourneySelect(individuals, 2*len(individuals))
+		if len(selected) != 2*len(individuals) {
+			t.Fatalf("Sample size %v selected %v individuals, expected %v", tourneySampleSize, len(selected), 2*len(individuals))
+		}
+		//Sample covers the whole population, so the best individual wins first
+		if tourneySampleSize == 100 && selected[0].fitness != 1 {
+			t.Errorf("Sample size %v first selected fitness = %v, expected 1", tourneySampleSize, selected[0].fitness)
+		}
+	}
+}
+
+func TestAsymmetricDrivingTime(t *testing.T) {
+	setTestDB(map[string]task{"P1.T1": newTestTask(1, 1, "W1")}, map[string]worker{"W1": {}})
+	projectsDB["P1"] = project{site: newTestSite(), latitude: 1, longitude: 1}
+	//Morning commute from the worker home to the site is shorter than the way back
+	matrix := location.NewMatrixProvider()
+	matrix.SetDrivingTime(0, 0, 1, 1, 1)
+	matrix.SetDrivingTime(1, 1, 0, 0, 3)
+	drivingTimeProvider = matrix
+
+	if drivingTime := calcProjectDrivingTime(0, 0, "P1"); drivingTime != 1 {
+		t.Errorf("Driving time to the project = %v, expected 1", drivingTime)
+	}
+	individual := scheduleTestTasks("P1.T1")
+	if individual.workers[0].drivingHours != 1 {
+		t.Errorf("Worker driving hours = %v, expected 1", individual.workers[0].drivingHours)
+	}
+	if startTime := individual.tasks[0].startTime; !startTime.Equal(testDateTime(21, 9)) {
+		t.Errorf("Task start time = %v, expected arrival after 1 hour of driving", startTime)
+	}
+}
+
+func TestRollingHorizon(t *testing.T) {
+	setTestDB(map[string]task{"P1.T1": newTestTask(4, 1, "W1", "W2"), "P1.T2": newTestTask(8, 1, "W1", "W2"), "P1.T3": newTestTask(2, 1, "W2")}, map[string]worker{"W1": {}, "W2": {}})
+	defer func() { frozenTasks = nil }()
+	now := testDateTime(22, 12)
+	scheduleStartTime = now
+	baseline := []scheduledTaskJSON{
+		{ProjectID: "P1", TaskID: "T1", Start: testDateTime(21, 8).Format(time.RFC3339), Stop: testDateTime(21, 12).Format(time.RFC3339), AssigneeIDs: []string{"W1"}},
+		{ProjectID: "P1", TaskID: "T2", Start: testDateTime(22, 8).Format(time.RFC3339), Stop: testDateTime(22, 16).Format(time.RFC3339), AssigneeIDs: []string{"W2"}},
+		{ProjectID: "P1", TaskID: "T3", Start: testDateTime(23, 8).Format(time.RFC3339), Stop: testDateTime(23, 10).Format(time.RFC3339), AssigneeIDs: []string{"W2"}},
+	}
+	applyRollingHorizon(baseline, now)
+	if _, ok := frozenTasks["P1.T3"]; ok || len(frozenTasks) != 2 {
+		t.Fatalf("Frozen tasks = %v, expected completed and in-progress tasks only", frozenTasks)
+	}
+	if duration := tasksDB["P1.T2"].duration; duration != 4 {
+		t.Errorf("In-progress task duration = %v, expected remaining 4 hours", duration)
+	}
+
+	individual := scheduleTestTasks("P1.T1", "P1.T2", "P1.T3")
+	completedTask, inProgressTask, futureTask := findTestTask(individual, "P1.T1"), findTestTask(individual, "P1.T2"), findTestTask(individual, "P1.T3")
+	if !completedTask.startTime.Equal(testDateTime(21, 8)) || !completedTask.stopTime.Equal(testDateTime(21, 12)) || !reflect.DeepEqual(completedTask.assignees, []string{"W1"}) {
+		t.Errorf("Completed task = %v, expected baseline Monday 8:00-12:00 by W1", completedTask)
+	}
+	if !inProgressTask.startTime.Equal(now) || !inProgressTask.stopTime.Equal(testDateTime(22, 16)) || !reflect.DeepEqual(inProgressTask.assignees, []string{"W2"}) {
+		t.Errorf("In-progress task = %v, expected pinned Tuesday 12:00-16:00 by W2", inProgressTask)
+	}
+	//Future task waits for its only valid worker to finish the in-progress task
+	if !reflect.DeepEqual(futureTask.assignees, []string{"W2"}) || futureTask.startTime.Before(inProgressTask.stopTime) {
+		t.Errorf("Future task = %v, expected W2 after the in-progress task", futureTask)
+	}
+}
+
+func TestWriteTaskInfoCSVRoundTrip(t *testing.T) {
+	setTestDB(map[string]task{"P1.T1": newTestTask(4, 1, "W1", "W2"), "P1.T2": newTestTask(6, 2, "W1", "W2")}, map[string]worker{"W1": {}, "W2": {}})
+	individual := scheduleTestTasks("P1.T1", "P1.T2")
+	defer chdirTestFiles(t, nil)()
+	writeTaskInfoCSV(tasksDBFileName, individual)
+	tasks, err := readTaskInfoCSV()
+	if err != nil {
+		t.Fatal(err)
+	}
+	//Re-loaded tasks are pinned to the scheduled start and workers
+	for _, task := range individual.tasks {
+		reloadedTask, ok := tasks[task.taskID]
+		if !ok {
+			t.Fatalf("Task %v is missing in the exported CSV", task.taskID)
+		}
+		assignees := make(map[string]struct{})
+		for _, workerID := range task.assignees {
+			assignees[workerID] = struct{}{}
+		}
+		if !reloadedTask.pinnedDateTime.Equal(task.startTime) || !reflect.DeepEqual(reloadedTask.pinnedWorkerIDs, assignees) || reloadedTask.pinMode != pinOnlyMode {
+			t.Errorf("Task %v pinned to %v %v %v, expected %v %v %v", task.taskID, reloadedTask.pinnedDateTime, reloadedTask.pinnedWorkerIDs, reloadedTask.pinMode, task.startTime, assignees, pinOnlyMode)
+		}
+		if reloadedTask.duration != tasksDB[task.taskID].duration || reloadedTask.minWorkerCount != tasksDB[task.taskID].minWorkerCount {
+			t.Errorf("Task %v = %+v, expected the original duration and worker count", task.taskID, reloadedTask)
+		}
+	}
+}
+
+func TestSynchronizeCrew(t *testing.T) {
+	defer func(synchronize bool) { synchronizeCrew = synchronize }(synchronizeCrew)
+	//W2 is busy with the first task until 10:00, W1 is available at 8:00
+	setTestDB(map[string]task{"P1.T1": newTestTask(2, 1, "W2"), "P1.T2": newTestTask(4, 2, "W1", "W2")}, map[string]worker{"W1": {}, "W2": {}})
+	for _, test := range []struct {
+		synchronize       bool
+		expectedStartTime time.Time
+	}{
+		{false, testDateTime(21, 8)},
+		{true, testDateTime(21, 10)},
+	} {
+		synchronizeCrew = test.synchronize
+		crewTask := findTestTask(scheduleTestTasks("P1.T1", "P1.T2"), "P1.T2")
+		if !crewTask.startTime.Equal(test.expectedStartTime) || !crewTask.stopTime.Equal(newTestSite().AddHours(test.expectedStartTime, 4)) {
+			t.Errorf("Synchronized %v crew task %v-%v, expected start at %v", test.synchronize, crewTask.startTime, crewTask.stopTime, test.expectedStartTime)
+		}
+	}
+}
+
+func TestSynchronizeCrewTimeOff(t *testing.T) {
+	defer func(synchronize bool) { synchronizeCrew = synchronize }(synchronizeCrew)
+	synchronizeCrew = true
+	//W2 joins the crew task first, W1 arrives after the first task when W2 is on time off
+	setTestDB(map[string]task{"P1.T1": newTestTask(8, 1, "W1"), "P1.T2": newTestTask(8, 2, "W1", "W2")}, map[string]worker{
+		"W1": {},
+		"W2": {blockedRanges: []dateTimeRange{{startTime: testDateTime(22, 0), endTime: testDateTime(31, 0)}}},
+	})
+	if crewTask := findTestTask(scheduleTestTasks("P1.T1", "P1.T2"), "P1.T2"); isTaskScheduled(crewTask) {
+		t.Errorf("Crew task is scheduled from %v to %v %v during the W2 time off", crewTask.startTime, crewTask.stopTime, crewTask.assignees)
+	}
+}
+
+func TestCalculateWeekSummaries(t *testing.T) {
+	secondProjectTask := newTestTask(12, 1)
+	secondProjectTask.project = "P2"
+	setTestDB(map[string]task{"P1.T1": newTestTask(8, 1), "P2.T1": secondProjectTask, "P2.T2": secondProjectTask}, map[string]worker{"W1": {}, "W2": {}})
+	projectsDB["P2"] = project{site: newTestSite()}
+	//W1 task from Friday to Monday is split between the ISO weeks 52 and 53
+	individual := individual{tasks: []scheduledTask{
+		{taskID: "P1.T1", startTime: testDateTime(24, 8), stopTime: testDateTime(24, 16), assignees: []string{"W1"}},
+		{taskID: "P2.T1", startTime: testDateTime(25, 8), stopTime: testDateTime(28, 12), assignees: []string{"W1"}},
+		{taskID: "P2.T2", startTime: testDateTime(28, 8), stopTime: testDateTime(28, 14), assignees: []string{"W2"}},
+	}}
+	expected := map[string][]weekSummary{
+		"W1": {
+			{year: 2020, week: 52, projects: map[string]struct{}{"P1": {}, "P2": {}}, hours: 16},
+			{year: 2020, week: 53, projects: map[string]struct{}{"P2": {}}, hours: 4},
+		},
+		"W2": {
+			{year: 2020, week: 53, projects: map[string]struct{}{"P2": {}}, hours: 6},
+		},
+	}
+	if summaries := calculateWeekSummaries(individual); !reflect.DeepEqual(summaries, expected) {
+		t.Errorf("Week summaries = %+v, expected %+v", summaries, expected)
+	}
+}
+
+func TestCrewsNotMixed(t *testing.T) {
+	defer func(strategy string) { assignmentStrategy = strategy }(assignmentStrategy)
+	crewTask := newTestTask(4, 2, "W1", "W2", "W3", "W4")
+	crewTask.crews = parseCrews("W1 W2|W3 W4")
+	//W1 and W3 are busy, so the earliest pair W2 and W4 is mixed from both crews
+	setTestDB(map[string]task{"P1.T1": newTestTask(2, 1, "W1"), "P1.T2": newTestTask(2, 1, "W3"), "P1.T3": crewTask}, map[string]worker{"W1": {}, "W2": {}, "W3": {}, "W4": {}})
+	for _, assignmentStrategy = range []string{bestFitStrategy, firstAvailableStrategy} {
+		assignees := findTestTask(scheduleTestTasks("P1.T1", "P1.T2", "P1.T3"), "P1.T3").assignees
+		sort.Strings(assignees)
+		if !reflect.DeepEqual(assignees, []string{"W1", "W2"}) && !reflect.DeepEqual(assignees, []string{"W3", "W4"}) {
+			t.Errorf("Strategy %v crew task assignees = %v, expected one of the crews", assignmentStrategy, assignees)
+		}
+	}
+}
+
+func TestLoadConfigRoundTrip(t *testing.T) {
+	defaults := currentTuningConfig()
+	defer chdirTestFiles(t, map[string]string{"config.json": `{"populationSize": 50, "crossoverRate": 0.5, "weightDemand": 3}`})()
+	config := loadConfig("config.json")
+	expected := defaults
+	expected.PopulationSize = 50
+	expected.CrossoverRate = 0.5
+	expected.WeightDemand = 3
+	//Unspecified fields keep the default values
+	if config != expected {
+		t.Fatalf("Loaded config = %+v, expected %+v", config, expected)
+	}
+	configData, err := json.Marshal(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile("config.json", configData, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if reloadedConfig := loadConfig("config.json"); reloadedConfig != config {
+		t.Errorf("Reloaded config = %+v, expected %+v", reloadedConfig, config)
+	}
+}
+
+func TestLoadConfigYAML(t *testing.T) {
+	defaults := currentTuningConfig()
+	defer chdirTestFiles(t, map[string]string{"config.yaml": "populationSize: 50\ncrossoverMethod: pmx\nweightProjectContinuity: 2.5\nsynchronizeCrew: true\n"})()
+	expected := defaults
+	expected.PopulationSize = 50
+	expected.CrossoverMethod = "pmx"
+	expected.WeightProjectContinuity = 2.5
+	expected.SynchronizeCrew = true
+	//Unspecified fields keep the default values
+	if config := loadConfig("config.yaml"); config != expected {
+		t.Errorf("Loaded config = %+v, expected %+v", config, expected)
+	}
+}
+
+func TestTuningConfigCoversFlags(t *testing.T) {
+	defer currentTuningConfig().apply()
+	defer func(args []string) { os.Args = args }(os.Args)
+	defer func(commandLine *flag.FlagSet) { flag.CommandLine = commandLine }(flag.CommandLine)
+	flag.CommandLine = flag.NewFlagSet("sambo", flag.ExitOnError)
+	os.Args = []string{"sambo"}
+	parseFlags()
+	//Run, input, output and report flags are not the tuning parameters
+	notTuning := make(map[string]struct{})
+	for _, name := range []string{"config", "seed", "threads", "strict-rates", "montecarlo", "now", "baseline", "gmaps", "gmaps-concurrency", "gmaps-qps",
+		"utilization", "by-project", "dispatch", "weekly", "timeline", "fitness-breakdown", "unlimited-workers", "neighbors", "bottlenecks", "diversity", "relax",
+		"reshuffle-log", "jsonl", "json", "out", "ics", "datetime-format", "export-tasks", "trace"} {
+		notTuning[name] = struct{}{}
+	}
+	flag.VisitAll(func(f *flag.Flag) {
+		if _, ok := notTuning[f.Name]; ok {
+			return
+		}
+		//Any new value of the tuning flag should change the config
+		oldConfig := currentTuningConfig()
+		value := "7"
+		if boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && boolFlag.IsBoolFlag() {
+			value = strconv.FormatBool(f.Value.String() != "true")
+		} else if f.Value.String() == value {
+			value = "8"
+		}
+		if err := f.Value.Set(value); err != nil {
+			t.Fatalf("Couldn't set -%v=%v: %v", f.Name, value, err)
+		}
+		if currentTuningConfig() == oldConfig {
+			t.Errorf("Flag -%v has no tuning config field", f.Name)
+		}
+		oldConfig.apply()
+	})
+}
+
+func TestFitnessBreakdownSum(t *testing.T) {
+	defer currentTuningConfig().apply()
+	defer func(continuity, idle, overtime, peakOvertime float32) {
+		weightProjectContinuity, weightProjectIdle, weightOvertime, weightPeakOvertime = continuity, idle, overtime, peakOvertime
+	}(weightProjectContinuity, weightProjectIdle, weightOvertime, weightPeakOvertime)
+	weightMakespan, weightDrivingHours, weightDelayHours = 2, 3, 0.5
+	weightProjectContinuity, weightProjectIdle, weightOvertime, weightPeakOvertime = 4, 1, 1.5, 2
+
+	optionalTask := newTestTask(2, 1, "W2")
+	optionalTask.optionalReward = 5
+	secondProjectTask := newTestTask(4, 1, "W1")
+	secondProjectTask.project = "P2"
+	//P1.T2 has no valid workers, so it's unscheduled
+	setTestDB(map[string]task{"P1.T1": newTestTask(9, 1, "W1"), "P1.T2": newTestTask(1, 1), "P1.T3": optionalTask, "P2.T1": secondProjectTask}, map[string]worker{"W1": {}, "W2": {}})
+	overtimeSite := newTestSite()
+	overtimeSite.MaxOvertimeHours = 2
+	overtimeSite.OvertimeMultiplier = 1.5
+	projectsDB = map[string]project{"P1": {site: overtimeSite}, "P2": {site: newTestSite()}}
+	drivingTimeProvider = testRouteProvider(0.5)
+
+	individual := scheduleTestTasks("P1.T1", "P1.T2", "P1.T3", "P2.T1")
+	breakdown := individual.fitnessData
+	sum := weightMakespan*breakdown.makespan + breakdown.unscheduledPenalty - breakdown.optionalReward + breakdown.projectSwitches + breakdown.projectIdle + breakdown.overtime + breakdown.peakOvertime + breakdown.driving + breakdown.delay
+	if !almostEqual(sum, individual.fitness) {
+		t.Errorf("Fitness breakdown %+v sum = %v, expected fitness %v", breakdown, sum, individual.fitness)
+	}
+	if breakdown.makespan == 0 || breakdown.unscheduledPenalty == 0 || breakdown.optionalReward == 0 || breakdown.driving == 0 {
+		t.Errorf("Fitness breakdown = %+v, expected makespan, unscheduled penalty, optional reward and driving components", breakdown)
+	}
+}
+
+func TestWorkerCountRange(t *testing.T) {
+	tests := []struct {
+		name              string
+		validWorkers      []string
+		busyWorker        string
+		expectedAssignees int
+	}{
+		{"single valid worker", []string{"W1"}, "", 1},
+		{"all workers free", []string{"W1", "W2", "W3"}, "", 3},
+		{"one worker busy", []string{"W1", "W2", "W3"}, "W3", 2},
+		{"no valid workers", nil, "", 0},
+	}
+	for _, test := range tests {
+		rangeTask := newTestTask(6, 2, test.validWorkers...)
+		rangeTask.minWorkerCount, rangeTask.maxWorkerCount = 1, 3
+		tasks := map[string]task{"P1.T2": rangeTask}
+		taskIDs := []string{"P1.T2"}
+		//Busy worker can't arrive before the range task start
+		if test.busyWorker != "" {
+			tasks["P1.T1"] = newTestTask(4, 1, test.busyWorker)
+			taskIDs = []string{"P1.T1", "P1.T2"}
+		}
+		setTestDB(tasks, map[string]worker{"W1": {}, "W2": {}, "W3": {}})
+		individual := scheduleTestTasks(taskIDs...)
+		if assignees := findTestTask(individual, "P1.T2").assignees; len(assignees) != test.expectedAssignees {
+			t.Errorf("Task with %v assignees = %v, expected %v workers", test.name, assignees, test.expectedAssignees)
+		}
+		//Only the task below minWorkerCount is unscheduled
+		expectedUnscheduled := 0
+		if test.expectedAssignees == 0 {
+			expectedUnscheduled = 1
+		}
+		if individual.fitnessData.unscheduledTasks != expectedUnscheduled {
+			t.Errorf("Task with %v unscheduled tasks = %v, expected %v", test.name, individual.fitnessData.unscheduledTasks, expectedUnscheduled)
+		}
+	}
+}
+
+func TestCalculateWorkersDemand(t *testing.T) {
+	setTestDB(map[string]task{"P1.T1": newTestTask(1, 1, "W1", "W2"), "P1.T2": newTestTask(1, 1, "W1"), "P1.T3": newTestTask(1, 1, "W1"), "P1.T4": newTestTask(1, 1, "W2")}, map[string]worker{"W1": {}, "W2": {}, "W3": {}})
+	//Demand is the share of the tasks the worker is valid for
+	expected := map[string]float32{"W1": 0.75, "W2": 0.5, "W3": 0}
+	var previousDemand map[string]float32
+	for i := 0; i < 2; i++ {
+		demand := make(map[string]float32)
+		for workerID, worker := range calculateWorkersDemand() {
+			demand[workerID] = worker.demand
+		}
+		if !reflect.DeepEqual(demand, expected) {
+			t.Errorf("Run %v demand = %v, expected %v", i, demand, expected)
+		}
+		if previousDemand != nil && !reflect.DeepEqual(demand, previousDemand) {
+			t.Errorf("Run %v demand = %v, previous run %v", i, demand, previousDemand)
+		}
+		previousDemand = demand
+	}
+}
+
+func TestFindPrerequisiteCycles(t *testing.T) {
+	//Tasks with the prerequisites, key is the task ID
+	newTasks := func(prerequisites map[string][]string) map[string]task {
+		tasks := make(map[string]task)
+		for taskID, prerequisiteIDs := range prerequisites {
+			newTask := newTestTask(1, 1)
+			newTask.prerequisites = make(map[string]float32)
+			for _, prerequisiteID := range prerequisiteIDs {
+				newTask.prerequisites[prerequisiteID] = 0
+			}
+			tasks[taskID] = newTask
+		}
+		return tasks
+	}
+	tests := []struct {
+		name          string
+		prerequisites map[string][]string
+		expected      [][]string
+	}{
+		{"self-loop", map[string][]string{"P1.T1": {"P1.T1"}, "P1.T2": nil}, [][]string{{"P1.T1", "P1.T1"}}},
+		{"two-node cycle", map[string][]string{"P1.T1": {"P1.T2"}, "P1.T2": {"P1.T1"}, "P1.T3": {"P1.T1"}}, [][]string{{"P1.T1", "P1.T2", "P1.T1"}}},
+		{"DAG", map[string][]string{"P1.T1": nil, "P1.T2": {"P1.T1"}, "P1.T3": {"P1.T1", "P1.T2"}}, nil},
+	}
+	for _, test := range tests {
+		setTestDB(newTasks(test.prerequisites), nil)
+		if cycles := findPrerequisiteCycles(); !reflect.DeepEqual(cycles, test.expected) {
+			t.Errorf("Cycles of %v = %v, expected %v", test.name, cycles, test.expected)
+		}
+	}
+}
+
+func TestSampleSwapNeighbors(t *testing.T) {
+	for tasksNumber := 1; tasksNumber <= 5; tasksNumber++ {
+		setTestDBIndependentTasks(tasksNumber)
+		var taskIDs []string
+		for i := 1; i <= tasksNumber; i++ {
+			taskIDs = append(taskIDs, "P1.T"+strconv.Itoa(i))
+		}
+		//Every pair of the tasks is swapped once
+		if fitnesses := sampleSwapNeighbors(scheduleTestTasks(taskIDs...)); len(fitnesses) != tasksNumber*(tasksNumber-1)/2 {
+			t.Errorf("%v tasks neighbors = %v, expected %v", tasksNumber, len(fitnesses), tasksNumber*(tasksNumber-1)/2)
+		}
+	}
+}
+
+func TestVerifyPinnedWorkers(t *testing.T) {
+	defer func(add bool) { addPinnedWorkers = add }(addPinnedWorkers)
+	tests := []struct {
+		name          string
+		pinnedWorker  string
+		add           bool
+		expectedValid bool
+	}{
+		{"valid pinned worker", "W1", false, true},
+		{"invalid pinned worker", "W2", false, false},
+		{"added pinned worker", "W2", true, true},
+	}
+	for _, test := range tests {
+		pinnedTask := newTestTask(4, 1, "W1")
+		pinnedTask.pinnedWorkerIDs = map[string]struct{}{test.pinnedWorker: {}}
+		pinnedTask.pinMode = pinOnlyMode
+		setTestDB(map[string]task{"P1.T1": pinnedTask}, map[string]worker{"W1": {}, "W2": {}})
+		addPinnedWorkers = test.add
+		if valid := verifyPinnedWorkers(); valid != test.expectedValid {
+			t.Errorf("Task with %v verified = %v, expected %v", test.name, valid, test.expectedValid)
+			continue
+		}
+		//Valid configuration is scheduled with the pinned worker
+		if test.expectedValid {
+			if assignees := findTestTask(scheduleTestTasks("P1.T1"), "P1.T1").assignees; !reflect.DeepEqual(assignees, []string{test.pinnedWorker}) {
+				t.Errorf("Task with %v assignees = %v, expected %v", test.name, assignees, test.pinnedWorker)
+			}
+		}
+	}
+}
+
+func TestNoInterruptionProject(t *testing.T) {
+	secondProjectTask := newTestTask(4, 1, "W1")
+	secondProjectTask.project = "P2"
+	setTestDB(map[string]task{"P1.T1": newTestTask(4, 1, "W1"), "P1.T2": newTestTask(4, 1, "W1"), "P2.T1": secondProjectTask}, map[string]worker{"W1": {}})
+	orders := [][]string{
+		{"P1.T1", "P1.T2", "P2.T1"},
+		{"P1.T1", "P2.T1", "P1.T2"},
+		{"P1.T2", "P1.T1", "P2.T1"},
+		{"P1.T2", "P2.T1", "P1.T1"},
+		{"P2.T1", "P1.T1", "P1.T2"},
+		{"P2.T1", "P1.T2", "P1.T1"},
+	}
+	for _, noInterruption := range []bool{false, true} {
+		projectsDB = map[string]project{"P1": {site: newTestSite(), noInterruption: noInterruption}, "P2": {site: newTestSite()}}
+		//P1 is interrupted if the P2 task is between the P1 tasks
+		bestFitness := float32(math.MaxFloat32)
+		var bestInterrupted []bool
+		for _, order := range orders {
+			individual := scheduleTestTasks(order...)
+			firstTask, secondTask, otherProjectTask := findTestTask(individual, "P1.T1"), findTestTask(individual, "P1.T2"), findTestTask(individual, "P2.T1")
+			interrupted := otherProjectTask.startTime.After(firstTask.startTime) != otherProjectTask.startTime.After(secondTask.startTime)
+			if individual.fitness < bestFitness {
+				bestFitness = individual.fitness
+				bestInterrupted = nil
+			}
+			if individual.fitness == bestFitness {
+				bestInterrupted = append(bestInterrupted, interrupted)
+			}
+		}
+		hasInterrupted := false
+		for _, interrupted := range bestInterrupted {
+			hasInterrupted = hasInterrupted || interrupted
+		}
+		//Without the flag the interrupted schedule is as good as the continuous one
+		if hasInterrupted == noInterruption {
+			t.Errorf("No interruption %v best schedules interrupted = %v", noInterruption, bestInterrupted)
+		}
+	}
+}
+
+func TestPinModes(t *testing.T) {
+	tests := []struct {
+		pinMode       string
+		pinnedWorkers []string
+		expected      [][]string
+	}{
+		{pinOnlyMode, []string{"W1", "W2"}, [][]string{{"W1", "W2"}}},
+		{pinAtLeastOneMode, []string{"W1"}, [][]string{{"W1", "W2"}, {"W1", "W3"}}},
+	}
+	for _, test := range tests {
+		pinnedTask := newTestTask(4, 2, "W1", "W2", "W3")
+		pinnedTask.pinMode = test.pinMode
+		pinnedTask.pinnedWorkerIDs = make(map[string]struct{})
+		for _, workerID := range test.pinnedWorkers {
+			pinnedTask.pinnedWorkerIDs[workerID] = struct{}{}
+		}
+		//W1 is busy, so it's the worst candidate without the pinning
+		setTestDB(map[string]task{"P1.T1": newTestTask(2, 1, "W1"), "P1.T2": pinnedTask}, map[string]worker{"W1": {}, "W2": {}, "W3": {}})
+		assignees := findTestTask(scheduleTestTasks("P1.T1", "P1.T2"), "P1.T2").assignees
+		sort.Strings(assignees)
+		found := false
+		for _, expected := range test.expected {
+			found = found || reflect.DeepEqual(assignees, expected)
+		}
+		if !found {
+			t.Errorf("Pin mode %v assignees = %v, expected one of %v", test.pinMode, assignees, test.expected)
+		}
+	}
+}
+
+func TestOutputDateTimeFormat(t *testing.T) {
+	defer func(layout string) { outputDateTimeFormat = layout }(outputDateTimeFormat)
+	defer func(testLogger *log.Logger) { logger = testLogger }(logger)
+	setTestDB(map[string]task{"P1.T1": newTestTask(4, 1, "W1")}, map[string]worker{"W1": {}})
+	individual := scheduleTestTasks("P1.T1")
+	//Start time of the task in every output
+	outputStartTimes := func() []string {
+		dir := t.TempDir()
+		logFile, err := ioutil.TempFile(dir, "log")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer logFile.Close()
+		logger = log.New(logFile)
+		prettyPrintTask(individual.tasks[0])
+		writeScheduleCSV(filepath.Join(dir, "schedule.csv"), individual)
+		writeScheduleJSONL(filepath.Join(dir, "schedule.jsonl"), individual)
+		writeScheduleJSON(filepath.Join(dir, "schedule.json"), individual)
+
+		var startTimes []string
+		output, err := ioutil.ReadFile(logFile.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		startTimes = append(startTimes, strings.Split(string(output), ";")[1])
+		scheduleCSV, err := ioutil.ReadFile(filepath.Join(dir, "schedule.csv"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		records, err := csv.NewReader(bytes.NewReader(scheduleCSV)).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		startTimes = append(startTimes, records[1][0])
+		scheduleLines, err := ioutil.ReadFile(filepath.Join(dir, "schedule.jsonl"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var taskJSON scheduledTaskJSON
+		if err := json.Unmarshal(scheduleLines, &taskJSON); err != nil {
+			t.Fatal(err)
+		}
+		startTimes = append(startTimes, taskJSON.Start)
+		scheduleDocument, err := ioutil.ReadFile(filepath.Join(dir, "schedule.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var schedule scheduleJSON
+		if err := json.Unmarshal(scheduleDocument, &schedule); err != nil {
+			t.Fatal(err)
+		}
+		return append(startTimes, schedule.Tasks[0].Start)
+	}
+
+	outputDateTimeFormat = ""
+	startTime := testDateTime(21, 8)
+	expected := []string{startTime.Format(prettyDateTimeFormat), startTime.Format(defaultDateTimeFormat), startTime.Format(time.RFC3339), startTime.Format(time.RFC3339)}
+	if startTimes := outputStartTimes(); !reflect.DeepEqual(startTimes, expected) {
+		t.Errorf("Default layout start times = %q, expected %q", startTimes, expected)
+	}
+	//Configured layout replaces the default layouts of all outputs
+	outputDateTimeFormat = "02.01.2006 15:04"
+	expected = []string{"21.12.2020 08:00", "21.12.2020 08:00", "21.12.2020 08:00", "21.12.2020 08:00"}
+	if startTimes := outputStartTimes(); !reflect.DeepEqual(startTimes, expected) {
+		t.Errorf("Configured layout start times = %q, expected %q", startTimes, expected)
+	}
+}
+
+func TestExportICS(t *testing.T) {
+	longTask := newTestTask(4, 1, "W1")
+	longTask.name = strings.Repeat("Concrete, formwork; rebar ", 5)
+	//P1.T3 has no valid workers, so it's not exported
+	setTestDB(map[string]task{"P1.T1": newTestTask(2, 1, "W1"), "P1.T2": longTask, "P1.T3": newTestTask(1, 1)}, map[string]worker{"W1": {name: "Ann"}})
+	individual := scheduleTestTasks("P1.T1", "P1.T2", "P1.T3")
+	fileName := filepath.Join(t.TempDir(), "schedule.ics")
+	exportICS(fileName, individual)
+	icsData, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(string(icsData), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("Line %q is not folded", line)
+		}
+	}
+	//Unfold the continuation lines and collect the event start times
+	var startTimes []string
+	events := 0
+	for _, line := range strings.Split(strings.ReplaceAll(string(icsData), "\r\n ", ""), "\r\n") {
+		if line == "BEGIN:VEVENT" {
+			events++
+		}
+		if strings.HasPrefix(line, "DTSTART:") {
+			startTimes = append(startTimes, strings.TrimPrefix(line, "DTSTART:"))
+		}
+	}
+	expected := []string{findTestTask(individual, "P1.T1").startTime.Format(icsDateTimeFormat), findTestTask(individual, "P1.T2").startTime.Format(icsDateTimeFormat)}
+	if events != 2 || !reflect.DeepEqual(startTimes, expected) {
+		t.Errorf("Events = %v with start times %v, expected 2 scheduled tasks starting at %v", events, startTimes, expected)
+	}
+}
+
+func TestRecurringTask(t *testing.T) {
+	recurringTask := newTestTask(4, 1, "W1")
+	recurringTask.recurrenceDays = 7
+	recurringTask.recurrenceCount = 4
+	setTestDB(map[string]task{"P1.T1": recurringTask}, map[string]worker{"W1": {}})
+	tasksDB = expandRecurringTasks(tasksDB)
+	var taskIDs []string
+	for taskID := range tasksDB {
+		taskIDs = append(taskIDs, taskID)
+	}
+	sort.Strings(taskIDs)
+	if len(taskIDs) != 4 {
+		t.Fatalf("Recurring task instances = %v, expected 4", taskIDs)
+	}
+	//Every instance starts at the beginning of its week
+	individual := scheduleTestTasks(taskIDs...)
+	var startTimes []time.Time
+	for _, task := range individual.tasks {
+		if !isTaskScheduled(task) {
+			t.Errorf("Instance %v is not scheduled", task.taskID)
+		}
+		startTimes = append(startTimes, task.startTime)
+	}
+	sort.Slice(startTimes, func(i, j int) bool { return startTimes[i].Before(startTimes[j]) })
+	for i, startTime := range startTimes {
+		if expected := testDateTime(21, 8).AddDate(0, 0, 7*i); !startTime.Equal(expected) {
+			t.Errorf("Instance %v start = %v, expected %v", i+1, startTime, expected)
+		}
+	}
+}
+
+func TestWriteScheduleJSONSummary(t *testing.T) {
+	//P1.T3 has no valid workers, so it's unscheduled
+	setTestDB(map[string]task{"P1.T1": newTestTask(8, 1, "W1"), "P1.T2": newTestTask(4, 1, "W1"), "P1.T3": newTestTask(1, 1)}, map[string]worker{"W1": {name: "Ann"}})
+	individual := scheduleTestTasks("P1.T1", "P1.T2", "P1.T3")
+	fileName := filepath.Join(t.TempDir(), "schedule.json")
+	writeScheduleJSON(fileName, individual)
+	scheduleDocument, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//Mirror of the documented JSON structure, independent from the exporter types
+	var schedule struct {
+		Summary struct {
+			Fitness          float32 `json:"fitness"`
+			UnscheduledTasks int     `json:"unscheduledTasks"`
+			FinishDateTime   string  `json:"finishDateTime"`
+		} `json:"summary"`
+		Tasks []struct {
+			TaskID        string   `json:"taskID"`
+			ProjectID     string   `json:"projectID"`
+			Start         string   `json:"start"`
+			Stop          string   `json:"stop"`
+			AssigneeIDs   []string `json:"assigneeIDs"`
+			AssigneeNames []string `json:"assigneeNames"`
+		} `json:"tasks"`
+	}
+	if err := json.Unmarshal(scheduleDocument, &schedule); err != nil {
+		t.Fatal(err)
+	}
+	//W1 works 12 hours, so the last task finishes on Tuesday at 12:00
+	if schedule.Summary.Fitness != individual.fitness || schedule.Summary.UnscheduledTasks != 1 || schedule.Summary.FinishDateTime != testDateTime(22, 12).Format(time.RFC3339) {
+		t.Errorf("Summary = %+v, expected fitness %v, 1 unscheduled task and finish at %v", schedule.Summary, individual.fitness, testDateTime(22, 12).Format(time.RFC3339))
+	}
+	if len(schedule.Tasks) != 3 || schedule.Tasks[0].TaskID != "T1" || schedule.Tasks[0].ProjectID != "P1" || !reflect.DeepEqual(schedule.Tasks[0].AssigneeNames, []string{"Ann"}) || len(schedule.Tasks[2].AssigneeIDs) != 0 {
+		t.Errorf("Tasks = %+v, expected 3 tasks with the unscheduled task last", schedule.Tasks)
+	}
+}
+
+func TestBottleneckWorkers(t *testing.T) {
+	//Only W1 can do the three long tasks
+	setTestDB(map[string]task{
+		"P1.T1": newTestTask(8, 1, "W1"),
+		"P1.T2": newTestTask(8, 1, "W1"),
+		"P1.T3": newTestTask(8, 1, "W1"),
+		"P1.T4": newTestTask(4, 1, "W2"),
+		"P1.T5": newTestTask(4, 1, "W3"),
+	}, map[string]worker{"W1": {}, "W2": {}, "W3": {}})
+	bottlenecks := calculateBottleneckWorkers(scheduleTestTasks("P1.T1", "P1.T4", "P1.T2", "P1.T5", "P1.T3"))
+	if len(bottlenecks) != 3 {
+		t.Fatalf("Bottlenecks = %+v, expected all 3 workers", bottlenecks)
+	}
+	if bottlenecks[0].workerID != "W1" || bottlenecks[0].criticalTasks != 3 {
+		t.Errorf("Top bottleneck = %+v, expected W1 with 3 critical tasks", bottlenecks[0])
+	}
+	for _, bottleneck := range bottlenecks[1:] {
+		if bottleneck.criticalTasks != 0 || bottleneck.utilization >= bottlenecks[0].utilization {
+			t.Errorf("Bottleneck %+v, expected no critical tasks and lower utilization than W1", bottleneck)
+		}
+	}
+}
+
+func TestFinishDateTime(t *testing.T) {
+	setTestDB(map[string]task{"P1.T1": newTestTask(4, 1, "W1"), "P1.T2": newTestTask(2, 1, "W2")}, map[string]worker{"W1": {}, "W2": {}})
+	individual := scheduleTestTasks("P1.T1", "P1.T2")
+	if !individual.fitnessData.finishDateTime.Equal(testDateTime(21, 12)) || individual.fitnessData.unscheduledTasks != 0 {
+		t.Errorf("Finish datetime = %v, unscheduled tasks = %v, expected %v and 0", individual.fitnessData.finishDateTime, individual.fitnessData.unscheduledTasks, testDateTime(21, 12))
+	}
+	//Tasks without valid workers are never scheduled
+	setTestDB(map[string]task{"P1.T1": newTestTask(4, 1), "P1.T2": newTestTask(2, 1)}, map[string]worker{"W1": {}, "W2": {}})
+	individual = scheduleTestTasks("P1.T1", "P1.T2")
+	if !individual.fitnessData.finishDateTime.IsZero() || individual.fitnessData.unscheduledTasks != 2 {
+		t.Errorf("Unscheduled finish datetime = %v, unscheduled tasks = %v, expected zero time and 2", individual.fitnessData.finishDateTime, individual.fitnessData.unscheduledTasks)
+	}
+}
+
+func TestRunGAEarlyStop(t *testing.T) {
+	defer currentTuningConfig().apply()
+	defer func(reshuffle bool) { reshuffleStagnation = reshuffle }(reshuffleStagnation)
+	reshuffleStagnation = false
+	//All schedules of the equal independent tasks have the same fitness, so the best fitness never improves
+	setTestDBIndependentTasks(4)
+	populationSize = 5
+	pop, state := runGA(generatePopulation(), gaState{}, 100, 5, 0.01, nil)
+	if state.generationsNumber != 6 || !state.converged {
+		t.Errorf("Stagnation limit 5 stopped after %v generations, converged = %v, expected 6 generations", state.generationsNumber, state.converged)
+	}
+	//Continued run keeps the convergence state, so it stops after the first generation
+	if _, state = runGA(pop, state, 100, 5, 0.01, nil); state.generationsNumber != 7 || !state.converged {
+		t.Errorf("Continued run stopped after %v generations, converged = %v, expected 7 generations", state.generationsNumber, state.converged)
+	}
+	//Zero stagnation limit runs all generations
+	if _, state = runGA(generatePopulation(), gaState{}, 10, 0, 0.01, nil); state.generationsNumber != 10 || state.converged {
+		t.Errorf("Zero stagnation limit stopped after %v generations, converged = %v, expected 10 generations", state.generationsNumber, state.converged)
+	}
+}
+
+func TestUnscheduledPartialCredit(t *testing.T) {
+	//Penalty of the two-worker task with the given valid workers
+	schedule := func(validWorkers ...string) individual {
+		setTestDB(map[string]task{"P1.T1": newTestTask(4, 2, validWorkers...)}, map[string]worker{"W1": {}, "W2": {}})
+		return scheduleTestTasks("P1.T1")
+	}
+	oneShort := schedule("W1")
+	unstaffed := schedule()
+	if unstaffed.fitnessData.unscheduledPenalty != deadend {
+		t.Errorf("Unstaffed task penalty = %v, expected full penalty %v", unstaffed.fitnessData.unscheduledPenalty, deadend)
+	}
+	if oneShort.fitnessData.unscheduledPenalty != deadend/2 || oneShort.fitness >= unstaffed.fitness {
+		t.Errorf("One worker short task penalty = %v, fitness = %v, expected half penalty %v and better fitness than %v", oneShort.fitnessData.unscheduledPenalty, oneShort.fitness, deadend/2, unstaffed.fitness)
+	}
+}
+
+func TestZeroPinnedDateTimeSnap(t *testing.T) {
+	defer currentTuningConfig().apply()
+	pinnedDateTimeSnap = 0
+	pinnedTask := newTestTask(4, 1, "W1")
+	pinnedTask.pinnedDateTime = testDateTime(21, 8)
+	for _, test := range []struct {
+		arrival  time.Time
+		expected int
+	}{
+		{testDateTime(21, 8), 1},
+		{testDateTime(21, 8).Add(time.Minute), 0},
+	} {
+		//Worker is available at the schedule start
+		setTestDB(map[string]task{"P1.T1": pinnedTask}, map[string]worker{"W1": {}})
+		scheduleStartTime = test.arrival
+		if assignees := findTestTask(scheduleTestTasks("P1.T1"), "P1.T1").assignees; len(assignees) != test.expected {
+			t.Errorf("Worker arriving at %v assignees = %v, expected %v workers", test.arrival, assignees, test.expected)
+		}
+	}
+}
+
+func TestSameSeedReproducible(t *testing.T) {
+	defer currentTuningConfig().apply()
+	setTestDB(map[string]task{
+		"P1.T1": newTestTask(4, 1, "W1", "W2"),
+		"P1.T2": newTestTask(8, 1, "W1"),
+		"P1.T3": newTestTask(2, 2, "W1", "W2"),
+		"P1.T4": newTestTask(6, 1, "W2"),
+		"P1.T5": newTestTask(3, 1, "W1", "W2"),
+	}, map[string]worker{"W1": {}, "W2": {}})
+	populationSize = 8
+	//Hashes of the final population sorted by fitness
+	runHashes := func() []uint64 {
+		rand.Seed(42)
+		population, _ := runGA(generatePopulation(), gaState{}, 5, 0, 0, nil)
+		var hashes []uint64
+		for _, individual := range population.individuals {
+			hashes = append(hashes, calcIndividualHash(individual))
+		}
+		return hashes
+	}
+	if firstHashes, secondHashes := runHashes(), runHashes(); !reflect.DeepEqual(firstHashes, secondHashes) {
+		t.Errorf("Runs with the same seed have different individuals:\n%v\n%v", firstHashes, secondHashes)
+	}
+}
+
+func TestReadCSVQuotedUnicodeNames(t *testing.T) {
+	defer chdirTestFiles(t, map[string]string{
+		workersDBFileName:  "\ufeffname,id,latitude,longitude\n\"O'Brien, Jr.\",W1,49.25,-123.10\n",
+		projectsDBFileName: "\ufeffid,name,latitude,longitude,target_start,target_end,daily_start,daily_end\nP1,\"Café Zürich, Phase 2\",49.28,-123.12,2020-12-21,2021-01-31,08:00,16:00\n",
+	})()
+	workers, err := readWorkerInfoCSV()
+	if err != nil {
+		t.Fatal(err)
+	}
+	projects, err := readProjectInfoCSV()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if workers["W1"].name != "O'Brien, Jr." {
+		t.Errorf("Worker name = %q, expected %q", workers["W1"].name, "O'Brien, Jr.")
+	}
+	if projects["P1"].name != "Café Zürich, Phase 2" {
+		t.Errorf("Project name = %q, expected %q", projects["P1"].name, "Café Zürich, Phase 2")
+	}
+
+	//Names are preserved in the exported schedule
+	setTestDB(map[string]task{"P1.T1": newTestTask(4, 1, "W1")}, workers)
+	projectsDB = map[string]project{"P1": {name: projects["P1"].name, site: newTestSite()}}
+	writeScheduleCSV("schedule.csv", scheduleTestTasks("P1.T1"))
+	scheduleFile, err := os.Open("schedule.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer scheduleFile.Close()
+	records, err := csv.NewReader(scheduleFile).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if projectName, workerNames := records[1][2], records[1][4]; projectName != "Café Zürich, Phase 2" || workerNames != "O'Brien, Jr." {
+		t.Errorf("Exported project name = %q, worker names = %q", projectName, workerNames)
+	}
+}
+
+func TestReadProjectInfoCSVNegativeValues(t *testing.T) {
+	defer func(allow bool) { allowOvertime = allow }(allowOvertime)
+	allowOvertime = true
+	header := "id,name,latitude,longitude,target_start,target_end,daily_start,daily_end,max_overtime_hours,driving_speed\n"
+	tests := []struct {
+		record   string
+		expected string
+	}{
+		{"P1,Deck,49.28,-123.12,2020-12-21,2021-01-31,08:00,16:00,-2,\n", "negative max_overtime_hours -2"},
+		{"P1,Deck,49.28,-123.12,2020-12-21,2021-01-31,08:00,16:00,,-60\n", "negative driving_speed -60"},
+	}
+	for _, test := range tests {
+		restore := chdirTestFiles(t, map[string]string{projectsDBFileName: header + test.record})
+		_, err := readProjectInfoCSV()
+		restore()
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("Record %q error = %v, expected %q", test.record, err, test.expected)
+		}
+	}
+}
+
+func TestWorkerTimelines(t *testing.T) {
+	secondProjectTask := newTestTask(2, 1, "W1")
+	secondProjectTask.project = "P2"
+	setTestDB(map[string]task{"P1.T1": newTestTask(3, 1, "W1"), "P2.T1": secondProjectTask}, map[string]worker{"W1": {}, "W2": {}})
+	projectsDB["P2"] = project{site: newTestSite()}
+	drivingTimeProvider = testRouteProvider(1)
+	individual := individual{tasks: []scheduledTask{
+		{taskID: "P1.T1", startTime: testDateTime(21, 9), stopTime: testDateTime(21, 12), assignees: []string{"W1"}},
+		{taskID: "P2.T1", startTime: testDateTime(21, 14), stopTime: testDateTime(21, 16), assignees: []string{"W1"}},
+	}}
+	days, timelines := buildWorkerTimelines(individual)
+	if len(days) != 1 {
+		t.Fatalf("Timeline days = %v, expected Monday only", days)
+	}
+	expectedKinds := map[string][]string{
+		"W1": {travelBlock, taskBlock, idleBlock, travelBlock, taskBlock},
+		"W2": {idleBlock},
+	}
+	for workerID, expected := range expectedKinds {
+		blocks := timelines[days[0]][workerID]
+		var kinds []string
+		var hours float64
+		cursor := testDateTime(21, 8)
+		for _, block := range blocks {
+			kinds = append(kinds, block.kind)
+			hours += block.stopTime.Sub(block.startTime).Hours()
+			if !block.startTime.Equal(cursor) {
+				t.Errorf("Worker %v block %+v starts after the gap from %v", workerID, block, cursor)
+			}
+			cursor = block.stopTime
+		}
+		//Blocks cover the whole working day
+		if hours != 8 || !cursor.Equal(testDateTime(21, 16)) {
+			t.Errorf("Worker %v blocks cover %v hours until %v, expected 8 hours until 16:00", workerID, hours, cursor)
+		}
+		if !reflect.DeepEqual(kinds, expected) {
+			t.Errorf("Worker %v blocks = %v, expected %v", workerID, kinds, expected)
+		}
+	}
+}
+
+func TestGeneratePopulationSchedulesSmallPool(t *testing.T) {
+	defer currentTuningConfig().apply()
+	defer func(threads int) { threadsNum = threads }(threadsNum)
+	setTestDBIndependentTasks(4)
+	populationSize = 3
+	threadsNum = 256
+	individuals := generatePopulation().individuals
+	//Pool is limited to the 3 individuals, so the call returns instead of the deadlock
+	generatePopulationSchedules(individuals)
+	for i, individual := range individuals {
+		if individual.fitness == 0 {
+			t.Errorf("Individual %v fitness is not calculated", i)
+		}
+	}
+}
+
+func TestGeneratePopulationSchedulesLastIndividual(t *testing.T) {
+	defer currentTuningConfig().apply()
+	defer func(threads int) { threadsNum = threads }(threadsNum)
+	setTestDBIndependentTasks(4)
+	populationSize = 5
+	//One elite and batches of three threads leave the last individual alone in the final batch
+	threadsNum = 3
+	individuals := generatePopulation().individuals
+	generatePopulationSchedules(individuals)
+	for i, individual := range individuals {
+		if individual.fitness == 0 || !isTaskScheduled(individual.tasks[0]) {
+			t.Errorf("Individual %v of %v is not scheduled, fitness = %v", i, len(individuals), individual.fitness)
+		}
+	}
+}
+
+func TestAnalyzeConstraintRelaxations(t *testing.T) {
+	//Both tasks need W1 on Monday, the crew task also needs W2 who is on time off after Monday.
+	//Synchronized crew doesn't let W1 join the crew task after the solo task
+	defer func(synchronize bool) { synchronizeCrew = synchronize }(synchronizeCrew)
+	synchronizeCrew = true
+	soloTask := newTestTask(8, 1, "W1")
+	soloTask.windowEnd = testDateTime(21, 16)
+	crewTask := newTestTask(8, 2, "W1", "W2")
+	crewTask.windowEnd = testDateTime(21, 16)
+	setTestDB(map[string]task{"P1.T1": soloTask, "P1.T2": crewTask}, map[string]worker{
+		"W1": {},
+		"W2": {blockedRanges: []dateTimeRange{{startTime: testDateTime(22, 0), endTime: testDateTime(31, 0)}}},
+	})
+	rand.Seed(1)
+	individual := scheduleTestTasks("P1.T1", "P1.T2")
+	if individual.fitnessData.unscheduledTasks != 1 {
+		t.Fatalf("Unscheduled tasks = %v, expected infeasible schedule with 1 unscheduled task", individual.fitnessData.unscheduledTasks)
+	}
+	relaxations := analyzeConstraintRelaxations(individual)
+	if len(relaxations) != 3 {
+		t.Fatalf("Relaxations = %+v, expected 2 deadlines and 1 project overtime", relaxations)
+	}
+	//Only the solo task can be moved to Tuesday, the overtime is too short for both tasks on Monday
+	if best := relaxations[0]; best.category != deadlineRelaxation || best.id != "P1.T1" || best.unscheduledTasks != 0 {
+		t.Errorf("Best relaxation = %+v, expected P1.T1 deadline with no unscheduled tasks", best)
+	}
+	for _, relaxation := range relaxations[1:] {
+		if relaxation.unscheduledTasks == 0 {
+			t.Errorf("Relaxation %+v makes the schedule feasible, expected only P1.T1 deadline", relaxation)
+		}
+	}
+	if deadline := tasksDB["P1.T1"].windowEnd; !deadline.Equal(testDateTime(21, 16)) {
+		t.Errorf("Relaxed deadline %v is not restored", deadline)
+	}
+}
+
+func TestTransmogrifyPopulationSize(t *testing.T) {
+	defer currentTuningConfig().apply()
+	setTestDBIndependentTasks(6)
+	rand.Seed(1)
+	populationSize = 7
+	pop := generatePopulation()
+	generatePopulationSchedules(pop.individuals)
+	sortPopulation(pop.individuals)
+	//Generation size depends on the population only, not on the global population size
+	populationSize = 20
+	pop = transmogrifyPopulation(pop, mutationRate)
+	generatePopulationSchedules(pop.individuals)
+	if len(pop.individuals) != 7 {
+		t.Fatalf("New population size = %v, expected 7", len(pop.individuals))
+	}
+	for i, individual := range pop.individuals {
+		if individual.fitness == 0 {
+			t.Errorf("Individual %v fitness is not calculated", i)
+		}
+	}
+}
+
+func TestCrossoverPMXPermutation(t *testing.T) {
+	defer currentTuningConfig().apply()
+	crossoverRate = 1
+	maxCrossoverLength = 20
+	rand.Seed(1)
+	for i := 0; i < 200; i++ {
+		parents := []individual{newRandomTestIndividual(20), newRandomTestIndividual(20)}
+		for _, child := range crossoverIndividualsPMX(parents) {
+			childPositions := taskPositions(child)
+			if len(childPositions) != 20 {
+				t.Fatalf("Child %v is not a permutation of the parent tasks", child.tasks)
+			}
+			for taskID := range taskPositions(parents[0]) {
+				if _, ok := childPositions[taskID]; !ok {
+					t.Fatalf("Child %v has no task %v", child.tasks, taskID)
+				}
+			}
+		}
+	}
+}
+
+func TestRankSelectPrefersBest(t *testing.T) {
+	var individuals []individual
+	//Fitness is shuffled, so the selection depends on the rank and not on the position
+	for i, v := range rand.Perm(11) {
+		individuals = append(individuals, newTestIndividual(float32(v+1), "T"+strconv.Itoa(i)))
+	}
+	rand.Seed(1)
+	selections := make(map[float32]int)
+	for i := 0; i < 5000; i++ {
+		selections[rankSelect(individuals, 1)[0].fitness]++
+	}
+	//Best individual weight is 11 and median weight is 6 out of 66
+	if selections[1] <= selections[6] || selections[6] <= selections[11] {
+		t.Errorf("Best, median and worst individuals are selected %v, %v and %v times, expected fewer selections for the worse ranks", selections[1], selections[6], selections[11])
+	}
+}
+
+func TestTourneySelectShrinkingSample(t *testing.T) {
+	defer func(size int) { tourneySampleSize = size }(tourneySampleSize)
+	tourneySampleSize = 4
+	var individuals []individual
+	for i := 0; i < 6; i++ {
+		individuals = append(individuals, newTestIndividual(float32(i+1), "T"+strconv.Itoa(i)))
+	}
+	rand.Seed(1)
+	//Every selection removes the winner from the sample order, so it becomes shorter than the sample after 3 selections
+	selected := tourneySelect(individuals, len(individuals))
+	selectedFitness := make(map[float32]struct{})
+	for _, v := range selected {
+		selectedFitness[v.fitness] = struct{}{}
+	}
+	if len(selectedFitness) != len(individuals) {
+		t.Errorf("Selected fitness %v, expected every individual once before the sample order starts over", selectedFitness)
+	}
+}
+
+func TestRequiredTrades(t *testing.T) {
+	//W2 is busy with the first task, so the free W1 is a better fit for the second one without the trades
+	tradeTask := newTestTask(4, 1, "W1", "W2")
+	setTestDB(map[string]task{"P1.T1": newTestTask(4, 1, "W2"), "P1.T2": tradeTask}, map[string]worker{"W1": {trades: []string{"plumbing"}}, "W2": {trades: []string{"electrical"}}})
+	if task := findTestTask(scheduleTestTasks("P1.T1", "P1.T2"), "P1.T2"); !reflect.DeepEqual(task.assignees, []string{"W1"}) {
+		t.Fatalf("Task without trades is assigned to %v, expected the free W1", task.assignees)
+	}
+	tradeTask.trades = []string{"electrical"}
+	tasksDB["P1.T2"] = tradeTask
+	task := findTestTask(scheduleTestTasks("P1.T1", "P1.T2"), "P1.T2")
+	if !reflect.DeepEqual(task.assignees, []string{"W2"}) || !task.startTime.Equal(testDateTime(21, 12)) {
+		t.Errorf("Electrical task is assigned to %v at %v, expected W2 after the first task", task.assignees, task.startTime)
+	}
+}
+
+func TestWorkerTimeOff(t *testing.T) {
+	tests := []struct {
+		name              string
+		timeOff           dateTimeRange
+		expectedStartTime time.Time
+	}{
+		//Task straddling the time off is pushed after it as a whole
+		{"inside", dateTimeRange{startTime: testDateTime(21, 10), endTime: testDateTime(21, 12)}, testDateTime(21, 12)},
+		{"touching start", dateTimeRange{startTime: testDateTime(20, 8), endTime: testDateTime(21, 8)}, testDateTime(21, 8)},
+		{"touching stop", dateTimeRange{startTime: testDateTime(21, 16), endTime: testDateTime(22, 16)}, testDateTime(21, 8)},
+	}
+	for _, test := range tests {
+		setTestDB(map[string]task{"P1.T1": newTestTask(8, 1, "W1")}, map[string]worker{"W1": {blockedRanges: []dateTimeRange{test.timeOff}}})
+		task := findTestTask(scheduleTestTasks("P1.T1"), "P1.T1")
+		if !task.startTime.Equal(test.expectedStartTime) || !task.stopTime.Equal(newTestSite().AddHours(test.expectedStartTime, 8)) {
+			t.Errorf("Task with the time off %v is scheduled from %v to %v, expected start at %v", test.name, task.startTime, task.stopTime, test.expectedStartTime)
+		}
+	}
+}
+
+func TestWorkerDailyHours(t *testing.T) {
+	halfDayWorker := worker{dailyStartTime: time.Date(0, 1, 1, 8, 0, 0, 0, time.UTC), dailyEndTime: time.Date(0, 1, 1, 12, 0, 0, 0, time.UTC)}
+	tests := []struct {
+		name             string
+		worker           worker
+		expectedStopTime time.Time
+	}{
+		{"full-time", worker{}, testDateTime(21, 16)},
+		//4 hours per day, so the task continues on Tuesday morning
+		{"half-day", halfDayWorker, testDateTime(22, 12)},
+	}
+	for _, test := range tests {
+		setTestDB(map[string]task{"P1.T1": newTestTask(8, 1, "W1")}, map[string]worker{"W1": test.worker})
+		task := findTestTask(scheduleTestTasks("P1.T1"), "P1.T1")
+		if !task.startTime.Equal(testDateTime(21, 8)) || !task.stopTime.Equal(test.expectedStopTime) {
+			t.Errorf("Task of the %v worker is scheduled from %v to %v, expected from %v to %v", test.name, task.startTime, task.stopTime, testDateTime(21, 8), test.expectedStopTime)
+		}
+	}
+}
+
+func TestOvertime(t *testing.T) {
+	tests := []struct {
+		name             string
+		maxOvertimeHours float32
+		expectedStopTime time.Time
+		expectedOvertime float32
+	}{
+		{"allowed", 2, testDateTime(21, 17), 1},
+		{"not allowed", 0, testDateTime(22, 9), 0},
+		//Remaining work is longer than the overtime window
+		{"too short", 0.5, testDateTime(22, 9), 0},
+	}
+	for _, test := range tests {
+		setTestDB(map[string]task{"P1.T1": newTestTask(9, 1, "W1")}, map[string]worker{"W1": {}})
+		site := newTestSite()
+		site.MaxOvertimeHours = test.maxOvertimeHours
+		site.OvertimeMultiplier = 1.5
+		projectsDB["P1"] = project{site: site}
+		individual := scheduleTestTasks("P1.T1")
+		if task := findTestTask(individual, "P1.T1"); !task.stopTime.Equal(test.expectedStopTime) {
+			t.Errorf("Task with the overtime %v stops at %v, expected %v", test.name, task.stopTime, test.expectedStopTime)
+		}
+		if overtimeHours := individual.workers[0].overtimeHours; !almostEqual(overtimeHours, test.expectedOvertime) {
+			t.Errorf("Worker overtime with the overtime %v = %v hours, expected %v", test.name, overtimeHours, test.expectedOvertime)
+		}
+	}
+}
+
+func TestCalcProjectsFinishDateTime(t *testing.T) {
+	secondProjectTask := newTestTask(16, 1, "W2")
+	secondProjectTask.project = "P2"
+	//P1.T2 has no valid workers, so it doesn't finish the project
+	setTestDB(map[string]task{"P1.T1": newTestTask(4, 1, "W1"), "P1.T2": newTestTask(1, 1), "P2.T1": secondProjectTask}, map[string]worker{"W1": {}, "W2": {}})
+	projectsDB["P2"] = project{site: newTestSite()}
+	expected := map[string]time.Time{"P1": testDateTime(21, 12), "P2": testDateTime(22, 16)}
+	if finishDateTime := calcProjectsFinishDateTime(scheduleTestTasks("P1.T1", "P1.T2", "P2.T1")); !reflect.DeepEqual(finishDateTime, expected) {
+		t.Errorf("Projects finish datetime = %v, expected %v", finishDateTime, expected)
+	}
+}
+
+func TestLessDrivingWins(t *testing.T) {
+	defer currentTuningConfig().apply()
+	weightDrivingHours = 1
+	setTestDB(map[string]task{"P1.T1": newTestTask(8, 1, "W1"), "P1.T2": newTestTask(8, 1, "W2")}, map[string]worker{"W1": {}, "W2": {}})
+	//Same tasks and makespan, only the worker driving hours are different
+	newDrivingIndividual := func(drivingHours float32) individual {
+		individual := individual{tasks: []scheduledTask{
+			{taskID: "P1.T1", startTime: testDateTime(21, 8), stopTime: testDateTime(21, 16), assignees: []string{"W1"}},
+			{taskID: "P1.T2", startTime: testDateTime(21, 8), stopTime: testDateTime(21, 16), assignees: []string{"W2"}},
+		}}
+		for _, workerID := range []string{"W1", "W2"} {
+			individual.workers = append(individual.workers, scheduledWorker{workerID: workerID, drivingHours: drivingHours})
+		}
+		return calculateIndividualFitness(individual)
+	}
+	shortDriving := newDrivingIndividual(0.5)
+	longDriving := newDrivingIndividual(2)
+	if shortDriving.fitnessData.makespan != longDriving.fitnessData.makespan || shortDriving.fitness >= longDriving.fitness {
+		t.Errorf("Short driving fitness = %v (makespan %v), long driving = %v (makespan %v), expected the same makespan and better short driving", shortDriving.fitness, shortDriving.fitnessData.makespan, longDriving.fitness, longDriving.fitnessData.makespan)
+	}
+	//Unscheduled task is still worse than any driving
+	unscheduled := newDrivingIndividual(0)
+	unscheduled.tasks[1].assignees = nil
+	if unscheduled = calculateIndividualFitness(unscheduled); unscheduled.fitness <= longDriving.fitness {
+		t.Errorf("Unscheduled fitness = %v, expected worse than long driving fitness %v", unscheduled.fitness, longDriving.fitness)
+	}
+}
+
+func TestTotalDrivingHours(t *testing.T) {
+	setTestDB(map[string]task{"P1.T1": newTestTask(2, 1, "W1"), "P1.T2": newTestTask(2, 1, "W2")}, map[string]worker{
+		"W1": {latitude: 49.2827, longitude: -123.1207},
+		"W2": {latitude: 49.1666, longitude: -123.1336},
+	})
+	projectsDB["P1"] = project{site: newTestSite(), latitude: 49.2488, longitude: -122.9805}
+	provider := location.HaversineProvider{Speed: 40}
+	drivingTimeProvider = provider
+	//Every worker drives a single leg from home to the project
+	var expected float32
+	for _, worker := range workersDB {
+		drivingTime, err := provider.DrivingTime(worker.latitude, worker.longitude, 49.2488, -122.9805)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected += drivingTime
+	}
+	if drivingHours := scheduleTestTasks("P1.T1", "P1.T2").fitnessData.drivingHours; expected == 0 || !almostEqual(drivingHours, expected) {
+		t.Errorf("Total driving hours = %v, expected %v", drivingHours, expected)
+	}
+}
+
+func TestReadWorkerSwappedCoordinates(t *testing.T) {
+	defer chdirTestFiles(t, map[string]string{
+		workersDBFileName: "name,id,latitude,longitude\nAnn,W1,49.25,-123.10\nBob,W2,-123.10,49.25\n",
+	})()
+	_, err := readWorkerInfoCSV()
+	if err == nil || !strings.Contains(err.Error(), "swapped") || !strings.Contains(err.Error(), "-123.1") {
+		t.Errorf("Swapped worker coordinates error = %v, expected the out of range latitude", err)
+	}
+}
+
+func TestParseLatLon(t *testing.T) {
+	tests := []struct {
+		record        []string
+		expectedError string
+	}{
+		{[]string{"W1", "49.25", "-123.10"}, ""},
+		{[]string{"W1", "north", "-123.10"}, "couldn't parse worker latitude value in column 2"},
+		{[]string{"W1", "49.25", "west"}, "couldn't parse worker longitude value in column 3"},
+		{[]string{"W1", "-123.10", "49.25"}, "invalid worker coordinates in columns 2 and 3"},
+	}
+	for _, test := range tests {
+		latitude, longitude, err := parseLatLon(test.record, 1, 2, "worker")
+		if test.expectedError == "" {
+			if err != nil || latitude != 49.25 || longitude != -123.10 {
+				t.Errorf("parseLatLon %v = %v, %v, %v, expected 49.25, -123.10", test.record, latitude, longitude, err)
+			}
+			continue
+		}
+		if err == nil || !strings.HasPrefix(err.Error(), test.expectedError) {
+			t.Errorf("parseLatLon %v error = %v, expected %q", test.record, err, test.expectedError)
+		}
+	}
+}
+
+func TestReadTaskInfoCSVLagHours(t *testing.T) {
+	const header = "project,id,name,valid_workers,prerequisites,ideal_worker_count,min_worker_count,max_worker_count,duration,lag_hours,pinned_datetime,pinned_workers\n" +
+		"P1,T1,Dig,W1,,1,1,1,4,,,\n" +
+		"P1,T2,Frame,W1,,1,1,1,4,,,\n"
+	tests := []struct {
+		name                  string
+		prerequisites         string
+		lagHours              string
+		expectedPrerequisites map[string]float32
+		expectedError         string
+	}{
+		{"matched", "T1 T2", "2 -1", map[string]float32{"P1.T1": 2, "P1.T2": -1}, ""},
+		//Empty lag hours are no lag for all prerequisites
+		{"no lag", "T1 T2", "", map[string]float32{"P1.T1": 0, "P1.T2": 0}, ""},
+		{"empty", "", "", map[string]float32{}, ""},
+		{"mismatched", "T1 T2", "2", nil, "task P1.T3 has 2 prerequisites, but 1 lag hours values"},
+		{"lag without prerequisites", "", "2", nil, "task P1.T3 has 0 prerequisites, but 1 lag hours values"},
+	}
+	for _, test := range tests {
+		restore := chdirTestFiles(t, map[string]string{tasksDBFileName: header + "P1,T3,Pour,W1," + test.prerequisites + ",1,1,1,4," + test.lagHours + ",,\n"})
+		tasks, err := readTaskInfoCSV()
+		restore()
+		if test.expectedError != "" {
+			if err == nil || !strings.Contains(err.Error(), test.expectedError) {
+				t.Errorf("%v lag hours error = %v, expected %q", test.name, err, test.expectedError)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v lag hours error = %v", test.name, err)
+		} else if prerequisites := tasks["P1.T3"].prerequisites; !reflect.DeepEqual(prerequisites, test.expectedPrerequisites) {
+			t.Errorf("%v lag hours prerequisites = %v, expected %v", test.name, prerequisites, test.expectedPrerequisites)
+		}
+	}
+}
+
+func TestLeadPrerequisite(t *testing.T) {
+	leadTask := newTestTask(4, 1, "W2")
+	leadTask.prerequisites = map[string]float32{"P1.T1": -4}
+	setTestDB(map[string]task{"P1.T1": newTestTask(8, 1, "W1"), "P1.T2": leadTask}, map[string]worker{"W1": {}, "W2": {}})
+	//Successor starts 4 hours before the prerequisite stops
+	if task := findTestTask(scheduleTestTasks("P1.T1", "P1.T2"), "P1.T2"); !task.startTime.Equal(testDateTime(21, 12)) {
+		t.Errorf("Task with the 4 hours lead starts at %v, expected %v", task.startTime, testDateTime(21, 12))
+	}
+}
+
+func TestDependencyTypes(t *testing.T) {
+	tests := []struct {
+		dependencyType    string
+		expectedStartTime time.Time
+	}{
+		{finishToStart, testDateTime(22, 10)},
+		//Successor starts 2 hours after the prerequisite starts
+		{startToStart, testDateTime(21, 10)},
+		//Successor stops 2 hours after the prerequisite stops, on Tuesday at 10:00
+		{finishToFinish, testDateTime(21, 14)},
+	}
+	for _, test := range tests {
+		successor := newTestTask(4, 1, "W2")
+		successor.prerequisites = map[string]float32{"P1.T1": 2}
+		successor.dependencyTypes = map[string]string{"P1.T1": test.dependencyType}
+		setTestDB(map[string]task{"P1.T1": newTestTask(8, 1, "W1"), "P1.T2": successor}, map[string]worker{"W1": {}, "W2": {}})
+		if task := findTestTask(scheduleTestTasks("P1.T1", "P1.T2"), "P1.T2"); !task.startTime.Equal(test.expectedStartTime) {
+			t.Errorf("%v successor starts at %v, expected %v", test.dependencyType, task.startTime, test.expectedStartTime)
+		}
+	}
+}
+
+func TestPartialAllocation(t *testing.T) {
+	tests := []struct {
+		allocation             float32
+		expectedSecondStopTime time.Time
+	}{
+		//Two half-day tasks share W1 on Monday
+		{0.5, testDateTime(21, 16)},
+		//Full-time tasks follow each other
+		{1, testDateTime(22, 16)},
+	}
+	for _, test := range tests {
+		halfDayTask := newTestTask(8, 1, "W1")
+		halfDayTask.allocation = test.allocation
+		setTestDB(map[string]task{"P1.T1": halfDayTask, "P1.T2": halfDayTask}, map[string]worker{"W1": {}})
+		individual := scheduleTestTasks("P1.T1", "P1.T2")
+		if task := findTestTask(individual, "P1.T2"); !task.stopTime.Equal(test.expectedSecondStopTime) || !reflect.DeepEqual(task.assignees, []string{"W1"}) {
+			t.Errorf("Second task with %v allocation stops at %v %v, expected %v W1", test.allocation, task.stopTime, task.assignees, test.expectedSecondStopTime)
+		}
+	}
+}
+
+func TestDistinctAssignees(t *testing.T) {
+	tests := []struct {
+		validWorkers      []string
+		expectedAssignees []string
+	}{
+		{[]string{"W1", "W2"}, []string{"W1", "W2"}},
+		//Single valid worker can't fill both places, so the task stays unscheduled
+		{[]string{"W1"}, []string{"W1"}},
+	}
+	for _, test := range tests {
+		setTestDB(map[string]task{"P1.T1": newTestTask(4, 2, test.validWorkers...)}, map[string]worker{"W1": {}, "W2": {}})
+		task := findTestTask(scheduleTestTasks("P1.T1"), "P1.T1")
+		assignees := append([]string(nil), task.assignees...)
+		sort.Strings(assignees)
+		if !reflect.DeepEqual(assignees, test.expectedAssignees) {
+			t.Errorf("Task with valid workers %v is assigned to %v, expected %v", test.validWorkers, task.assignees, test.expectedAssignees)
+		}
+		if isTaskScheduled(task) != (len(test.validWorkers) == 2) {
+			t.Errorf("Task with valid workers %v scheduled = %v", test.validWorkers, isTaskScheduled(task))
+		}
+	}
+}
+
+func TestHomeDrivingDiscount(t *testing.T) {
+	defer func(discount float32) { homeDrivingDiscount = discount }(homeDrivingDiscount)
+	tests := []struct {
+		discount          float32
+		expectedStartTime time.Time
+	}{
+		{0, testDateTime(21, 9)},
+		{0.5, testDateTime(21, 8).Add(30 * time.Minute)},
+		{1, testDateTime(21, 8)},
+	}
+	for _, test := range tests {
+		homeDrivingDiscount = test.discount
+		setTestDB(map[string]task{"P1.T1": newTestTask(2, 1, "W1")}, map[string]worker{"W1": {}})
+		drivingTimeProvider = testRouteProvider(1)
+		individual := scheduleTestTasks("P1.T1")
+		//First leg from home is 1 hour of driving before the discount
+		if task := findTestTask(individual, "P1.T1"); !task.startTime.Equal(test.expectedStartTime) {
+			t.Errorf("First task with %v home driving discount starts at %v, expected %v", test.discount, task.startTime, test.expectedStartTime)
+		}
+		if drivingHours := individual.workers[0].drivingHours; !almostEqual(drivingHours, 1-test.discount) {
+			t.Errorf("Driving hours with %v home driving discount = %v, expected %v", test.discount, drivingHours, 1-test.discount)
+		}
+	}
+}
+
+func TestCalculateRemainingWorkersDemand(t *testing.T) {
+	setTestDB(map[string]task{"P1.T1": newTestTask(1, 1, "W1", "W2"), "P1.T2": newTestTask(1, 1, "W1"), "P1.T3": newTestTask(1, 1, "W1"), "P1.T4": newTestTask(1, 1, "W2")}, map[string]worker{"W1": {}, "W2": {}})
+	tasks := newTestIndividual(0, "P1.T1", "P1.T2", "P1.T3", "P1.T4").tasks
+	if demand, expected := calculateRemainingWorkersDemand(tasks, nil), map[string]float32{"W1": 0.75, "W2": 0.5}; !reflect.DeepEqual(demand, expected) {
+		t.Errorf("Demand before the assignments = %v, expected %v", demand, expected)
+	}
+	//W1 tasks are assigned, so W2 is needed for every remaining task
+	tasks[1].assignees = []string{"W1"}
+	tasks[2].assignees = []string{"W1"}
+	if demand, expected := calculateRemainingWorkersDemand(tasks, nil), map[string]float32{"W1": 0.5, "W2": 1}; !reflect.DeepEqual(demand, expected) {
+		t.Errorf("Demand after the W1 assignments = %v, expected %v", demand, expected)
+	}
+	//Dropped tasks are not remaining
+	if demand, expected := calculateRemainingWorkersDemand(tasks, map[string]struct{}{"P1.T4": {}}), map[string]float32{"W1": 1, "W2": 1}; !reflect.DeepEqual(demand, expected) {
+		t.Errorf("Demand without the dropped task = %v, expected %v", demand, expected)
+	}
+}
+
+//countingRouteProvider is a RouteProvider with 1 hour driving time for all routes, the driving time calls are counted
+type countingRouteProvider struct {
+	calls *int
+}
+
+func (provider countingRouteProvider) DrivingTime(originLatitude, originLongitude, destinationLatitude, destinationLongitude float64) (float32, error) {
+	*provider.calls++
+	return 1, nil
+}
+
+//BenchmarkWorkersFitnessTaint reports driving time calls to assign 5 workers of 100 to the task,
+//when only the assigned worker is tainted and when all workers are recalculated after every assignment
+func BenchmarkWorkersFitnessTaint(b *testing.B) {
+	workers := make(map[string]worker)
+	for i := 0; i < 100; i++ {
+		workers["W"+strconv.Itoa(i)] = worker{}
+	}
+	setTestDB(map[string]task{"P1.T1": newTestTask(8, 5)}, workers)
+	var calls int
+	drivingTimeProvider = countingRouteProvider{&calls}
+	task := newTestIndividual(0, "P1.T1").tasks[0]
+	individualWorkers := make([]scheduledWorker, 0, len(workers))
+	for workerID := range workers {
+		individualWorkers = append(individualWorkers, scheduledWorker{workerID: workerID, availableAt: scheduleStartTime})
+	}
+	for _, taintAll := range []bool{false, true} {
+		name := "taint-assigned"
+		if taintAll {
+			name = "taint-all"
+		}
+		b.Run(name, func(b *testing.B) {
+			calls = 0
+			for i := 0; i < b.N; i++ {
+				//New task taints all workers
+				for j := range individualWorkers {
+					individualWorkers[j].tainted = true
+				}
+				for j := 0; j < tasksDB["P1.T1"].idealWorkerCount; j++ {
+					calculateWorkersFitness(task, individualWorkers, nil, nil)
+					//Worker j is assigned, so only its availability and location are changed
+					for k := range individualWorkers {
+						individualWorkers[k].tainted = taintAll || k == j
+					}
+				}
+			}
+			b.ReportMetric(float64(calls)/float64(b.N), "drivingTimes/op")
+		})
+	}
+}
+
+func TestPinnedDateTimeSnapWindow(t *testing.T) {
+	defer currentTuningConfig().apply()
+	pinnedDateTimeSnap = 8
+	pinnedTask := newTestTask(4, 1, "W1")
+	pinnedTask.pinnedDateTime = testDateTime(22, 10)
+	//Snap window is 8 working hours around the pin, from Monday 10:00 to Wednesday 10:00
+	for _, test := range []struct {
+		name      string
+		arrival   time.Time
+		scheduled bool
+	}{
+		{"before pin", testDateTime(22, 8), true},
+		{"after pin", testDateTime(22, 12), true},
+		{"window start", testDateTime(21, 10), true},
+		{"before window", testDateTime(21, 8), false},
+		{"after window", testDateTime(23, 12), false},
+	} {
+		setTestDB(map[string]task{"P1.T1": pinnedTask}, map[string]worker{"W1": {}})
+		scheduleStartTime = test.arrival
+		task := findTestTask(scheduleTestTasks("P1.T1"), "P1.T1")
+		if isTaskScheduled(task) != test.scheduled {
+			t.Errorf("Worker arriving %v at %v scheduled = %v, expected %v", test.name, test.arrival, isTaskScheduled(task), test.scheduled)
+		}
+		if test.scheduled && !task.startTime.Equal(pinnedTask.pinnedDateTime) {
+			t.Errorf("Worker arriving %v at %v starts at %v, expected exactly at the pin", test.name, test.arrival, task.startTime)
+		}
+	}
+}
+
+func TestPinnedPoorFitWorker(t *testing.T) {
+	//W1 is busy with the first task, so the free W2 is a better fit for the second one without the pin
+	pinnedTask := newTestTask(4, 1, "W1", "W2")
+	setTestDB(map[string]task{"P1.T1": newTestTask(4, 1, "W1"), "P1.T2": pinnedTask}, map[string]worker{"W1": {}, "W2": {}})
+	if task := findTestTask(scheduleTestTasks("P1.T1", "P1.T2"), "P1.T2"); !reflect.DeepEqual(task.assignees, []string{"W2"}) {
+		t.Fatalf("Not pinned task is assigned to %v, expected the free W2", task.assignees)
+	}
+	pinnedTask.pinnedWorkerIDs = map[string]struct{}{"W1": {}}
+	tasksDB["P1.T2"] = pinnedTask
+	if task := findTestTask(scheduleTestTasks("P1.T1", "P1.T2"), "P1.T2"); !reflect.DeepEqual(task.assignees, []string{"W1"}) || !task.startTime.Equal(testDateTime(21, 12)) {
+		t.Errorf("Task pinned to W1 is assigned to %v at %v, expected W1 after the first task", task.assignees, task.startTime)
+	}
+}
+
+func TestTaskStartWindow(t *testing.T) {
+	//Inspection can start on Tuesday from 9:00 to 12:00
+	inspectionTask := newTestTask(2, 1, "W1")
+	inspectionTask.windowStart = testDateTime(22, 9)
+	inspectionTask.latestStart = testDateTime(22, 12)
+	tests := []struct {
+		name              string
+		busyHours         float32
+		expectedStartTime time.Time
+	}{
+		{"free worker", 0, testDateTime(22, 9)},
+		{"worker busy until Tuesday 11:00", 11, testDateTime(22, 11)},
+		{"worker busy until Tuesday 14:00", 14, time.Time{}},
+	}
+	for _, test := range tests {
+		tasks := map[string]task{"P1.T1": inspectionTask}
+		taskIDs := []string{"P1.T1"}
+		if test.busyHours > 0 {
+			tasks["P1.T0"] = newTestTask(test.busyHours, 1, "W1")
+			taskIDs = []string{"P1.T0", "P1.T1"}
+		}
+		setTestDB(tasks, map[string]worker{"W1": {}})
+		task := findTestTask(scheduleTestTasks(taskIDs...), "P1.T1")
+		if test.expectedStartTime.IsZero() {
+			if isTaskScheduled(task) {
+				t.Errorf("Task with the %v is scheduled at %v after the latest start", test.name, task.startTime)
+			}
+		} else if !isTaskScheduled(task) || !task.startTime.Equal(test.expectedStartTime) {
+			t.Errorf("Task with the %v starts at %v, expected %v", test.name, task.startTime, test.expectedStartTime)
+		}
+	}
+}
+
+func TestGeneratePopulationSameSeed(t *testing.T) {
+	defer currentTuningConfig().apply()
+	setTestDBIndependentTasks(20)
+	populationSize = 5
+	//Task and worker orders of all individuals
+	generateOrders := func() [][]string {
+		rand.Seed(7)
+		var orders [][]string
+		for _, individual := range generatePopulation().individuals {
+			var order []string
+			for _, task := range individual.tasks {
+				order = append(order, task.taskID)
+			}
+			for _, worker := range individual.workers {
+				order = append(order, worker.workerID)
+			}
+			orders = append(orders, order)
+		}
+		return orders
+	}
+	//Map iteration order is different for every call, so the same orders come from the sorted IDs only
+	if firstOrders, secondOrders := generateOrders(), generateOrders(); !reflect.DeepEqual(firstOrders, secondOrders) {
+		t.Errorf("Populations with the same seed have different orders:\n%v\n%v", firstOrders, secondOrders)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"math/rand"
+	"sort"
+)
+
+//IDs of all optional tasks in the sorted order
+func optionalTaskIDs() []string {
+	var taskIDs []string
+	for taskID, task := range tasksDB {
+		if task.optionalReward > 0 {
+			taskIDs = append(taskIDs, taskID)
+		}
+	}
+	sort.Strings(taskIDs)
+	return taskIDs
+}
+
+//Randomly drop optional tasks from the new individual
+func dropOptionalTasks(individual individual) individual {
+	individual.droppedTasks = make(map[string]struct{})
+	for _, taskID := range optionalTaskIDs() {
+		if rand.Intn(2) == 0 {
+			individual.droppedTasks[taskID] = struct{}{}
+		}
+	}
+	return individual
+}
+
+//Drop or restore a random optional task
+func toggleOptionalMutation(individual individual) individual {
+	taskIDs := optionalTaskIDs()
+	if len(taskIDs) == 0 {
+		return individual
+	}
+	taskID := taskIDs[rand.Intn(len(taskIDs))]
+	if _, ok := individual.droppedTasks[taskID]; ok {
+		delete(individual.droppedTasks, taskID)
+	} else {
+		individual.droppedTasks[taskID] = struct{}{}
+	}
+	return individual
+}
+
+//Verify that mandatory tasks don't depend on the optional tasks, they could be never scheduled otherwise
+func verifyOptionalTasks() {
+	for k, task := range tasksDB {
+		if task.optionalReward > 0 {
+			continue
+		}
+		for prerequisiteID := range task.prerequisites {
+			if tasksDB[prerequisiteID].optionalReward > 0 {
+				logger.Error("Mandatory task depends on the optional task")
+				logger.Errorf("Task ID:%v, prerequisite ID:%v", k, prerequisiteID)
+			}
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"strconv"
+	"time"
+)
+
+//Separator between the recurring task ID and the instance number
+const recurrenceSeparator string = "#"
+
+//Replace every recurring task with its instances. The first instance keeps the original task ID, so the prerequisites still refer to it,
+//next instances get "#<number>" suffix. Every instance is pinned or has the task window shifted by the recurrence interval,
+//window of the task without window end is the recurrence interval
+func expandRecurringTasks(tasks map[string]task) map[string]task {
+	for taskID, recurringTask := range tasks {
+		if recurringTask.recurrenceDays == 0 {
+			continue
+		}
+		//Instances are shifted from the pinned time, window start or the schedule start
+		baseTime := scheduleStartTime
+		if !recurringTask.windowStart.IsZero() {
+			baseTime = recurringTask.windowStart
+		}
+		if !recurringTask.pinnedDateTime.IsZero() {
+			baseTime = recurringTask.pinnedDateTime
+		}
+
+		instancesCount := 0
+		for i := 0; recurringTask.recurrenceCount == 0 || i < recurringTask.recurrenceCount; i++ {
+			instanceTime := baseTime.AddDate(0, 0, i*recurringTask.recurrenceDays)
+			if recurringTask.recurrenceCount == 0 && !instanceTime.Before(recurringTask.recurrenceUntil.AddDate(0, 0, 1)) {
+				break
+			}
+			instance := recurringTask
+			instance.recurrenceDays = 0
+			instance.recurrenceCount = 0
+			instance.recurrenceUntil = time.Time{}
+			if !recurringTask.pinnedDateTime.IsZero() {
+				instance.pinnedDateTime = instanceTime
+			} else {
+				instance.windowStart = instanceTime
+				//Instance should be finished inside its own recurrence interval by default
+				instance.windowEnd = baseTime.AddDate(0, 0, (i+1)*recurringTask.recurrenceDays)
+				if !recurringTask.windowEnd.IsZero() {
+					instance.windowEnd = recurringTask.windowEnd.AddDate(0, 0, i*recurringTask.recurrenceDays)
+				}
+			}
+			if !recurringTask.latestStart.IsZero() {
+				instance.latestStart = recurringTask.latestStart.AddDate(0, 0, i*recurringTask.recurrenceDays)
+			}
+			instanceID := taskID
+			if i > 0 {
+				instanceID = taskID + recurrenceSeparator + strconv.Itoa(i+1)
+			}
+			tasks[instanceID] = instance
+			instancesCount++
+		}
+		if instancesCount == 0 {
+			logger.Error("Original task: ", recurringTask)
+			logger.Fatal("Recurring task has no instances: ", taskID)
+		}
+		logger.Infof("Recurring task %v expanded into %v instances", taskID, instancesCount)
+	}
+	return tasks
+}
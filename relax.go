@@ -0,0 +1,112 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	deadlineRelaxation string = "deadline" //ignore the task window end and latest start
+	overtimeRelaxation string = "overtime" //extend the project daily end time by relaxationOvertimeHours
+	pinRelaxation      string = "pin"      //drop the task pinned datetime and pinned workers
+
+	relaxationOvertimeHours int = 2 //overtime hours added to the project working day
+	relaxationSamples       int = 3 //random task orders scheduled in addition to the best individual order
+)
+
+//Single constraint relaxation and its effect on the infeasible schedule
+type constraintRelaxation struct {
+	category         string //deadlineRelaxation, overtimeRelaxation or pinRelaxation
+	id               string //relaxed task or project ID
+	unscheduledTasks int    //fewest unscheduled mandatory tasks of the greedy schedules with the relaxation
+}
+
+//List all constraints which can be relaxed, one relaxation per task deadline, task pin and project working day
+func listConstraintRelaxations() []constraintRelaxation {
+	var relaxations []constraintRelaxation
+	for taskID, task := range tasksDB {
+		if !task.windowEnd.IsZero() || !task.latestStart.IsZero() {
+			relaxations = append(relaxations, constraintRelaxation{category: deadlineRelaxation, id: taskID})
+		}
+		if !task.pinnedDateTime.IsZero() || len(task.pinnedWorkerIDs) > 0 {
+			relaxations = append(relaxations, constraintRelaxation{category: pinRelaxation, id: taskID})
+		}
+	}
+	for projectID := range projectsDB {
+		relaxations = append(relaxations, constraintRelaxation{category: overtimeRelaxation, id: projectID})
+	}
+	return relaxations
+}
+
+//Relax the constraint in the global DBs and return the function to restore it
+func (relaxation constraintRelaxation) apply() func() {
+	switch relaxation.category {
+	case deadlineRelaxation, pinRelaxation:
+		originalTask := tasksDB[relaxation.id]
+		relaxedTask := originalTask
+		if relaxation.category == deadlineRelaxation {
+			relaxedTask.windowEnd = time.Time{}
+			relaxedTask.latestStart = time.Time{}
+		} else {
+			relaxedTask.pinnedDateTime = time.Time{}
+			relaxedTask.pinnedWorkerIDs = make(map[string]struct{})
+		}
+		tasksDB[relaxation.id] = relaxedTask
+		return func() { tasksDB[relaxation.id] = originalTask }
+	default:
+		//Tasks with their own calendar keep their working hours
+		originalProject := projectsDB[relaxation.id]
+		relaxedProject := originalProject
+		relaxedProject.site.DailyEndTime = originalProject.site.DailyEndTime.Add(time.Duration(relaxationOvertimeHours) * time.Hour)
+		projectsDB[relaxation.id] = relaxedProject
+		return func() { projectsDB[relaxation.id] = originalProject }
+	}
+}
+
+//Count unscheduled mandatory tasks of the best greedy schedule: the individual task order and a few random orders
+func countRelaxedUnscheduledTasks(individual individual) int {
+	unscheduledTasks := scheduleIndividual(copyIndividual(individual), nil).fitnessData.unscheduledTasks
+	for i := 0; i < relaxationSamples; i++ {
+		if sampleUnscheduledTasks := scheduleIndividual(generateIndividual(), nil).fitnessData.unscheduledTasks; sampleUnscheduledTasks < unscheduledTasks {
+			unscheduledTasks = sampleUnscheduledTasks
+		}
+	}
+	return unscheduledTasks
+}
+
+//Try every constraint relaxation one at a time and rank them by the number of unscheduled tasks, the most helpful relaxation goes first.
+//Global DBs are modified temporarily, so it should not run in parallel with the schedule generation
+func analyzeConstraintRelaxations(individual individual) []constraintRelaxation {
+	relaxations := listConstraintRelaxations()
+	for i, relaxation := range relaxations {
+		restore := relaxation.apply()
+		relaxations[i].unscheduledTasks = countRelaxedUnscheduledTasks(individual)
+		restore()
+	}
+	sort.Slice(relaxations, func(i, j int) bool {
+		if relaxations[i].unscheduledTasks != relaxations[j].unscheduledTasks {
+			return relaxations[i].unscheduledTasks < relaxations[j].unscheduledTasks
+		}
+		if relaxations[i].category != relaxations[j].category {
+			return relaxations[i].category < relaxations[j].category
+		}
+		return relaxations[i].id < relaxations[j].id
+	})
+	return relaxations
+}
+
+func prettyPrintConstraintRelaxations(individual individual) {
+	if individual.fitnessData.unscheduledTasks == 0 {
+		logger.Info("Best schedule is feasible, no relaxation required")
+		return
+	}
+	relaxations := analyzeConstraintRelaxations(individual)
+	for _, v := range relaxations {
+		logger.Infof(";%v;%v;%v", v.category, v.id, v.unscheduledTasks)
+	}
+	if len(relaxations) == 0 || relaxations[0].unscheduledTasks >= individual.fitnessData.unscheduledTasks {
+		logger.Info("No single relaxation reduces the unscheduled tasks number =", individual.fitnessData.unscheduledTasks)
+		return
+	}
+	logger.Infof("Relax %v %v to reduce unscheduled tasks from %v to %v", relaxations[0].category, relaxations[0].id, individual.fitnessData.unscheduledTasks, relaxations[0].unscheduledTasks)
+}
@@ -0,0 +1,427 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"gitlab.com/alex.skylight/sambo/calendar"
+)
+
+type workerUtilization struct {
+	workerID       string
+	assignedHours  float32
+	availableHours float32
+	utilization    float32 //assigned hours / available hours in percents
+}
+
+//Daily window covering working hours of all the sites, lunch, holidays and working weekdays are not included
+func horizonSite() calendar.Site {
+	var site calendar.Site
+	first := true
+	for _, project := range projectsDB {
+		dailyStartTime := time.Date(0, 1, 1, project.site.DailyStartTime.Hour(), project.site.DailyStartTime.Minute(), project.site.DailyStartTime.Second(), 0, time.UTC)
+		dailyEndTime := time.Date(0, 1, 1, project.site.DailyEndTime.Hour(), project.site.DailyEndTime.Minute(), project.site.DailyEndTime.Second(), 0, time.UTC)
+		if first || dailyStartTime.Before(site.DailyStartTime) {
+			site.DailyStartTime = dailyStartTime
+		}
+		if first || dailyEndTime.After(site.DailyEndTime) {
+			site.DailyEndTime = dailyEndTime
+		}
+		first = false
+	}
+	return site
+}
+
+//Step of the worker availability walk, equal to the calendar time rounding
+const utilizationStep = 10 * time.Minute
+
+//Working calendars of the worker for the scheduled tasks of the worker, worker without scheduled tasks is measured against the valid tasks calendars.
+//Tasks without own calendar share the project calendar
+func workerUtilizationSites(individual individual, workerID string) []calendar.Site {
+	sites := make(map[string]calendar.Site)
+	addSite := func(taskID string) {
+		siteID := tasksDB[taskID].project
+		if tasksDB[taskID].site != nil {
+			siteID = taskID
+		}
+		if site, ok := workerTaskSite(workerID, taskID); ok {
+			sites[siteID] = site
+		}
+	}
+	for _, task := range individual.tasks {
+		if isTaskScheduled(task) && isWorkerAssigned(task, workerID) {
+			addSite(task.taskID)
+		}
+	}
+	if len(sites) == 0 {
+		for taskID, task := range tasksDB {
+			if _, ok := task.validWorkers[workerID]; ok {
+				addSite(taskID)
+			}
+		}
+	}
+
+	siteIDs := make([]string, 0, len(sites))
+	for siteID := range sites {
+		siteIDs = append(siteIDs, siteID)
+	}
+	sort.Strings(siteIDs)
+	sortedSites := make([]calendar.Site, 0, len(sites))
+	for _, siteID := range siteIDs {
+		sortedSites = append(sortedSites, sites[siteID])
+	}
+	return sortedSites
+}
+
+//Calculate working hours between startTime and endTime when the worker can work in any of the sites and has no time off.
+//Time is walked in utilizationStep slots, so overlapping site calendars are counted once
+func calcWorkerAvailableHours(workerID string, sites []calendar.Site, startTime, endTime time.Time) float32 {
+	//Slot hours are summed in float64 to avoid the rounding drift over the long horizon
+	var hours float64
+	for dayStartTime := startTime; dayStartTime.Before(endTime); {
+		dayEndTime := time.Date(dayStartTime.Year(), dayStartTime.Month(), dayStartTime.Day()+1, 0, 0, 0, 0, dayStartTime.Location())
+		if dayEndTime.After(endTime) {
+			dayEndTime = endTime
+		}
+		//Days without working hours in all sites are skipped at once
+		var dayHours float32
+		for _, site := range sites {
+			dayHours += site.WorkingHoursBetween(dayStartTime, dayEndTime)
+		}
+		for slotStartTime := dayStartTime; dayHours > 0 && slotStartTime.Before(dayEndTime); slotStartTime = slotStartTime.Add(utilizationStep) {
+			slotEndTime := slotStartTime.Add(utilizationStep)
+			if slotEndTime.After(dayEndTime) {
+				slotEndTime = dayEndTime
+			}
+			if _, ok := findTimeOffOverlap(workerID, slotStartTime, slotEndTime); ok {
+				continue
+			}
+			var slotHours float32
+			for _, site := range sites {
+				if siteHours := site.WorkingHoursBetween(slotStartTime, slotEndTime); siteHours > slotHours {
+					slotHours = siteHours
+				}
+			}
+			hours += float64(slotHours)
+		}
+		dayStartTime = dayEndTime
+	}
+	return float32(hours)
+}
+
+//Calculate utilization for every worker between scheduleStartTime and horizonEndTime, sorted from the most to the least utilized.
+//Only scheduled tasks are counted, partially allocated task counts the allocated share of the task duration
+func calculateWorkersUtilization(individual individual, horizonEndTime time.Time) []workerUtilization {
+	assignedHours := make(map[string]float32)
+	for _, task := range individual.tasks {
+		if !isTaskScheduled(task) {
+			continue
+		}
+		for _, workerID := range task.assignees {
+			assignedHours[workerID] += tasksDB[task.taskID].duration * tasksDB[task.taskID].allocation
+		}
+	}
+
+	var utilizations []workerUtilization
+	for workerID := range workersDB {
+		availableHours := calcWorkerAvailableHours(workerID, workerUtilizationSites(individual, workerID), scheduleStartTime, horizonEndTime)
+		var utilization float32
+		if availableHours > 0 {
+			utilization = 100 * assignedHours[workerID] / availableHours
+		}
+		utilizations = append(utilizations, workerUtilization{workerID: workerID, assignedHours: assignedHours[workerID], availableHours: availableHours, utilization: utilization})
+	}
+
+	sort.Slice(utilizations, func(i, j int) bool {
+		if utilizations[i].utilization == utilizations[j].utilization {
+			return utilizations[i].workerID < utilizations[j].workerID
+		}
+		return utilizations[i].utilization > utilizations[j].utilization
+	})
+	return utilizations
+}
+
+func prettyPrintWorkersUtilization(individual individual) {
+	var horizonEndTime time.Time
+	for _, task := range individual.tasks {
+		if horizonEndTime.Before(task.stopTime) {
+			horizonEndTime = task.stopTime
+		}
+	}
+	for _, v := range calculateWorkersUtilization(individual, horizonEndTime) {
+		logger.Infof(";%v;%v;%.2f;%.2f;%.1f%%", workersDB[v.workerID].name, v.workerID, v.assignedHours, v.availableHours, v.utilization)
+	}
+}
+
+//Group tasks by project ID, projects are sorted by ID and tasks inside every project by start time
+func groupTasksByProject(individual individual) ([]string, map[string][]scheduledTask) {
+	projectTasks := make(map[string][]scheduledTask)
+	for _, task := range individual.tasks {
+		projectID := tasksDB[task.taskID].project
+		projectTasks[projectID] = append(projectTasks[projectID], task)
+	}
+
+	projectIDs := make([]string, 0, len(projectTasks))
+	for projectID, tasks := range projectTasks {
+		projectIDs = append(projectIDs, projectID)
+		//Unscheduled tasks go last
+		sort.SliceStable(tasks, func(i, j int) bool {
+			if tasks[i].startTime.IsZero() != tasks[j].startTime.IsZero() {
+				return tasks[j].startTime.IsZero()
+			}
+			return tasks[i].startTime.Before(tasks[j].startTime)
+		})
+	}
+	sort.Strings(projectIDs)
+	return projectIDs, projectTasks
+}
+
+func prettyPrintScheduleByProject(individual individual) {
+	projectIDs, projectTasks := groupTasksByProject(individual)
+	for _, projectID := range projectIDs {
+		logger.Infof("Project %v: %v", projectID, projectsDB[projectID].name)
+		for _, task := range projectTasks[projectID] {
+			prettyPrintTask(task)
+		}
+	}
+}
+
+//Task of the worker on the specific day, start and stop times are limited to the site working hours of the day
+type dispatchEntry struct {
+	task      scheduledTask
+	startTime time.Time
+	stopTime  time.Time
+}
+
+//Pivot scheduled tasks into per-day, per-worker dispatch sheets. Returns days with at least one task in the chronological order
+//and dispatch entries by day and worker ID, sorted by start time
+func buildDispatchSheets(individual individual) ([]time.Time, map[time.Time]map[string][]dispatchEntry) {
+	sheets := make(map[time.Time]map[string][]dispatchEntry)
+	for _, task := range individual.tasks {
+		if task.startTime.IsZero() || task.stopTime.IsZero() {
+			continue
+		}
+		site := taskSite(task.taskID)
+		day := time.Date(task.startTime.Year(), task.startTime.Month(), task.startTime.Day(), 0, 0, 0, 0, task.startTime.Location())
+		for day.Before(task.stopTime) {
+			nextDay := day.AddDate(0, 0, 1)
+			dayStartTime := time.Date(day.Year(), day.Month(), day.Day(), site.DailyStartTime.Hour(), site.DailyStartTime.Minute(), site.DailyStartTime.Second(), 0, day.Location())
+			dayEndTime := time.Date(day.Year(), day.Month(), day.Day(), site.DailyEndTime.Hour(), site.DailyEndTime.Minute(), site.DailyEndTime.Second(), 0, day.Location())
+			entry := dispatchEntry{task: task, startTime: dayStartTime, stopTime: dayEndTime}
+			if entry.startTime.Before(task.startTime) {
+				entry.startTime = task.startTime
+			}
+			if entry.stopTime.After(task.stopTime) {
+				entry.stopTime = task.stopTime
+			}
+			//Skip days outside of the task working calendar
+			if site.WorkingHoursBetween(entry.startTime, entry.stopTime) > 0 {
+				if sheets[day] == nil {
+					sheets[day] = make(map[string][]dispatchEntry)
+				}
+				assigned := make(map[string]struct{})
+				for _, workerID := range task.assignees {
+					if _, ok := assigned[workerID]; ok {
+						continue
+					}
+					assigned[workerID] = struct{}{}
+					sheets[day][workerID] = append(sheets[day][workerID], entry)
+				}
+			}
+			day = nextDay
+		}
+	}
+
+	days := make([]time.Time, 0, len(sheets))
+	for day, workers := range sheets {
+		days = append(days, day)
+		for _, entries := range workers {
+			sort.Slice(entries, func(i, j int) bool {
+				return entries[i].startTime.Before(entries[j].startTime)
+			})
+		}
+	}
+	sort.Slice(days, func(i, j int) bool {
+		return days[i].Before(days[j])
+	})
+	return days, sheets
+}
+
+func prettyPrintDispatchSheets(individual individual) {
+	workerIDs := make([]string, 0, len(workersDB))
+	for workerID := range workersDB {
+		workerIDs = append(workerIDs, workerID)
+	}
+	sort.Strings(workerIDs)
+
+	days, sheets := buildDispatchSheets(individual)
+	for _, day := range days {
+		logger.Infof("Dispatch sheet %v %v", day.Format(defaultDateFormat), day.Weekday())
+		for _, workerID := range workerIDs {
+			entries := sheets[day][workerID]
+			if len(entries) == 0 {
+				logger.Infof(";%v;%v;idle", workersDB[workerID].name, workerID)
+				continue
+			}
+			for _, entry := range entries {
+				logger.Infof(";%v;%v;%v;%v;%v;%v", workersDB[workerID].name, workerID, projectsDB[tasksDB[entry.task.taskID].project].name, tasksDB[entry.task.taskID].name, entry.startTime.Format(defaultTimeFormat), entry.stopTime.Format(defaultTimeFormat))
+			}
+		}
+	}
+}
+
+//Worker deployment in the single ISO week
+type weekSummary struct {
+	year     int
+	week     int
+	projects map[string]struct{}
+	hours    float32
+}
+
+//Aggregate dispatch entries into ISO-week buckets per worker, weeks are sorted chronologically
+func calculateWeekSummaries(individual individual) map[string][]weekSummary {
+	days, sheets := buildDispatchSheets(individual)
+	summaries := make(map[string][]weekSummary)
+	for _, day := range days {
+		year, week := day.ISOWeek()
+		for workerID, entries := range sheets[day] {
+			workerSummaries := summaries[workerID]
+			//Days are sorted, so the current week is always the last one
+			if len(workerSummaries) == 0 || workerSummaries[len(workerSummaries)-1].year != year || workerSummaries[len(workerSummaries)-1].week != week {
+				workerSummaries = append(workerSummaries, weekSummary{year: year, week: week, projects: make(map[string]struct{})})
+			}
+			summary := &workerSummaries[len(workerSummaries)-1]
+			for _, entry := range entries {
+				summary.projects[tasksDB[entry.task.taskID].project] = struct{}{}
+				summary.hours += taskSite(entry.task.taskID).WorkingHoursBetween(entry.startTime, entry.stopTime)
+			}
+			summaries[workerID] = workerSummaries
+		}
+	}
+	return summaries
+}
+
+func prettyPrintWeekSummaries(individual individual) {
+	summaries := calculateWeekSummaries(individual)
+	workerIDs := make([]string, 0, len(summaries))
+	for workerID := range summaries {
+		workerIDs = append(workerIDs, workerID)
+	}
+	sort.Strings(workerIDs)
+	for _, workerID := range workerIDs {
+		for _, summary := range summaries[workerID] {
+			var projectNames []string
+			for projectID := range summary.projects {
+				projectNames = append(projectNames, projectsDB[projectID].name)
+			}
+			sort.Strings(projectNames)
+			logger.Infof(";%v;%v;%d-W%02d;%v;%.2f", workersDB[workerID].name, workerID, summary.year, summary.week, strings.Join(projectNames, ","), summary.hours)
+		}
+	}
+}
+
+//Print fitness components of the first count individuals, population should be sorted
+func prettyPrintFitnessBreakdown(individuals []individual, count int) {
+	if count > len(individuals) {
+		count = len(individuals)
+	}
+	for i, individual := range individuals[:count] {
+		logger.Infof(";%v;%.2f;%.2f;%v;%.2f;%.2f;%.2f;%.2f;%.2f;%.2f;%.2f;%.2f", i, individual.fitness, individual.fitnessData.makespan, individual.fitnessData.unscheduledTasks, individual.fitnessData.unscheduledPenalty, individual.fitnessData.optionalReward, individual.fitnessData.projectSwitches, individual.fitnessData.projectIdle, individual.fitnessData.overtime, individual.fitnessData.peakOvertime, individual.fitnessData.driving, individual.fitnessData.delay)
+	}
+}
+
+//Worker timeline block kinds
+const (
+	taskBlock   string = "TASK"
+	travelBlock string = "TRAVEL"
+	idleBlock   string = "IDLE"
+)
+
+//Contiguous part of the worker day
+type timelineBlock struct {
+	kind      string
+	taskID    string //empty for the idle blocks
+	startTime time.Time
+	stopTime  time.Time
+}
+
+//Fill every worker day between the earliest site start and the latest site end with contiguous task, travel and idle blocks.
+//Travel from the previous location ends at the task start, the previous location is carried over between days
+func buildWorkerTimelines(individual individual) ([]time.Time, map[time.Time]map[string][]timelineBlock) {
+	site := horizonSite()
+	days, sheets := buildDispatchSheets(individual)
+	type position struct {
+		latitude  float64
+		longitude float64
+	}
+	positions := make(map[string]position)
+	for workerID, worker := range workersDB {
+		positions[workerID] = position{latitude: worker.latitude, longitude: worker.longitude}
+	}
+
+	timelines := make(map[time.Time]map[string][]timelineBlock)
+	for _, day := range days {
+		dayStartTime := time.Date(day.Year(), day.Month(), day.Day(), site.DailyStartTime.Hour(), site.DailyStartTime.Minute(), site.DailyStartTime.Second(), 0, day.Location())
+		dayEndTime := time.Date(day.Year(), day.Month(), day.Day(), site.DailyEndTime.Hour(), site.DailyEndTime.Minute(), site.DailyEndTime.Second(), 0, day.Location())
+		timelines[day] = make(map[string][]timelineBlock)
+		for workerID := range workersDB {
+			var blocks []timelineBlock
+			addBlock := func(kind string, taskID string, startTime time.Time, stopTime time.Time) {
+				if stopTime.After(startTime) {
+					blocks = append(blocks, timelineBlock{kind: kind, taskID: taskID, startTime: startTime, stopTime: stopTime})
+				}
+			}
+			cursor := dayStartTime
+			for _, entry := range sheets[day][workerID] {
+				project := projectsDB[tasksDB[entry.task.taskID].project]
+				//Overlapping entries are cut to keep the blocks contiguous
+				startTime, stopTime := entry.startTime, entry.stopTime
+				if startTime.Before(cursor) {
+					startTime = cursor
+				}
+				if stopTime.After(dayEndTime) {
+					stopTime = dayEndTime
+				}
+				if !stopTime.After(startTime) {
+					continue
+				}
+				drivingTime := calcProjectDrivingTime(positions[workerID].latitude, positions[workerID].longitude, tasksDB[entry.task.taskID].project)
+				travelStartTime := startTime.Add(-time.Duration(float64(drivingTime) * float64(time.Hour)))
+				if travelStartTime.Before(cursor) {
+					travelStartTime = cursor
+				}
+				addBlock(idleBlock, "", cursor, travelStartTime)
+				addBlock(travelBlock, entry.task.taskID, travelStartTime, startTime)
+				addBlock(taskBlock, entry.task.taskID, startTime, stopTime)
+				cursor = stopTime
+				positions[workerID] = position{latitude: project.latitude, longitude: project.longitude}
+			}
+			addBlock(idleBlock, "", cursor, dayEndTime)
+			timelines[day][workerID] = blocks
+		}
+	}
+	return days, timelines
+}
+
+func prettyPrintWorkerTimelines(individual individual) {
+	workerIDs := make([]string, 0, len(workersDB))
+	for workerID := range workersDB {
+		workerIDs = append(workerIDs, workerID)
+	}
+	sort.Strings(workerIDs)
+
+	days, timelines := buildWorkerTimelines(individual)
+	for _, day := range days {
+		logger.Infof("Worker timeline %v %v", day.Format(defaultDateFormat), day.Weekday())
+		for _, workerID := range workerIDs {
+			for _, block := range timelines[day][workerID] {
+				var taskName string
+				if block.taskID != "" {
+					taskName = tasksDB[block.taskID].name
+				}
+				logger.Infof(";%v;%v;%v;%v;%v;%v", workersDB[workerID].name, workerID, block.startTime.Format(defaultTimeFormat), block.stopTime.Format(defaultTimeFormat), block.kind, taskName)
+			}
+		}
+	}
+}
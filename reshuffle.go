@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/csv"
+	"os"
+	"strconv"
+)
+
+//GA parameters randomized by the stagnation reshuffle
+type reshuffleParameters struct {
+	tourneySampleSize      int
+	crossoverParentsNumber int
+	maxCrossoverLength     int
+	maxMutatedGenes        int
+	mutationTypePreference float32
+}
+
+//Snapshot of the current reshuffle parameters
+func currentReshuffleParameters() reshuffleParameters {
+	return reshuffleParameters{
+		tourneySampleSize:      tourneySampleSize,
+		crossoverParentsNumber: crossoverParentsNumber,
+		maxCrossoverLength:     maxCrossoverLength,
+		maxMutatedGenes:        maxMutatedGenes,
+		mutationTypePreference: mutationTypePreference,
+	}
+}
+
+func (parameters reshuffleParameters) toStrings() []string {
+	return []string{
+		strconv.Itoa(parameters.tourneySampleSize),
+		strconv.Itoa(parameters.crossoverParentsNumber),
+		strconv.Itoa(parameters.maxCrossoverLength),
+		strconv.Itoa(parameters.maxMutatedGenes),
+		strconv.FormatFloat(float64(parameters.mutationTypePreference), 'f', -1, 32),
+	}
+}
+
+//Create reshuffle log CSV file and write the header
+func createReshuffleLogCSV(fileName string) (*os.File, *csv.Writer) {
+	reshuffleLogFile, err := os.Create(fileName)
+	if err != nil {
+		logger.Fatal("Couldn't create the "+fileName+" file\r\n", err)
+	}
+	reshuffleLogWriter := csv.NewWriter(reshuffleLogFile)
+	header := []string{"generation", "fitness"}
+	for _, prefix := range []string{"old_", "new_"} {
+		header = append(header, prefix+"tourney_sample_size", prefix+"crossover_parents_number", prefix+"max_crossover_length", prefix+"max_mutated_genes", prefix+"mutation_type_preference")
+	}
+	err = reshuffleLogWriter.Write(header)
+	if err != nil {
+		logger.Fatal("Couldn't write the "+fileName+" file\r\n", err)
+	}
+	return reshuffleLogFile, reshuffleLogWriter
+}
+
+//Record the reshuffle event with parameters before and after the reshuffle
+func writeReshuffleEvent(reshuffleLogWriter *csv.Writer, generation int, fitness float32, oldParameters, newParameters reshuffleParameters) {
+	record := []string{strconv.Itoa(generation), strconv.FormatFloat(float64(fitness), 'f', -1, 32)}
+	record = append(record, oldParameters.toStrings()...)
+	record = append(record, newParameters.toStrings()...)
+	err := reshuffleLogWriter.Write(record)
+	if err != nil {
+		logger.Fatal("Couldn't write the reshuffle event\r\n", err)
+	}
+	reshuffleLogWriter.Flush()
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const taskDurationRiskDBFileName string = "task_duration_risk.csv"
+
+//Three-point estimate of the task duration for the triangular distribution
+type durationEstimate struct {
+	min  float32
+	mode float32
+	max  float32
+}
+
+//key is the task ID
+var taskDurationRiskDB map[string]durationEstimate
+
+func readTaskDurationRiskCSV() (map[string]durationEstimate, error) {
+	var estimateTemp durationEstimate
+	taskDurationRiskDB := make(map[string]durationEstimate)
+	taskDurationRiskDBFile, err := os.Open(taskDurationRiskDBFileName)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open the %v file: %v", taskDurationRiskDBFileName, err)
+	}
+	rowErrors := csvErrors{fileName: taskDurationRiskDBFileName}
+	taskDurationRiskData := newCSVReader(taskDurationRiskDBFile)
+	taskDurationRiskColumns, err := readCSVHeader(taskDurationRiskData, []string{"project", "task", "min", "mode", "max"})
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", taskDurationRiskDBFileName, err)
+	}
+	line := 1
+	for {
+		taskDurationRiskRecord, err := taskDurationRiskData.Read()
+		line++
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			rowErrors.add(line, taskDurationRiskRecord, "%v", err)
+			continue
+		}
+		minDuration, err := strconv.ParseFloat(taskDurationRiskColumns.get(taskDurationRiskRecord, "min"), 32)
+		if err != nil {
+			rowErrors.add(line, taskDurationRiskRecord, "couldn't parse task min duration value: %v", err)
+			continue
+		}
+		modeDuration, err := strconv.ParseFloat(taskDurationRiskColumns.get(taskDurationRiskRecord, "mode"), 32)
+		if err != nil {
+			rowErrors.add(line, taskDurationRiskRecord, "couldn't parse task mode duration value: %v", err)
+			continue
+		}
+		maxDuration, err := strconv.ParseFloat(taskDurationRiskColumns.get(taskDurationRiskRecord, "max"), 32)
+		if err != nil {
+			rowErrors.add(line, taskDurationRiskRecord, "couldn't parse task max duration value: %v", err)
+			continue
+		}
+		if minDuration > modeDuration || modeDuration > maxDuration {
+			rowErrors.add(line, taskDurationRiskRecord, "task duration estimate should satisfy min <= mode <= max")
+			continue
+		}
+		estimateTemp.min = float32(minDuration)
+		estimateTemp.mode = float32(modeDuration)
+		estimateTemp.max = float32(maxDuration)
+		taskDurationRiskDB[taskDurationRiskColumns.get(taskDurationRiskRecord, "project")+"."+taskDurationRiskColumns.get(taskDurationRiskRecord, "task")] = estimateTemp
+	}
+	return taskDurationRiskDB, rowErrors.err()
+}
+
+//Sample duration from the triangular distribution with inverse CDF
+func sampleTriangular(estimate durationEstimate) float32 {
+	if estimate.max <= estimate.min {
+		return estimate.mode
+	}
+	u := rand.Float64()
+	a, b, c := float64(estimate.min), float64(estimate.max), float64(estimate.mode)
+	if u < (c-a)/(b-a) {
+		return float32(a + math.Sqrt(u*(b-a)*(c-a)))
+	}
+	return float32(b - math.Sqrt((1-u)*(b-a)*(b-c)))
+}
+
+//Recalculate start and stop times of the scheduled tasks with the new durations, keeping task order and assignees intact
+func replayIndividualSchedule(oldIndividual individual, durations map[string]float32) individual {
+	type workerState struct {
+		availableAt time.Time
+		latitude    float64
+		longitude   float64
+	}
+
+	newIndividual := copyIndividual(oldIndividual)
+	//Only scheduled tasks can be replayed, original start time order respects the prerequisites
+	var order []int
+	for i, task := range newIndividual.tasks {
+		if isTaskScheduled(task) {
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return newIndividual.tasks[order[i]].startTime.Before(newIndividual.tasks[order[j]].startTime)
+	})
+
+	workers := make(map[string]workerState)
+	for k, v := range workersDB {
+		workers[k] = workerState{availableAt: scheduleStartTime, latitude: v.latitude, longitude: v.longitude}
+	}
+	startTimes := make(map[string]time.Time)
+	stopTimes := make(map[string]time.Time)
+
+	for _, i := range order {
+		task := newIndividual.tasks[i]
+		taskInfo := tasksDB[task.taskID]
+		taskProject := projectsDB[taskInfo.project]
+		startTime := scheduleStartTime
+		//Wait for all prerequisites with lag/lead hours
+		for prerequisiteID := range taskInfo.prerequisites {
+			if stopTime, ok := stopTimes[prerequisiteID]; ok {
+				dependencyStartTime := calcDependencyStartTime(task.taskID, prerequisiteID, startTimes[prerequisiteID], stopTime)
+				if startTime.Before(dependencyStartTime) {
+					startTime = dependencyStartTime
+				}
+			}
+		}
+		//Wait for all assignees to arrive
+		for _, workerID := range task.assignees {
+			worker := workers[workerID]
+			drivingTime := calcProjectDrivingTime(worker.latitude, worker.longitude, taskInfo.project)
+			arrivalTime := taskSite(task.taskID).AddHours(worker.availableAt, float32(math.Round(100*float64(drivingTime))/100))
+			if startTime.Before(arrivalTime) {
+				startTime = arrivalTime
+			}
+		}
+		if !taskInfo.pinnedDateTime.IsZero() {
+			startTime = taskInfo.pinnedDateTime
+		}
+
+		duration, ok := durations[task.taskID]
+		if !ok {
+			duration = taskInfo.duration
+		}
+		task.startTime = startTime
+		task.stopTime = taskSite(task.taskID).AddTaskHours(startTime, duration)
+		startTimes[task.taskID] = task.startTime
+		stopTimes[task.taskID] = task.stopTime
+		for _, workerID := range task.assignees {
+			workers[workerID] = workerState{availableAt: task.stopTime, latitude: taskProject.latitude, longitude: taskProject.longitude}
+		}
+		newIndividual.tasks[i] = task
+	}
+	return newIndividual
+}
+
+//Calculate makespan of the individual in hours from the schedule start time
+func calcMakespan(individual individual) float32 {
+	var makespan float32
+	for _, task := range individual.tasks {
+		if makespan < float32(task.stopTime.Sub(scheduleStartTime).Hours()) {
+			makespan = float32(task.stopTime.Sub(scheduleStartTime).Hours())
+		}
+	}
+	return makespan
+}
+
+//Resample task durations and replay the individual schedule, returns sorted makespans for all trials
+func runMonteCarlo(individual individual, trials int) []float32 {
+	makespans := make([]float32, 0, trials)
+	durations := make(map[string]float32)
+	for i := 0; i < trials; i++ {
+		for taskID, estimate := range taskDurationRiskDB {
+			durations[taskID] = sampleTriangular(estimate)
+		}
+		makespans = append(makespans, calcMakespan(replayIndividualSchedule(individual, durations)))
+	}
+	sort.Slice(makespans, func(i, j int) bool {
+		return makespans[i] < makespans[j]
+	})
+	return makespans
+}
+
+//Nearest-rank percentile from the sorted slice, p in 0-100
+func percentile(sorted []float32, p float32) float32 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(float64(p) / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"time"
+)
+
+//Tasks completed or in progress at the rolling "now", key is the task ID
+var frozenTasks map[string]scheduledTask
+
+//Read the baseline schedule exported with -jsonl
+func readBaselineJSONL(fileName string) []scheduledTaskJSON {
+	var baseline []scheduledTaskJSON
+	baselineFile, err := os.Open(fileName)
+	if err != nil {
+		logger.Fatal("Couldn't open the "+fileName+" file\r\n", err)
+	}
+	defer baselineFile.Close()
+	scanner := bufio.NewScanner(baselineFile)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var taskJSON scheduledTaskJSON
+		err = json.Unmarshal(scanner.Bytes(), &taskJSON)
+		if err != nil {
+			logger.Error("Original record: ", scanner.Text())
+			logger.Fatal("Couldn't parse the baseline task", err)
+		}
+		baseline = append(baseline, taskJSON)
+	}
+	if err = scanner.Err(); err != nil {
+		logger.Fatal("Couldn't read the "+fileName+" file\r\n", err)
+	}
+	return baseline
+}
+
+//Freeze baseline tasks finished before now and in-progress tasks with their assignees and the remaining duration only.
+//Future tasks are left for the optimization
+func applyRollingHorizon(baseline []scheduledTaskJSON, now time.Time) {
+	frozenTasks = make(map[string]scheduledTask)
+	for _, taskJSON := range baseline {
+		taskID := taskJSON.ProjectID + "." + taskJSON.TaskID
+		tempTask, ok := tasksDB[taskID]
+		if !ok {
+			logger.Fatal("Baseline task is missing: ", taskID)
+		}
+		if taskJSON.Start == "" || taskJSON.Stop == "" || len(taskJSON.AssigneeIDs) == 0 {
+			continue
+		}
+		//Baseline is exported with the same output layout
+		startTime, err := time.ParseInLocation(outputLayout(time.RFC3339), taskJSON.Start, now.Location())
+		if err != nil {
+			logger.Error("Original task: ", taskJSON)
+			logger.Fatal("Couldn't parse baseline task start value", err)
+		}
+		stopTime, err := time.ParseInLocation(outputLayout(time.RFC3339), taskJSON.Stop, now.Location())
+		if err != nil {
+			logger.Error("Original task: ", taskJSON)
+			logger.Fatal("Couldn't parse baseline task stop value", err)
+		}
+		startTime, stopTime = startTime.In(now.Location()), stopTime.In(now.Location())
+
+		//Unique assignees, the same worker can't do the task twice
+		assignees := make([]string, 0, len(taskJSON.AssigneeIDs))
+		assigneesSet := make(map[string]struct{})
+		for _, workerID := range taskJSON.AssigneeIDs {
+			if _, ok := assigneesSet[workerID]; !ok {
+				assigneesSet[workerID] = struct{}{}
+				assignees = append(assignees, workerID)
+			}
+		}
+
+		switch {
+		case !stopTime.After(now):
+			//Completed task is kept as is
+			logger.Infof("Task %v is completed at %v", taskID, stopTime)
+			tempTask.idealWorkerCount = len(assignees)
+			tempTask.minWorkerCount = len(assignees)
+			tempTask.maxWorkerCount = len(assignees)
+			frozenTasks[taskID] = scheduledTask{taskID: taskID, startTime: startTime, stopTime: stopTime, assignees: assignees}
+		case startTime.Before(now):
+			//In-progress task continues from now with the same workers
+			tempTask.duration = taskSite(taskID).WorkingHoursBetween(now, stopTime)
+			tempTask.idealWorkerCount = len(assignees)
+			tempTask.minWorkerCount = len(assignees)
+			tempTask.maxWorkerCount = len(assignees)
+			startTime = taskSite(taskID).AddHours(now, 0)
+			stopTime = taskSite(taskID).AddTaskHours(startTime, tempTask.duration)
+			logger.Infof("Task %v is in progress, remaining duration %v hours", taskID, tempTask.duration)
+			frozenTasks[taskID] = scheduledTask{taskID: taskID, startTime: startTime, stopTime: stopTime, assignees: assignees}
+		}
+		tasksDB[taskID] = tempTask
+	}
+}
+
+//Restore frozen tasks in the reset individual, keep their assignees busy and release their dependant tasks
+func freezeCompletedTasks(individual individual) individual {
+	if len(frozenTasks) == 0 {
+		return individual
+	}
+	for i, task := range individual.tasks {
+		if frozenTask, ok := frozenTasks[task.taskID]; ok {
+			individual.tasks[i].startTime = frozenTask.startTime
+			individual.tasks[i].stopTime = frozenTask.stopTime
+			individual.tasks[i].assignees = append(make([]string, 0, len(frozenTask.assignees)), frozenTask.assignees...)
+			individual.tasks[i].numPrerequisites = 0
+		}
+	}
+	for i, worker := range individual.workers {
+		for _, frozenTask := range frozenTasks {
+			for _, workerID := range frozenTask.assignees {
+				if workerID == worker.workerID && individual.workers[i].availableAt.Before(frozenTask.stopTime) {
+					individual.workers[i].availableAt = frozenTask.stopTime
+					individual.workers[i].started = true
+					individual.workers[i].latitude = projectsDB[tasksDB[frozenTask.taskID].project].latitude
+					individual.workers[i].longitude = projectsDB[tasksDB[frozenTask.taskID].project].longitude
+				}
+			}
+		}
+	}
+	for i, task := range individual.tasks {
+		if _, ok := frozenTasks[task.taskID]; ok {
+			continue
+		}
+		for prerequisiteID := range tasksDB[task.taskID].prerequisites {
+			if frozenTask, ok := frozenTasks[prerequisiteID]; ok {
+				individual.tasks[i].numPrerequisites--
+				newStartTime := calcDependencyStartTime(task.taskID, prerequisiteID, frozenTask.startTime, frozenTask.stopTime)
+				if individual.tasks[i].startTime.Before(newStartTime) {
+					individual.tasks[i].startTime = newStartTime
+				}
+			}
+		}
+	}
+	return individual
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"gitlab.com/alex.skylight/sambo/calendar"
+)
+
+const taskCalendarDBFileName string = "task_calendar.csv"
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+//Read task-specific working calendars, e.g. weekends only or night shift, and add them to the tasks.
+//Empty weekdays or daily times are inherited from the project site
+func readTaskCalendarCSV(tasks map[string]task) (map[string]task, error) {
+	taskCalendarDBFile, err := os.Open(taskCalendarDBFileName)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open the %v file: %v", taskCalendarDBFileName, err)
+	}
+	rowErrors := csvErrors{fileName: taskCalendarDBFileName}
+	taskCalendarData := newCSVReader(taskCalendarDBFile)
+	taskCalendarColumns, err := readCSVHeader(taskCalendarData, []string{"project", "task"})
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", taskCalendarDBFileName, err)
+	}
+	line := 1
+	for {
+		taskCalendarRecord, err := taskCalendarData.Read()
+		line++
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			rowErrors.add(line, taskCalendarRecord, "%v", err)
+			continue
+		}
+		taskID := taskCalendarColumns.get(taskCalendarRecord, "project") + "." + taskCalendarColumns.get(taskCalendarRecord, "task")
+		tempTask, ok := tasks[taskID]
+		if !ok {
+			rowErrors.add(line, taskCalendarRecord, "task is missing: %v", taskID)
+			continue
+		}
+		site := projectsDB[tempTask.project].site
+		if weekdays := strings.Fields(taskCalendarColumns.get(taskCalendarRecord, "weekdays")); len(weekdays) > 0 {
+			site.WorkingWeekdays = make(map[time.Weekday]struct{})
+			var unknownWeekdays []string
+			for _, v := range weekdays {
+				weekday, ok := weekdayNames[strings.ToLower(v)]
+				if !ok {
+					unknownWeekdays = append(unknownWeekdays, v)
+					continue
+				}
+				site.WorkingWeekdays[weekday] = struct{}{}
+			}
+			if len(unknownWeekdays) > 0 {
+				rowErrors.add(line, taskCalendarRecord, "couldn't parse task calendar weekday value: %v", strings.Join(unknownWeekdays, " "))
+				continue
+			}
+		}
+		if taskCalendarColumns.get(taskCalendarRecord, "daily_start") != "" {
+			site.DailyStartTime, err = time.Parse(defaultTimeFormat, taskCalendarColumns.get(taskCalendarRecord, "daily_start"))
+			if err != nil {
+				rowErrors.add(line, taskCalendarRecord, "couldn't parse task calendar daily start time value: %v", err)
+				continue
+			}
+		}
+		if taskCalendarColumns.get(taskCalendarRecord, "daily_end") != "" {
+			site.DailyEndTime, err = time.Parse(defaultTimeFormat, taskCalendarColumns.get(taskCalendarRecord, "daily_end"))
+			if err != nil {
+				rowErrors.add(line, taskCalendarRecord, "couldn't parse task calendar daily end time value: %v", err)
+				continue
+			}
+		}
+		//AddHours works inside a single day, night shift should end before midnight
+		if !site.DailyStartTime.Before(site.DailyEndTime) {
+			rowErrors.add(line, taskCalendarRecord, "task calendar daily start time should be before daily end time")
+			continue
+		}
+		tempTask.site = &site
+		tasks[taskID] = tempTask
+	}
+	return tasks, rowErrors.err()
+}
+
+//Working calendar of the task, project site is used if the task has no own calendar
+func taskSite(taskID string) calendar.Site {
+	if tasksDB[taskID].site != nil {
+		return *tasksDB[taskID].site
+	}
+	return projectsDB[tasksDB[taskID].project].site
+}
+
+//Working calendar of the worker doing the task: the task calendar limited by the worker daily hours, if defined.
+//Returns false if the worker daily hours don't overlap the task calendar daily hours
+func workerTaskSite(workerID string, taskID string) (calendar.Site, bool) {
+	site := taskSite(taskID)
+	worker := workersDB[workerID]
+	if worker.dailyStartTime.IsZero() {
+		return site, true
+	}
+	if site.DailyStartTime.Before(worker.dailyStartTime) {
+		site.DailyStartTime = worker.dailyStartTime
+	}
+	if worker.dailyEndTime.Before(site.DailyEndTime) {
+		site.DailyEndTime = worker.dailyEndTime
+	}
+	return site, site.DailyStartTime.Before(site.DailyEndTime)
+}
@@ -0,0 +1,28 @@
+package main
+
+import "time"
+
+//Find the worker time off overlapping the startTime-stopTime range, ranges touching at the boundary are not overlapping
+func findTimeOffOverlap(workerID string, startTime, stopTime time.Time) (dateTimeRange, bool) {
+	for _, blockedRange := range workersDB[workerID].blockedRanges {
+		if blockedRange.startTime.Before(stopTime) && startTime.Before(blockedRange.endTime) {
+			return blockedRange, true
+		}
+	}
+	return dateTimeRange{}, false
+}
+
+//Find the earliest start time, not before startTime, when the worker has no time off during the whole task duration.
+//Task straddling the time off is pushed after it
+func findTimeOffStartTime(workerID string, taskID string, startTime time.Time) time.Time {
+	site := taskSite(taskID)
+	for {
+		stopTime := site.AddTaskHours(startTime, tasksDB[taskID].duration)
+		blockedRange, ok := findTimeOffOverlap(workerID, startTime, stopTime)
+		if !ok {
+			return startTime
+		}
+		logger.Debugf("Worker is on time off, task:%v, worker:%v, startTime:%v, nextStartTime:%v", taskID, workerID, startTime, blockedRange.endTime)
+		startTime = site.AddHours(blockedRange.endTime, 0)
+	}
+}
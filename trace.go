@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/csv"
+	"os"
+	"strconv"
+)
+
+//Create assignment trace CSV file and write the header
+func createTraceCSV(fileName string) (*os.File, *csv.Writer) {
+	traceFile, err := os.Create(fileName)
+	if err != nil {
+		logger.Fatal("Couldn't create the "+fileName+" file\r\n", err)
+	}
+	traceWriter := csv.NewWriter(traceFile)
+	err = traceWriter.Write([]string{"event", "task_id", "worker_id", "fitness", "available_at", "start_time", "stop_time"})
+	if err != nil {
+		logger.Fatal("Couldn't write the "+fileName+" file\r\n", err)
+	}
+	return traceFile, traceWriter
+}
+
+func writeTraceRecord(traceWriter *csv.Writer, record []string) {
+	err := traceWriter.Write(record)
+	if err != nil {
+		logger.Fatal("Couldn't write the trace record\r\n", err)
+	}
+}
+
+//Record all valid workers for the task in the order they will be tried
+func traceCandidates(traceWriter *csv.Writer, task scheduledTask, workers []scheduledWorker) {
+	for _, worker := range workers {
+		if _, ok := tasksDB[task.taskID].validWorkers[worker.workerID]; !ok {
+			continue
+		}
+		writeTraceRecord(traceWriter, []string{"candidate", task.taskID, worker.workerID, strconv.FormatFloat(float64(worker.fitness), 'f', -1, 32), formatJSONTime(worker.availableAt), formatJSONTime(task.startTime), formatJSONTime(task.stopTime)})
+	}
+}
+
+//Record the chosen worker with the resulting task times
+func traceAssignment(traceWriter *csv.Writer, task scheduledTask, worker scheduledWorker) {
+	writeTraceRecord(traceWriter, []string{"assigned", task.taskID, worker.workerID, strconv.FormatFloat(float64(worker.fitness), 'f', -1, 32), formatJSONTime(worker.availableAt), formatJSONTime(task.startTime), formatJSONTime(task.stopTime)})
+}
+
+//Replay scheduling of the single individual and record every assignment decision to the trace file
+func writeScheduleTrace(fileName string, individual individual) {
+	traceFile, traceWriter := createTraceCSV(fileName)
+	defer traceFile.Close()
+	scheduleIndividual(copyIndividual(individual), traceWriter)
+	traceWriter.Flush()
+	if err := traceWriter.Error(); err != nil {
+		logger.Fatal("Couldn't write the "+fileName+" file\r\n", err)
+	}
+}